@@ -0,0 +1,372 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Record is an archived email, the listing provides the summary
+// while the body, the HTML and the source come from the fetched emails
+type Record struct {
+	Inbox      string        `json:"inbox"`
+	ID         string        `json:"id"`
+	Sender     *inbox.Sender `json:"sender,omitempty"`
+	Subject    string        `json:"subject"`
+	Date       *time.Time    `json:"date,omitempty"`
+	IsSPAM     bool          `json:"isSPAM"`
+	Body       string        `json:"body,omitempty"`
+	HTML       string        `json:"html,omitempty"`
+	Source     string        `json:"source,omitempty"`
+	ArchivedAt time.Time     `json:"archivedAt"`
+}
+
+var (
+	// recordsBucket holds the records keyed by inbox and mail ID
+	recordsBucket = []byte("records")
+	// wordsBucket indexes the records by the words of their subject,
+	// sender and body, a key is the word followed by the record key
+	wordsBucket = []byte("words")
+	// datesBucket indexes the records by their archiving date,
+	// a key is the date followed by the record key
+	datesBucket = []byte("dates")
+)
+
+// maxWordLength bounds the indexed words, the longer ones
+// are usually encoded data found in the bodies
+const maxWordLength = 64
+
+// lockTimeout bounds the time spent waiting for
+// another process writing in the archive
+const lockTimeout = 5 * time.Second
+
+// Store keeps the records in a bbolt database, the file is only opened
+// while a transaction runs so several processes can share the archive
+type Store struct {
+	path      string
+	retention time.Duration
+	now       func() time.Time
+	// mu serializes the transactions, the file lock is held per open file
+	mu sync.Mutex
+}
+
+// DefaultDir returns the archive directory, the
+// XDG_DATA_HOME directory is used when defined
+func DefaultDir() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "yogo", "archive"), nil
+}
+
+// Open opens the archive stored in dir, the records older than the
+// retention are removed, a retention of 0 keeps the records forever
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dir, "archive.db"), retention: retention, now: time.Now}
+	err := s.update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{recordsBucket, wordsBucket, datesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Prune(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AddItems records the emails of an inbox listing
+func (s *Store) AddItems(inboxName string, items []inbox.InboxItem) error {
+	return s.update(func(tx *bolt.Tx) error {
+		for _, item := range items {
+			err := s.put(tx, inboxName, item.ID, func(r *Record) {
+				r.Sender = item.Sender
+				r.Subject = item.Subject
+				r.Date = item.Date
+				r.IsSPAM = item.IsSPAM
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddMail records a fetched email, an HTML email provides
+// the body and the HTML, a source email the original message
+func (s *Store) AddMail(inboxName string, ID string, mail inbox.Mail) error {
+	return s.update(func(tx *bolt.Tx) error {
+		return s.put(tx, inboxName, ID, func(r *Record) {
+			switch m := mail.(type) {
+			case inbox.Source:
+				r.Source = string(m.Message())
+			case inbox.HTMLPage:
+				r.HTML = m.HTML()
+				r.Body = m.Content()
+			default:
+				r.Body = m.Content()
+			}
+		})
+	})
+}
+
+// Get returns an archived email
+func (s *Store) Get(inboxName string, ID string) (Record, error) {
+	var r Record
+	var found bool
+	err := s.view(func(tx *bolt.Tx) error {
+		var err error
+		r, found, err = get(tx, recordKey(inboxName, ID))
+		return err
+	})
+	if err == nil && !found {
+		err = fmt.Errorf(`email "%s" of inbox "%s" is not archived`, ID, inboxName)
+	}
+	return r, err
+}
+
+// Search returns the records having words starting with every word of
+// the query in their subject, sender or body, the most recent first,
+// an empty inbox name searches all inboxes, the records that can't be
+// read are skipped
+func (s *Store) Search(query string, inboxName string) (Records, error) {
+	records := Records{}
+	err := s.view(func(tx *bolt.Tx) error {
+		keys, err := match(tx, words(query))
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			r, found, err := get(tx, key)
+			if err != nil || !found {
+				continue
+			}
+			if inboxName != "" && r.Inbox != inboxName {
+				continue
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].time().After(records[j].time())
+	})
+	return records, err
+}
+
+// Prune removes the records archived before the retention period
+func (s *Store) Prune() (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	limit := dateKey(s.now().Add(-s.retention), nil)
+	count := 0
+	err := s.update(func(tx *bolt.Tx) error {
+		dates := [][]byte{}
+		c := tx.Bucket(datesBucket).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, limit) < 0; k, _ = c.Next() {
+			dates = append(dates, bytes.Clone(k))
+		}
+		for _, date := range dates {
+			if err := tx.Bucket(datesBucket).Delete(date); err != nil {
+				return err
+			}
+			if err := remove(tx, date[8:]); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// match returns the keys of the records having a word starting
+// with every term, all the records when there are no terms
+func match(tx *bolt.Tx, terms []string) ([][]byte, error) {
+	if len(terms) == 0 {
+		keys := [][]byte{}
+		err := tx.Bucket(recordsBucket).ForEach(func(k, v []byte) error {
+			keys = append(keys, bytes.Clone(k))
+			return nil
+		})
+		return keys, err
+	}
+	var matched map[string]bool
+	for _, term := range terms {
+		found := map[string]bool{}
+		prefix := []byte(term)
+		c := tx.Bucket(wordsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			key := string(k[bytes.IndexByte(k, 0)+1:])
+			if matched == nil || matched[key] {
+				found[key] = true
+			}
+		}
+		matched = found
+	}
+	keys := [][]byte{}
+	for key := range matched {
+		keys = append(keys, []byte(key))
+	}
+	return keys, nil
+}
+
+// put updates a record and its indexes, the archiving date is set when
+// the record is created and kept afterwards, a record that can't be
+// read is replaced
+func (s *Store) put(tx *bolt.Tx, inboxName string, ID string, f func(*Record)) error {
+	key := recordKey(inboxName, ID)
+	r, found, err := get(tx, key)
+	if err == nil && found {
+		if err := unindex(tx, key, r); err != nil {
+			return err
+		}
+	} else {
+		r = Record{Inbox: inboxName, ID: ID, ArchivedAt: s.now()}
+	}
+	f(&r)
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(recordsBucket).Put(key, data); err != nil {
+		return err
+	}
+	if err := tx.Bucket(datesBucket).Put(dateKey(r.ArchivedAt, key), nil); err != nil {
+		return err
+	}
+	for _, word := range recordWords(r) {
+		if err := tx.Bucket(wordsBucket).Put(wordKey(word, key), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove deletes a record, the words of a record that can't be read
+// are left in the index, the searches skip the missing records
+func remove(tx *bolt.Tx, key []byte) error {
+	if r, found, err := get(tx, key); err == nil && found {
+		if err := unindex(tx, key, r); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(recordsBucket).Delete(key)
+}
+
+func unindex(tx *bolt.Tx, key []byte, r Record) error {
+	if err := tx.Bucket(datesBucket).Delete(dateKey(r.ArchivedAt, key)); err != nil {
+		return err
+	}
+	for _, word := range recordWords(r) {
+		if err := tx.Bucket(wordsBucket).Delete(wordKey(word, key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func get(tx *bolt.Tx, key []byte) (Record, bool, error) {
+	r := Record{}
+	data := tx.Bucket(recordsBucket).Get(key)
+	if data == nil {
+		return r, false, nil
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, false, fmt.Errorf(`archived email "%s" is invalid : %w`, strings.ReplaceAll(string(key), "\x00", " "), err)
+	}
+	return r, true, nil
+}
+
+func (s *Store) update(f func(*bolt.Tx) error) error {
+	return s.transaction(false, f)
+}
+
+func (s *Store) view(f func(*bolt.Tx) error) error {
+	return s.transaction(true, f)
+}
+
+func (s *Store) transaction(readOnly bool, f func(*bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := bolt.Open(s.path, 0o644, &bolt.Options{Timeout: lockTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return fmt.Errorf(`archive "%s" is used by another process : %w`, s.path, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if readOnly {
+		return db.View(f)
+	}
+	return db.Update(f)
+}
+
+// recordKey identifies a record, the names are only used
+// as keys so they never end up in a file path
+func recordKey(inboxName string, ID string) []byte {
+	return []byte(inboxName + "\x00" + ID)
+}
+
+func dateKey(date time.Time, key []byte) []byte {
+	k := binary.BigEndian.AppendUint64(nil, uint64(date.UnixNano()))
+	return append(k, key...)
+}
+
+func wordKey(word string, key []byte) []byte {
+	return append([]byte(word+"\x00"), key...)
+}
+
+// recordWords returns the distinct words of the searchable fields
+func recordWords(r Record) []string {
+	seen := map[string]bool{}
+	list := []string{}
+	for _, word := range words(strings.Join([]string{r.Subject, r.Sender.String(), r.Body}, "\n")) {
+		if !seen[word] {
+			seen[word] = true
+			list = append(list, word)
+		}
+	}
+	return list
+}
+
+// words splits a text in lowercase words made of letters and digits
+func words(text string) []string {
+	list := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, word := range list {
+		if len(word) > maxWordLength {
+			list[i] = strings.ToValidUTF8(word[:maxWordLength], "")
+		}
+	}
+	return list
+}
@@ -0,0 +1,250 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+type mailMock struct {
+	content string
+}
+
+func (m mailMock) Content() string {
+	return m.content
+}
+
+func (m mailMock) GetLinks() []inbox.Link {
+	return nil
+}
+
+func (m mailMock) GetAttachments() []inbox.Attachment {
+	return nil
+}
+
+type htmlMailMock struct {
+	mailMock
+	html string
+}
+
+func (m htmlMailMock) HTML() string {
+	return m.html
+}
+
+func (m htmlMailMock) Document() (string, error) {
+	return "", nil
+}
+
+func (m htmlMailMock) SetHyperlinks(bool) {}
+
+func (m htmlMailMock) SetWidth(int) {}
+
+type sourceMailMock struct {
+	mailMock
+	message []byte
+}
+
+func (m sourceMailMock) SetRaw(bool) {}
+
+func (m sourceMailMock) FilterHeaders([]string, []string) {}
+
+func (m sourceMailMock) SetHeadersOnly(bool) {}
+
+func (m sourceMailMock) Message() []byte {
+	return m.message
+}
+
+func newStore(t *testing.T, retention time.Duration, now time.Time) *Store {
+	s, err := Open(t.TempDir(), retention)
+	assert.NoError(t, err)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	date := now.Add(-time.Hour)
+	s := newStore(t, 0, now)
+
+	assert.NoError(t, s.AddItems("test", []inbox.InboxItem{
+		{ID: "e_a/b", Subject: "Confirm your account", Sender: &inbox.Sender{Name: "Acme", Mail: "no-reply@acme.com"}, Date: &date},
+		{ID: "e_c", Subject: "Newsletter", Sender: &inbox.Sender{Name: "Shop"}, IsSPAM: true},
+	}))
+	assert.NoError(t, s.AddMail("test", "e_a/b", htmlMailMock{mailMock: mailMock{content: "Your code is 1234"}, html: "<p>Your code is 1234</p>"}))
+	assert.NoError(t, s.AddMail("test", "e_a/b", sourceMailMock{message: []byte("Subject: Confirm\r\n\r\nYour code is 1234")}))
+	assert.NoError(t, s.AddMail("other", "e_d", mailMock{content: "Your code is 5678"}))
+
+	r, err := s.Get("test", "e_a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, Record{
+		Inbox:      "test",
+		ID:         "e_a/b",
+		Sender:     &inbox.Sender{Name: "Acme", Mail: "no-reply@acme.com"},
+		Subject:    "Confirm your account",
+		Date:       &date,
+		Body:       "Your code is 1234",
+		HTML:       "<p>Your code is 1234</p>",
+		Source:     "Subject: Confirm\r\n\r\nYour code is 1234",
+		ArchivedAt: now,
+	}, r)
+	_, err = s.Get("test", "e_z")
+	assert.EqualError(t, err, `email "e_z" of inbox "test" is not archived`)
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	date := now.Add(-48 * time.Hour)
+	s := newStore(t, 0, now)
+	assert.NoError(t, s.AddItems("test", []inbox.InboxItem{
+		{ID: "a", Subject: "Confirm your account", Sender: &inbox.Sender{Name: "Acme"}, Date: &date},
+		{ID: "b", Subject: "Reset your password", Sender: &inbox.Sender{Mail: "security@acme.com"}},
+	}))
+	assert.NoError(t, s.AddMail("test", "a", mailMock{content: "Your code is 1234"}))
+	assert.NoError(t, s.AddItems("other", []inbox.InboxItem{{ID: "c", Subject: "Welcome", Sender: &inbox.Sender{Name: "Shop"}}}))
+
+	type scenario struct {
+		name     string
+		query    string
+		inbox    string
+		expected []string
+	}
+
+	scenarios := []scenario{
+		{
+			name:     "Every record",
+			expected: []string{"c", "b", "a"},
+		},
+		{
+			name:     "Subject and sender",
+			query:    "ACME your",
+			expected: []string{"b", "a"},
+		},
+		{
+			name:     "Body",
+			query:    "code 1234",
+			expected: []string{"a"},
+		},
+		{
+			name:     "Inbox",
+			inbox:    "other",
+			expected: []string{"c"},
+		},
+		{
+			name:     "Word prefix",
+			query:    "conf",
+			expected: []string{"a"},
+		},
+		{
+			name:     "No match",
+			query:    "acme invoice",
+			expected: []string{},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			records, err := s.Search(scenario.query, scenario.inbox)
+			assert.NoError(t, err)
+			IDs := []string{}
+			for _, r := range records {
+				IDs = append(IDs, r.ID)
+			}
+			assert.Equal(t, scenario.expected, IDs)
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	s, err := Open(dir, 0)
+	assert.NoError(t, err)
+	s.now = func() time.Time { return now.Add(-72 * time.Hour) }
+	assert.NoError(t, s.AddItems("test", []inbox.InboxItem{{ID: "old"}}))
+	s.now = func() time.Time { return now }
+	assert.NoError(t, s.AddItems("test", []inbox.InboxItem{{ID: "recent"}}))
+	// the archiving date is kept when a record is updated
+	assert.NoError(t, s.AddItems("test", []inbox.InboxItem{{ID: "old", Subject: "listed again"}}))
+
+	count, err := s.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	s.retention = 48 * time.Hour
+	count, err = s.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	_, err = s.Get("test", "old")
+	assert.Error(t, err)
+	_, err = s.Get("test", "recent")
+	assert.NoError(t, err)
+}
+
+func TestInboxNames(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, "archive"), 0)
+	assert.NoError(t, err)
+	for _, name := range []string{"..", ".", "a/b", "../../etc"} {
+		assert.NoError(t, s.AddItems(name, []inbox.InboxItem{{ID: "../x", Subject: name}}))
+		r, err := s.Get(name, "../x")
+		assert.NoError(t, err)
+		assert.Equal(t, name, r.Subject)
+	}
+
+	// the names never end up in a file path
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = os.ReadDir(filepath.Join(dir, "archive"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "archive.db", entries[0].Name())
+}
+
+func TestCorruptedRecord(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	s := newStore(t, 0, now)
+	assert.NoError(t, s.AddItems("test", []inbox.InboxItem{{ID: "a", Subject: "Welcome"}, {ID: "b", Subject: "Welcome back"}}))
+	assert.NoError(t, s.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(recordsBucket).Put(recordKey("test", "a"), []byte("{"))
+	}))
+
+	// the other records are still found
+	records, err := s.Search("welcome", "")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "b", records[0].ID)
+	_, err = s.Get("test", "a")
+	assert.EqualError(t, err, `archived email "test a" is invalid : unexpected end of JSON input`)
+
+	// the record is replaced when the email is listed again
+	assert.NoError(t, s.AddItems("test", []inbox.InboxItem{{ID: "a", Subject: "Welcome"}}))
+	records, err = s.Search("welcome", "")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	// and removed when expired
+	assert.NoError(t, s.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(recordsBucket).Put(recordKey("test", "b"), []byte("{"))
+	}))
+	s.retention = time.Hour
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	count, err := s.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	records, err = s.Search("", "")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const noDataToDisplayMsg = "[no data to display]"
+
+// Records are the results of a search
+type Records []Record
+
+// Coloured renders a line per record
+func (rs Records) Coloured() (string, error) {
+	if len(rs) == 0 {
+		return color.CyanString("No archived email found"), nil
+	}
+	lines := []string{}
+	for _, r := range rs {
+		sender := r.Sender.String()
+		if sender == "" {
+			sender = noDataToDisplayMsg
+		}
+		subject := r.Subject
+		if subject == "" {
+			subject = noDataToDisplayMsg
+		}
+		lines = append(lines, fmt.Sprintf(" %s %s %s %s : %s",
+			color.GreenString(r.time().Format("2006-01-02 15:04")),
+			color.MagentaString(r.Inbox),
+			r.ID,
+			color.YellowString(sender),
+			color.CyanString(subject),
+		))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Items returns the records
+func (rs Records) Items() []any {
+	items := []any{}
+	for _, r := range rs {
+		items = append(items, r)
+	}
+	return items
+}
+
+// Header returns the columns of the records
+func (rs Records) Header() []string {
+	return []string{"inbox", "id", "sender_name", "sender_mail", "subject", "date"}
+}
+
+// Rows returns a row per record
+func (rs Records) Rows() [][]string {
+	rows := [][]string{}
+	for _, r := range rs {
+		var name, mail string
+		if r.Sender != nil {
+			name, mail = r.Sender.Name, r.Sender.Mail
+		}
+		rows = append(rows, []string{r.Inbox, r.ID, name, mail, r.Subject, r.time().Format(time.RFC3339)})
+	}
+	return rows
+}
+
+// Coloured renders the record like a fetched email
+func (r Record) Coloured() (string, error) {
+	info := struct {
+		Inbox   string
+		From    string
+		Subject string
+		Date    string
+		Body    string
+	}{
+		Inbox:   color.MagentaString(r.Inbox),
+		From:    color.MagentaString(noDataToDisplayMsg),
+		Subject: color.YellowString(noDataToDisplayMsg),
+		Date:    color.GreenString(r.time().Format("2006-01-02 15:04")),
+		Body:    color.CyanString(noDataToDisplayMsg),
+	}
+	if sender := r.Sender.String(); sender != "" {
+		info.From = color.MagentaString(sender)
+	}
+	if r.Subject != "" {
+		info.Subject = color.YellowString(r.Subject)
+	}
+	if r.Body != "" {
+		info.Body = color.CyanString(r.Body)
+	}
+
+	var buf bytes.Buffer
+	tpl := template.Must(template.New("t").Parse(`---
+Inbox   : {{.Inbox}}
+From    : {{.From}}
+Subject : {{.Subject}}
+Date    : {{.Date}}
+---
+{{.Body}}
+---
+`))
+	err := tpl.Execute(&buf, info)
+	return buf.String(), err
+}
+
+// Header returns the columns of the record fields
+func (r Record) Header() []string {
+	return []string{"field", "value"}
+}
+
+// Rows returns a row per record field
+func (r Record) Rows() [][]string {
+	var name, mail string
+	if r.Sender != nil {
+		name, mail = r.Sender.Name, r.Sender.Mail
+	}
+	return [][]string{
+		{"inbox", r.Inbox},
+		{"id", r.ID},
+		{"sender_name", name},
+		{"sender_mail", mail},
+		{"subject", r.Subject},
+		{"date", r.time().Format(time.RFC3339)},
+		{"body", r.Body},
+	}
+}
+
+// time returns the reception date, the archiving
+// date when the email was never listed
+func (r Record) time() time.Time {
+	if r.Date != nil {
+		return *r.Date
+	}
+	return r.ArchivedAt
+}
@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordsColoured(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)
+	s, err := Records{
+		{Inbox: "test", ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme", Mail: "hello@acme.com"}, Date: &date},
+		{Inbox: "other", ID: "b", ArchivedAt: date.Add(time.Hour)},
+	}.Coloured()
+	assert.NoError(t, err)
+	assert.Equal(t, ` 2024-03-02 10:30 test a Acme <hello@acme.com> : Welcome
+ 2024-03-02 11:30 other b [no data to display] : [no data to display]`, s)
+
+	s, err = Records{}.Coloured()
+	assert.NoError(t, err)
+	assert.Equal(t, "No archived email found", s)
+}
+
+func TestRecordColoured(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)
+	s, err := Record{Inbox: "test", ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme"}, Date: &date, Body: "Hello"}.Coloured()
+	assert.NoError(t, err)
+	assert.Equal(t, `---
+Inbox   : test
+From    : Acme
+Subject : Welcome
+Date    : 2024-03-02 10:30
+---
+Hello
+---
+`, s)
+}
+
+func TestRecordsRows(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)
+	records := Records{{Inbox: "test", ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme"}, Date: &date, Body: "Hello"}}
+	assert.Equal(t, []string{"inbox", "id", "sender_name", "sender_mail", "subject", "date"}, records.Header())
+	assert.Equal(t, [][]string{{"test", "a", "Acme", "", "Welcome", "2024-03-02T10:30:00Z"}}, records.Rows())
+	assert.Equal(t, [][]string{
+		{"inbox", "test"},
+		{"id", "a"},
+		{"sender_name", "Acme"},
+		{"sender_mail", ""},
+		{"subject", "Welcome"},
+		{"date", "2024-03-02T10:30:00Z"},
+		{"body", "Hello"},
+	}, records[0].Rows())
+}
@@ -0,0 +1,132 @@
+// Package backend defines what the servers exposing the inboxes share
+package backend
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+// Inbox is an inbox served, an inbox is not safe for concurrent use and
+// the inboxes built by a server share the same yopmail session, so a
+// server serializes the calls made to its inboxes with a Session,
+// this also makes the rate limiter apply to the whole server
+type Inbox interface {
+	Refresh(int) error
+	GetMails() []inbox.InboxItem
+	FetchByID(string) (inbox.Mail, error)
+	DeleteByID(string) error
+	Flush() error
+}
+
+// Builder creates an inbox, when source is enabled the
+// emails are fetched with their source instead of their HTML
+type Builder func(name string, source bool) (Inbox, error)
+
+// Session serializes the calls made to the inboxes built by a server
+type Session struct {
+	mu    sync.Mutex
+	build Builder
+}
+
+// NewSession creates a session building its inboxes with build
+func NewSession(build Builder) *Session {
+	return &Session{build: build}
+}
+
+// Do builds an inbox and runs f, the other calls wait until f returns
+func (s *Session) Do(name string, source bool, f func(Inbox) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in, err := s.build(name, source)
+	if err != nil {
+		return err
+	}
+	return f(in)
+}
+
+// Conns accepts the connections of a TCP server and keeps track
+// of them so they are all closed when the server stops
+type Conns struct {
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// Serve accepts the connections until Close is called, every connection
+// is handled in its own goroutine and closed once handle returns
+func (c *Conns) Serve(l net.Listener, handle func(net.Conn)) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return errors.New("server is closed")
+	}
+	if c.listeners == nil {
+		c.listeners = map[net.Listener]struct{}{}
+	}
+	c.listeners[l] = struct{}{}
+	c.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			delete(c.listeners, l)
+			if c.closed {
+				return nil
+			}
+			return err
+		}
+		if !c.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go func() {
+			defer c.untrack(conn)
+			handle(conn)
+		}()
+	}
+}
+
+// Close stops the listeners and closes the connections,
+// it waits for the connections being handled to be over
+func (c *Conns) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	for l := range c.listeners {
+		l.Close()
+	}
+	for conn := range c.conns {
+		conn.Close()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+	return nil
+}
+
+func (c *Conns) track(conn net.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	if c.conns == nil {
+		c.conns = map[net.Conn]struct{}{}
+	}
+	c.conns[conn] = struct{}{}
+	c.wg.Add(1)
+	return true
+}
+
+func (c *Conns) untrack(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn.Close()
+	delete(c.conns, conn)
+	c.wg.Done()
+}
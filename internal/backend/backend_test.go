@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConns(t *testing.T) {
+	var c Conns
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	handled := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Serve(l, func(conn net.Conn) {
+			close(handled)
+			// The connection is held until the server closes it
+			_, _ = bufio.NewReader(conn).ReadString('\n')
+		})
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	<-handled
+
+	assert.NoError(t, c.Close())
+	assert.NoError(t, <-done)
+	_, err = bufio.NewReader(conn).ReadString('\n')
+	assert.Error(t, err)
+
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	assert.EqualError(t, c.Serve(l, func(conn net.Conn) {}), "server is closed")
+}
+
+func TestSessionDo(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	s := NewSession(func(name string, source bool) (Inbox, error) {
+		if name == "missing" {
+			return nil, errors.New(`inbox "missing" doesn't exist`)
+		}
+		return nil, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Do("test", false, func(Inbox) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, maxRunning.Load())
+
+	called := false
+	assert.EqualError(t, s.Do("missing", false, func(Inbox) error {
+		called = true
+		return nil
+	}), `inbox "missing" doesn't exist`)
+	assert.False(t, called)
+}
@@ -0,0 +1,150 @@
+// Package backendtest provides the fake inbox and the
+// helpers shared by the tests of the servers
+package backendtest
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Mail is an email fetched from its HTML page
+type Mail struct {
+	Text  string
+	Links []inbox.Link
+	// Doc is the JSON document of the email
+	Doc string
+}
+
+func (m Mail) Content() string {
+	return m.Text
+}
+
+func (m Mail) GetLinks() []inbox.Link {
+	return m.Links
+}
+
+func (m Mail) GetAttachments() []inbox.Attachment {
+	return nil
+}
+
+func (m Mail) JSON() (string, error) {
+	return m.Doc, nil
+}
+
+// Source is an email fetched from its source
+type Source struct {
+	Raw string
+}
+
+func (s Source) Content() string {
+	return s.Raw
+}
+
+func (s Source) GetLinks() []inbox.Link {
+	return nil
+}
+
+func (s Source) GetAttachments() []inbox.Attachment {
+	return nil
+}
+
+func (s Source) Message() []byte {
+	return []byte(s.Raw)
+}
+
+// Inbox returns its successive lists of emails at each refresh, the last
+// one being repeated, the lock must be held to change an inbox served
+type Inbox struct {
+	sync.Mutex
+	Lists        [][]inbox.InboxItem
+	RefreshError error
+	Mails        map[string]inbox.Mail
+	DeleteError  error
+
+	RefreshCount int
+	RefreshLimit int
+	FetchedIDs   []string
+	DeletedIDs   []string
+	Flushed      bool
+	items        []inbox.InboxItem
+}
+
+func (i *Inbox) Refresh(limit int) error {
+	i.Lock()
+	defer i.Unlock()
+	i.RefreshLimit = limit
+	if i.RefreshError != nil {
+		return i.RefreshError
+	}
+	if len(i.Lists) > 0 {
+		i.items = i.Lists[min(i.RefreshCount, len(i.Lists)-1)]
+	}
+	i.RefreshCount++
+	return nil
+}
+
+func (i *Inbox) GetMails() []inbox.InboxItem {
+	i.Lock()
+	defer i.Unlock()
+	return i.items
+}
+
+func (i *Inbox) FetchByID(ID string) (inbox.Mail, error) {
+	i.Lock()
+	defer i.Unlock()
+	i.FetchedIDs = append(i.FetchedIDs, ID)
+	mail, ok := i.Mails[ID]
+	if !ok {
+		return nil, fmt.Errorf(`failure when fetching email "%s"`, ID)
+	}
+	return mail, nil
+}
+
+func (i *Inbox) DeleteByID(ID string) error {
+	i.Lock()
+	defer i.Unlock()
+	if i.DeleteError != nil {
+		return i.DeleteError
+	}
+	i.DeletedIDs = append(i.DeletedIDs, ID)
+	return nil
+}
+
+func (i *Inbox) Flush() error {
+	i.Lock()
+	defer i.Unlock()
+	i.Flushed = true
+	return nil
+}
+
+// Server accepts the connections of the clients
+type Server interface {
+	Serve(net.Listener) error
+	Close() error
+}
+
+// Dial serves s on a local address and returns a connection
+// to it, the server is closed at the end of the test
+func Dial(t *testing.T, s Server) net.Conn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(l)
+	}()
+	t.Cleanup(func() {
+		assert.NoError(t, s.Close())
+		assert.NoError(t, <-done)
+	})
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
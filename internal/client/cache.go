@@ -0,0 +1,151 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrOffline is returned for the requests that can't be served from the cache
+var ErrOffline = errors.New("the request can't be performed offline")
+
+const (
+	cacheHTML   = "html"
+	cacheSource = "source"
+	cacheText   = "text"
+	cacheInbox  = "inbox"
+)
+
+// evictionInterval is the time after which the cache is checked
+// again for the entries to remove
+const evictionInterval = time.Hour
+
+// Cache stores the pages fetched on disk, the content of an email
+// never changes so its page is read from the cache once fetched, the
+// inbox pages are only read from the cache when working offline
+type Cache struct {
+	dir     string
+	maxAge  time.Duration
+	maxSize int64
+	now     func() time.Time
+	// mu guards the eviction budget, walking the cache is expensive
+	// so it's only done on the first write, then after the eviction
+	// interval or when a tenth of the maximum size has been written
+	mu        sync.Mutex
+	evictedAt time.Time
+	written   int64
+}
+
+// NewCache creates a cache in dir, the entries older than maxAge are
+// ignored and the oldest entries are removed to stay under maxSize
+// bytes, a zero value disables the limit
+func NewCache(dir string, maxAge time.Duration, maxSize int64) *Cache {
+	return &Cache{dir: dir, maxAge: maxAge, maxSize: maxSize, now: time.Now}
+}
+
+// DefaultCacheDir returns the cache directory of the user
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "yogo"), nil
+}
+
+// get returns a cached page, the kind of the page is a directory
+// and the page name is a hash of the parts identifying the page
+func (c *Cache) get(kind string, parts ...string) ([]byte, bool) {
+	path := c.path(kind, parts...)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if c.maxAge > 0 && c.now().Sub(info.ModTime()) > c.maxAge {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func (c *Cache) put(data []byte, kind string, parts ...string) error {
+	path := c.path(kind, parts...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written += int64(len(data))
+	if !c.evictedAt.IsZero() && c.now().Sub(c.evictedAt) < evictionInterval && (c.maxSize <= 0 || c.written < c.maxSize/10) {
+		return nil
+	}
+	c.evictedAt, c.written = c.now(), 0
+	return c.evict()
+}
+
+// evict removes the expired entries, then the oldest
+// ones until the cache fits in its maximum size
+func (c *Cache) evict() error {
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	entries := []entry{}
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if c.maxAge > 0 && c.now().Sub(info.ModTime()) > c.maxAge {
+			return os.Remove(path)
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil || c.maxSize <= 0 {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.Remove(e.path); err != nil {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+func (c *Cache) path(kind string, parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%s\x00", part)
+	}
+	return filepath.Join(c.dir, kind, hex.EncodeToString(h.Sum(nil)))
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	c := NewCache(t.TempDir(), time.Hour, 0)
+	c.now = func() time.Time { return now }
+
+	_, ok := c.get(cacheHTML, "box1", "ABC")
+	assert.False(t, ok)
+
+	assert.NoError(t, c.put([]byte("html"), cacheHTML, "box1", "ABC"))
+	data, ok := c.get(cacheHTML, "box1", "ABC")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("html"), data)
+
+	// the kinds and the inboxes don't share their entries
+	_, ok = c.get(cacheSource, "box1", "ABC")
+	assert.False(t, ok)
+	_, ok = c.get(cacheHTML, "box2", "ABC")
+	assert.False(t, ok)
+
+	c.now = func() time.Time { return now.Add(2 * time.Hour) }
+	_, ok = c.get(cacheHTML, "box1", "ABC")
+	assert.False(t, ok)
+}
+
+func TestCacheEviction(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	now := time.Now()
+	c := NewCache(dir, 24*time.Hour, 10)
+
+	assert.NoError(t, c.put([]byte("expired"), cacheHTML, "box1", "A"))
+	assert.NoError(t, os.Chtimes(c.path(cacheHTML, "box1", "A"), now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+	assert.NoError(t, c.put([]byte("oldest"), cacheHTML, "box1", "B"))
+	assert.NoError(t, os.Chtimes(c.path(cacheHTML, "box1", "B"), now.Add(-time.Hour), now.Add(-time.Hour)))
+	assert.NoError(t, c.put([]byte("newest"), cacheSource, "box1", "C"))
+	assert.NoError(t, c.evict())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{c.path(cacheSource, "box1", "C")}, paths)
+}
+
+func TestCacheEvictionBudget(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	c := NewCache(t.TempDir(), 24*time.Hour, 100)
+	c.now = func() time.Time { return now }
+	expire := func(parts ...string) {
+		assert.NoError(t, os.Chtimes(c.path(cacheHTML, parts...), now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+	}
+	exists := func(parts ...string) bool {
+		_, err := os.Stat(c.path(cacheHTML, parts...))
+		return err == nil
+	}
+
+	// the first write checks the cache
+	assert.NoError(t, c.put([]byte("a"), cacheHTML, "box1", "A"))
+	expire("box1", "A")
+
+	// a small write doesn't
+	assert.NoError(t, c.put([]byte("b"), cacheHTML, "box1", "B"))
+	assert.True(t, exists("box1", "A"))
+
+	// writing a tenth of the maximum size does
+	assert.NoError(t, c.put([]byte("0123456789"), cacheHTML, "box1", "C"))
+	assert.False(t, exists("box1", "A"))
+	expire("box1", "B")
+
+	// so does a write after the eviction interval
+	assert.NoError(t, c.put([]byte("d"), cacheHTML, "box1", "D"))
+	assert.True(t, exists("box1", "B"))
+	c.now = func() time.Time { return now.Add(2 * evictionInterval) }
+	assert.NoError(t, c.put([]byte("e"), cacheHTML, "box1", "E"))
+	assert.False(t, exists("box1", "B"))
+}
+
+func TestGetMailPageWithCache(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockYopmailSetup()
+	httpmock.RegisterResponder("GET", refURL+"/en/mail?b=box1&id=mABCDEFGH",
+		httpmock.NewStringResponder(200, "<div id='mail'>hello</div>"))
+
+	cache := NewCache(t.TempDir(), 0, 0)
+	c, err := New[MailHTMLDoc](false, WithCache(cache))
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		doc, err := c.GetMailPage("box1", "ABCDEFGH")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", doc.Find("#mail").Text())
+	}
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET "+refURL+"/en/mail?b=box1&id=mABCDEFGH"])
+
+	// the source is a different page
+	httpmock.RegisterResponder("GET", refURL+"/en/mail?b=box1&id=sABCDEFGH",
+		httpmock.NewStringResponder(200, "<pre>source</pre>"))
+	s, err := New[MailSourceDoc](false, WithCache(cache))
+	assert.NoError(t, err)
+	doc, err := s.GetMailPage("box1", "ABCDEFGH")
+	assert.NoError(t, err)
+	assert.Equal(t, "source", doc.Find("pre").Text())
+}
+
+func TestOffline(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockYopmailSetup()
+	httpmock.RegisterResponder("GET", refURL+"/en/inbox?ad=0&ctrl=&d=&id=&login=box1&p=1&r_c=&scrl=&spam=true&v=3.1&yj=ytest&yp=yptest",
+		httpmock.NewStringResponder(200, "w.finrmail(25,2,1,0,0,'alt.zk-4nyqp5l','')<div class='m' id='e_A'></div>"))
+	httpmock.RegisterResponder("GET", refURL+"/en/mail?b=box1&id=mABCDEFGH",
+		httpmock.NewStringResponder(200, "<div id='mail'>hello</div>"))
+
+	_, err := New[MailHTMLDoc](false, WithOffline())
+	assert.EqualError(t, err, "the cache must be enabled to work offline")
+
+	cache := NewCache(t.TempDir(), 0, 0)
+	c, err := New[MailHTMLDoc](false, WithCache(cache), WithInboxCache())
+	assert.NoError(t, err)
+	_, err = c.GetMailsPage("box1", 1)
+	assert.NoError(t, err)
+	_, err = c.GetMailPage("box1", "ABCDEFGH")
+	assert.NoError(t, err)
+
+	httpmock.Reset()
+	o, err := New[MailHTMLDoc](false, WithCache(cache), WithOffline())
+	assert.NoError(t, err)
+	page, err := o.GetMailsPage("box1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Find("div.m").Length())
+	doc, err := o.GetMailPage("box1", "ABCDEFGH")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", doc.Find("#mail").Text())
+
+	_, err = o.GetMailsPage("box1", 2)
+	assert.EqualError(t, err, `page 2 of inbox "box1" is not cached : the request can't be performed offline`)
+	_, err = o.GetMailPage("box1", "IJKLMNOP")
+	assert.EqualError(t, err, `email "IJKLMNOP" is not cached : the request can't be performed offline`)
+	assert.True(t, errors.Is(o.DeleteMail("box1", "ABCDEFGH"), ErrOffline))
+	assert.True(t, errors.Is(o.FlushMail("box1", "ABCDEFGH"), ErrOffline))
+	assert.Equal(t, 0, httpmock.GetTotalCallCount())
+}
+
+func TestInboxCache(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockYopmailSetup()
+	httpmock.RegisterResponder("GET", refURL+"/en/inbox?ad=0&ctrl=&d=&id=&login=box1&p=1&r_c=&scrl=&spam=true&v=3.1&yj=ytest&yp=yptest",
+		httpmock.NewStringResponder(200, "w.finrmail(25,2,1,0,0,'alt.zk-4nyqp5l','')<div class='m' id='e_A'></div>"))
+
+	cache := NewCache(t.TempDir(), 0, 0)
+	c, err := New[MailHTMLDoc](false, WithCache(cache))
+	assert.NoError(t, err)
+	_, err = c.GetMailsPage("box1", 1)
+	assert.NoError(t, err)
+	_, ok := cache.get(cacheInbox, "box1", "1")
+	assert.False(t, ok)
+
+	c, err = New[MailHTMLDoc](false, WithCache(cache), WithInboxCache())
+	assert.NoError(t, err)
+	_, err = c.GetMailsPage("box1", 1)
+	assert.NoError(t, err)
+	_, ok = cache.get(cacheInbox, "box1", "1")
+	assert.True(t, ok)
+}
+
+func TestCacheFailure(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockYopmailSetup()
+	httpmock.RegisterResponder("GET", refURL+"/en/inbox?ad=0&ctrl=&d=&id=&login=box1&p=1&r_c=&scrl=&spam=true&v=3.1&yj=ytest&yp=yptest",
+		httpmock.NewStringResponder(200, "w.finrmail(25,2,1,0,0,'alt.zk-4nyqp5l','')<div class='m' id='e_A'></div>"))
+	httpmock.RegisterResponder("GET", refURL+"/en/mail?b=box1&id=mABCDEFGH",
+		httpmock.NewStringResponder(200, "<div id='mail'>hello</div>"))
+
+	// the cache directory can't be created as a file has the same name
+	dir := filepath.Join(t.TempDir(), "cache")
+	assert.NoError(t, os.WriteFile(dir, []byte{}, 0o644))
+	c, err := New[MailHTMLDoc](false, WithCache(NewCache(dir, 0, 0)), WithInboxCache())
+	assert.NoError(t, err)
+	page, err := c.GetMailsPage("box1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, page.Find("div.m").Length())
+	doc, err := c.GetMailPage("box1", "ABCDEFGH")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", doc.Find("#mail").Text())
+}
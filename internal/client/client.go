@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -46,24 +47,121 @@ type MailTextDoc goquery.Document
 type Client[M MailDoc] struct {
 	browser    *browser
 	apiVersion string
+	options
 }
 
-// New creates a new client
-func New[M MailDoc](enableDebugMode bool) (Client[M], error) {
-	browser := newBrowser(enableDebugMode)
-	c, err := browser.fetch("GET", refURL, map[string]string{}, nil)
+type options struct {
+	cache              *Cache
+	inboxCache         bool
+	offline            bool
+	session            *Session
+	minRequestInterval time.Duration
+}
+
+// Option configures a client
+type Option func(*options)
+
+// WithCache reads the emails from the cache and stores the pages fetched
+func WithCache(cache *Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithInboxCache stores the inbox pages fetched in the cache too, they
+// are read back when working offline, the commands polling an inbox
+// don't use it as they would write a page on every poll
+func WithInboxCache() Option {
+	return func(o *options) {
+		o.inboxCache = true
+	}
+}
+
+// WithOffline serves the pages from the cache only, no request is sent
+func WithOffline() Option {
+	return func(o *options) {
+		o.offline = true
+	}
+}
+
+// WithSession makes the client use an existing session
+// instead of opening a new one
+func WithSession(session *Session) Option {
+	return func(o *options) {
+		o.session = session
+	}
+}
+
+// WithMinRequestInterval spaces out the requests of the session by at least
+// interval, a longer interval defined with YOGO_REQUEST_INTERVAL is kept
+func WithMinRequestInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.minRequestInterval = interval
+	}
+}
+
+// Session holds the cookies, the api version and the rate limiter,
+// several clients created with the same session behave as a single
+// browser, the requests made through a session must not run concurrently
+type Session struct {
+	browser    *browser
+	apiVersion string
+}
+
+// NewSession opens a session, the api version is retrieved
+// from the home page unless the client works offline
+func NewSession(enableDebugMode bool, opts ...Option) (*Session, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	rateLimiter, err := newRateLimiter(o.minRequestInterval)
 	if err != nil {
-		return Client[M]{}, err
+		return nil, err
+	}
+	session := &Session{browser: newBrowser(enableDebugMode, rateLimiter)}
+	if o.offline {
+		return session, nil
 	}
-	apiVersion, err := parseApiVersion(c.String())
+	c, err := session.browser.fetch("GET", refURL, map[string]string{}, nil)
 	if err != nil {
-		return Client[M]{}, err
+		return nil, err
 	}
-	return Client[M]{apiVersion: apiVersion, browser: browser}, nil
+	if session.apiVersion, err = parseApiVersion(c.String()); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// New creates a new client
+func New[M MailDoc](enableDebugMode bool, opts ...Option) (Client[M], error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.offline && o.cache == nil {
+		return Client[M]{}, errors.New("the cache must be enabled to work offline")
+	}
+	session := o.session
+	if session == nil {
+		var err error
+		if session, err = NewSession(enableDebugMode, opts...); err != nil {
+			return Client[M]{}, err
+		}
+	}
+	return Client[M]{apiVersion: session.apiVersion, browser: session.browser, options: o}, nil
 }
 
-// GetMailsPage fetches all html pages containing emails data
+// GetMailsPage fetches all html pages containing emails data, the pages
+// stored in the cache with WithInboxCache are only used when working offline
 func (c Client[M]) GetMailsPage(identifier string, page int) (*goquery.Document, error) {
+	if c.offline {
+		data, ok := c.cache.get(cacheInbox, identifier, strconv.Itoa(page))
+		if !ok {
+			return nil, fmt.Errorf(`page %d of inbox "%s" is not cached : %w`, page, identifier, ErrOffline)
+		}
+		return goquery.NewDocumentFromReader(bytes.NewReader(data))
+	}
 	URL, err := c.decorateURL("inbox?d=&ctrl=&scrl=&spam=true&ad=0&r_c=&id=", c.apiVersion, false, map[string]string{"login": identifier, "p": strconv.Itoa(page)})
 	if err != nil {
 		return nil, err
@@ -77,17 +175,40 @@ func (c Client[M]) GetMailsPage(identifier string, page int) (*goquery.Document,
 	if err != nil {
 		return nil, err
 	}
+	if c.cache != nil && c.inboxCache {
+		// the cache is best effort, a failure must not lose a fetched page
+		_ = c.cache.put(content.Bytes(), cacheInbox, identifier, strconv.Itoa(page))
+	}
 	return goquery.NewDocumentFromReader(content)
 }
 
-// GetMailPage fetches html page containing the email
+// GetMailPage fetches html page containing the email,
+// the page is read from the cache when it is available
 func (c Client[M]) GetMailPage(identifier string, mailID string) (doc M, err error) {
 	var kind mailKind
+	cacheKind := cacheText
 	switch any(doc).(type) {
 	case MailHTMLDoc:
 		kind = mailHTML
+		cacheKind = cacheHTML
 	case MailSourceDoc:
 		kind = mailSource
+		cacheKind = cacheSource
+	case MailTextDoc:
+		kind = mailText
+	}
+	if c.cache != nil {
+		if data, ok := c.cache.get(cacheKind, identifier, mailID); ok {
+			d, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+			if err != nil {
+				return doc, err
+			}
+			return M(*d), nil
+		}
+	}
+	if c.offline {
+		err = fmt.Errorf(`email "%s" is not cached : %w`, mailID, ErrOffline)
+		return
 	}
 	URL, err := c.decorateURL("mail", c.apiVersion, true, map[string]string{"b": identifier, "id": fmt.Sprintf("%s%s", kind, mailID)})
 	if err != nil {
@@ -102,6 +223,10 @@ func (c Client[M]) GetMailPage(identifier string, mailID string) (doc M, err err
 	if err != nil {
 		return
 	}
+	if c.cache != nil {
+		// the cache is best effort, a failure must not lose a fetched page
+		_ = c.cache.put(content.Bytes(), cacheKind, identifier, mailID)
+	}
 	d, err := goquery.NewDocumentFromReader(content)
 	if err != nil {
 		return
@@ -111,6 +236,9 @@ func (c Client[M]) GetMailPage(identifier string, mailID string) (doc M, err err
 
 // DeleteMail removes an email from yopmail inbox
 func (c Client[M]) DeleteMail(identifier string, mailID string) error {
+	if c.offline {
+		return ErrOffline
+	}
 	URL, err := c.decorateURL("inbox?p=1&ctrl=&ad=0&r_c=&id=", c.apiVersion, false, map[string]string{"login": identifier, "d": mailID})
 	if err != nil {
 		return err
@@ -125,6 +253,9 @@ func (c Client[M]) DeleteMail(identifier string, mailID string) error {
 
 // FlushMail removes all yopmail inbox mails
 func (c Client[M]) FlushMail(identifier string, mailID string) error {
+	if c.offline {
+		return ErrOffline
+	}
 	URL, err := c.decorateURL("inbox?p=1&d=all&ad=0&r_c=&id=", c.apiVersion, false, map[string]string{"login": identifier, "ctrl": mailID})
 	if err != nil {
 		return err
@@ -185,13 +316,15 @@ type browser struct {
 	cookies           map[string]string
 	enableDebugMode   bool
 	httpClientFactory httpClientFactory
+	rateLimiter       *rateLimiter
 }
 
-func newBrowser(enableDebugMode bool) *browser {
+func newBrowser(enableDebugMode bool, rateLimiter *rateLimiter) *browser {
 	return &browser{
 		cookies:           map[string]string{},
 		enableDebugMode:   enableDebugMode,
 		httpClientFactory: httpClientFactory{},
+		rateLimiter:       rateLimiter,
 	}
 }
 
@@ -246,6 +379,7 @@ func (b *browser) fetch(method string, URL string, headers map[string]string, bo
 	if err != nil {
 		return nil, wrapError(errMsg, err)
 	}
+	b.rateLimiter.wait()
 	res, err := c.Do(r)
 	if err != nil {
 		return nil, wrapError(errMsg, err)
@@ -344,3 +478,34 @@ func (h httpClientFactory) create() (*http.Client, error) {
 	}
 	return client, nil
 }
+
+// rateLimiter spaces out the requests, too many calls
+// in a short period of time trigger a CAPTCHA
+type rateLimiter struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	last     time.Time
+}
+
+// newRateLimiter reads the interval from YOGO_REQUEST_INTERVAL, it's
+// raised to minInterval when it's shorter
+func newRateLimiter(minInterval time.Duration) (*rateLimiter, error) {
+	var interval time.Duration
+	if os.Getenv("YOGO_REQUEST_INTERVAL") != "" {
+		i, err := strconv.Atoi(os.Getenv("YOGO_REQUEST_INTERVAL"))
+		if err != nil {
+			return nil, err
+		}
+		interval = time.Duration(i) * time.Millisecond
+	}
+	return &rateLimiter{interval: max(interval, minInterval)}, nil
+}
+
+func (r *rateLimiter) wait() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if d := r.interval - time.Since(r.last); d > 0 {
+		time.Sleep(d)
+	}
+	r.last = time.Now()
+}
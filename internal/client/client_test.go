@@ -80,7 +80,7 @@ func TestFetchDocument(t *testing.T) {
 			},
 		}} {
 		t.Run(s.name, func(t *testing.T) {
-			b := newBrowser(false)
+			b := newBrowser(false, &rateLimiter{})
 
 			s.setup()
 			s.test(b.fetchDocument("GET", "http://abcdefg.com", map[string]string{}, nil))
@@ -142,7 +142,7 @@ func TestFetch(t *testing.T) {
 		},
 	}} {
 		t.Run(s.name, func(t *testing.T) {
-			b := newBrowser(false)
+			b := newBrowser(false, &rateLimiter{})
 			s.setup()
 			s.test(b.fetch("GET", "http://hijklm.com", map[string]string{"header1": "value1", "header2": "value2"}, nil))
 			httpmock.Reset()
@@ -394,6 +394,20 @@ func TestGetMailPage(t *testing.T) {
 	}
 }
 
+func TestGetMailTextPage(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockYopmailSetup()
+	httpmock.RegisterResponder("GET", refURL+"/en/mail?b=box1&id=tABCDEFGH",
+		httpmock.NewStringResponder(200, `<div id="mail">Hello</div>`))
+
+	c, err := New[MailTextDoc](false)
+	assert.NoError(t, err)
+	doc, err := c.GetMailPage("box1", "ABCDEFGH")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", doc.Find("div#mail").Text())
+}
+
 func TestDeleteMail(t *testing.T) {
 	type scenario struct {
 		name  string
@@ -575,6 +589,74 @@ func TestHTTPClientFactoryCreate(t *testing.T) {
 	}
 }
 
+func TestRateLimiterWait(t *testing.T) {
+	type scenario struct {
+		name        string
+		setup       func()
+		minInterval time.Duration
+		test        func(time.Duration, error)
+	}
+
+	for _, s := range []scenario{{
+		name:  "No interval defined",
+		setup: func() {},
+		test: func(d time.Duration, err error) {
+			assert.NoError(t, err)
+			assert.Less(t, d, 50*time.Millisecond)
+		},
+	}, {
+		name: "Define an interval",
+		setup: func() {
+			os.Setenv("YOGO_REQUEST_INTERVAL", "100")
+		},
+		test: func(d time.Duration, err error) {
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		},
+	}, {
+		name:        "Define a minimal interval",
+		setup:       func() {},
+		minInterval: 100 * time.Millisecond,
+		test: func(d time.Duration, err error) {
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		},
+	}, {
+		name: "Define an interval longer than the minimal interval",
+		setup: func() {
+			os.Setenv("YOGO_REQUEST_INTERVAL", "150")
+		},
+		minInterval: 100 * time.Millisecond,
+		test: func(d time.Duration, err error) {
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, d, 150*time.Millisecond)
+		},
+	}, {
+		name: "Define a wrong interval",
+		setup: func() {
+			os.Setenv("YOGO_REQUEST_INTERVAL", "a")
+		},
+		test: func(d time.Duration, err error) {
+			assert.EqualError(t, err, `strconv.Atoi: parsing "a": invalid syntax`)
+		},
+	}} {
+		t.Run(s.name, func(t *testing.T) {
+			os.Setenv("YOGO_REQUEST_INTERVAL", "")
+			s.setup()
+			r, err := newRateLimiter(s.minInterval)
+			os.Setenv("YOGO_REQUEST_INTERVAL", "")
+			if err != nil {
+				s.test(0, err)
+				return
+			}
+			r.last = time.Now()
+			start := time.Now()
+			r.wait()
+			s.test(time.Since(start), nil)
+		})
+	}
+}
+
 func TestCheckInboxCAPTCHA(t *testing.T) {
 	type scenario struct {
 		name string
@@ -614,3 +696,39 @@ func mockYopmailSetup() {
 	httpmock.RegisterResponder("GET", refURL,
 		httpmock.NewStringResponder(200, `<script src="/ver/3.1/webmail.js"></script><input id="yp" value="yptest">`))
 }
+
+func TestSession(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockYopmailSetup()
+	httpmock.RegisterResponder("GET", refURL+"/en/mail?b=box1&id=mABCDEFGH",
+		httpmock.NewStringResponder(200, "<div id='mail'>hello</div>"))
+	httpmock.RegisterResponder("GET", refURL+"/en/mail?b=box1&id=sABCDEFGH",
+		httpmock.NewStringResponder(200, "<div id='mail'><pre>Subject: hello</pre></div>"))
+
+	session, err := NewSession(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.1", session.apiVersion)
+
+	html, err := New[MailHTMLDoc](false, WithSession(session))
+	assert.NoError(t, err)
+	source, err := New[MailSourceDoc](false, WithSession(session))
+	assert.NoError(t, err)
+	assert.Same(t, html.browser, source.browser)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET "+refURL])
+
+	_, err = html.GetMailPage("box1", "ABCDEFGH")
+	assert.NoError(t, err)
+	_, err = source.GetMailPage("box1", "ABCDEFGH")
+	assert.NoError(t, err)
+
+	httpmock.Reset()
+	httpmock.RegisterResponder("GET", refURL, httpmock.NewStringResponder(200, ""))
+	_, err = NewSession(false)
+	assert.EqualError(t, err, "api version could not be extracted")
+	offline, err := NewSession(false, WithOffline())
+	assert.NoError(t, err)
+	assert.Equal(t, "", offline.apiVersion)
+}
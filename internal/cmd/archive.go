@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/archive"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+)
+
+type archiveOpener func() (*archive.Store, error)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Search the emails recorded in the local archive",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var archiveSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the archived emails having a word starting with each word of the query in their subject, sender or body",
+	RunE:  archiveSearch(openArchive),
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var archiveShowCmd = &cobra.Command{
+	Use:               "show <inbox> <id>",
+	Short:             "Show an archived email",
+	RunE:              archiveShow(openArchive),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addArchiveSearchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("inbox", "", "Only search the emails of this inbox")
+	addOutputFlag(cmd)
+}
+
+func addArchiveShowFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("source", false, "Display the original message when the source was fetched")
+	addOutputFlag(cmd)
+}
+
+// openArchive opens the archive once per process, the expired
+// records are removed when the archive is opened
+var openArchive = sharedArchive(newArchive)
+
+// sharedArchive returns the store opened by the first successful call
+func sharedArchive(archiveOpener archiveOpener) archiveOpener {
+	var mu sync.Mutex
+	var store *archive.Store
+	return func() (*archive.Store, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if store != nil {
+			return store, nil
+		}
+		s, err := archiveOpener()
+		if err != nil {
+			return nil, err
+		}
+		store = s
+		return store, nil
+	}
+}
+
+// newArchive opens the archive defined through the
+// YOGO_ARCHIVE_DIR and YOGO_ARCHIVE_RETENTION variables
+func newArchive() (*archive.Store, error) {
+	dir := os.Getenv("YOGO_ARCHIVE_DIR")
+	if dir == "" {
+		var err error
+		if dir, err = archive.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	var retention time.Duration
+	if days := os.Getenv("YOGO_ARCHIVE_RETENTION"); days != "" {
+		d, err := strconv.Atoi(days)
+		if err != nil || d < 0 {
+			return nil, errors.New("YOGO_ARCHIVE_RETENTION must be a positive number of days")
+		}
+		retention = time.Duration(d) * 24 * time.Hour
+	}
+	return archive.Open(dir, retention)
+}
+
+// archivedInbox records in the archive the emails
+// listed and fetched through the decorated inbox
+type archivedInbox struct {
+	Inbox
+	name  string
+	store *archive.Store
+}
+
+// withArchive decorates an inbox when the archive is enabled with YOGO_ARCHIVE
+func withArchive(in Inbox, name string, archiveOpener archiveOpener) (Inbox, error) {
+	enabled := os.Getenv("YOGO_ARCHIVE")
+	if enabled == "" {
+		return in, nil
+	}
+	if ok, err := strconv.ParseBool(enabled); err != nil || !ok {
+		return in, err
+	}
+	store, err := archiveOpener()
+	if err != nil {
+		return nil, err
+	}
+	return &archivedInbox{Inbox: in, name: name, store: store}, nil
+}
+
+// unwrapInbox returns the inbox decorated by the archive, the
+// decorator only records the emails so it is rendered as is
+func unwrapInbox(in Inbox) Inbox {
+	if a, ok := in.(*archivedInbox); ok {
+		return a.Inbox
+	}
+	return in
+}
+
+func (a *archivedInbox) ParseInboxPages(limit int) error {
+	if err := a.Inbox.ParseInboxPages(limit); err != nil {
+		return err
+	}
+	return a.store.AddItems(a.name, a.GetMails())
+}
+
+func (a *archivedInbox) Refresh(limit int) error {
+	if err := a.Inbox.Refresh(limit); err != nil {
+		return err
+	}
+	return a.store.AddItems(a.name, a.GetMails())
+}
+
+func (a *archivedInbox) Fetch(offset int) (inbox.Mail, error) {
+	return a.FetchByID(a.GetMails()[offset].ID)
+}
+
+func (a *archivedInbox) FetchByID(ID string) (inbox.Mail, error) {
+	m, err := a.Inbox.FetchByID(ID)
+	if err != nil {
+		return nil, err
+	}
+	return m, a.store.AddMail(a.name, ID, m)
+}
+
+func archiveSearch(archiveOpener archiveOpener) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		renderer, err := newRenderer(cmd)
+		if err != nil {
+			return err
+		}
+		inboxName, err := cmd.Flags().GetString("inbox")
+		if err != nil {
+			return err
+		}
+		if inboxName != "" {
+			inboxName = normalizeInboxName(inboxName)
+		}
+		store, err := archiveOpener()
+		if err != nil {
+			return err
+		}
+		records, err := store.Search(strings.Join(args, " "), inboxName)
+		if err != nil {
+			return err
+		}
+		output, err := renderer.Render(records)
+		if err != nil {
+			return err
+		}
+		cmd.Println(output)
+		return nil
+	}
+}
+
+func archiveShow(archiveOpener archiveOpener) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		renderer, err := newRenderer(cmd)
+		if err != nil {
+			return err
+		}
+		source, err := cmd.Flags().GetBool("source")
+		if err != nil {
+			return err
+		}
+		store, err := archiveOpener()
+		if err != nil {
+			return err
+		}
+		record, err := store.Get(normalizeInboxName(args[0]), args[1])
+		if err != nil {
+			return err
+		}
+		if source {
+			if record.Source == "" {
+				return errors.New("the email source is not archived, it is recorded when the source is fetched")
+			}
+			cmd.Print(record.Source)
+			return nil
+		}
+		output, err := renderer.Render(record)
+		if err != nil {
+			return err
+		}
+		cmd.Println(output)
+		return nil
+	}
+}
+
+func init() {
+	addArchiveSearchFlags(archiveSearchCmd)
+	addArchiveShowFlags(archiveShowCmd)
+	archiveCmd.AddCommand(archiveSearchCmd)
+	archiveCmd.AddCommand(archiveShowCmd)
+	RootCmd.AddCommand(archiveCmd)
+}
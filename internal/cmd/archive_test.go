@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/archive"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newArchiveOpener(t *testing.T) archiveOpener {
+	dir := t.TempDir()
+	return func() (*archive.Store, error) {
+		return archive.Open(dir, 0)
+	}
+}
+
+func TestWithArchive(t *testing.T) {
+	opener := newArchiveOpener(t)
+	in := &InboxMock{
+		refreshItems: [][]inbox.InboxItem{{{ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme"}}}},
+		fetchMail:    MailMock{content: "Your code is 1234"},
+	}
+
+	t.Setenv("YOGO_ARCHIVE", "")
+	decorated, err := withArchive(in, "test", opener)
+	assert.NoError(t, err)
+	assert.Equal(t, in, decorated)
+
+	t.Setenv("YOGO_ARCHIVE", "false")
+	decorated, err = withArchive(in, "test", opener)
+	assert.NoError(t, err)
+	assert.Equal(t, in, decorated)
+
+	t.Setenv("YOGO_ARCHIVE", "yes please")
+	_, err = withArchive(in, "test", opener)
+	assert.EqualError(t, err, `strconv.ParseBool: parsing "yes please": invalid syntax`)
+
+	t.Setenv("YOGO_ARCHIVE", "true")
+	_, err = withArchive(in, "test", func() (*archive.Store, error) {
+		return nil, errors.New("archive error")
+	})
+	assert.EqualError(t, err, "archive error")
+
+	decorated, err = withArchive(in, "test", opener)
+	assert.NoError(t, err)
+	assert.NoError(t, decorated.Refresh(15))
+	m, err := decorated.Fetch(0)
+	assert.NoError(t, err)
+	assert.Equal(t, in.fetchMail, m)
+	assert.Equal(t, []string{"a"}, in.fetchedIDs)
+
+	store, err := opener()
+	assert.NoError(t, err)
+	r, err := store.Get("test", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "Welcome", r.Subject)
+	assert.Equal(t, "Your code is 1234", r.Body)
+}
+
+func TestArchiveSearch(t *testing.T) {
+	type scenario struct {
+		name        string
+		args        []string
+		flags       map[string]string
+		output      string
+		errExpected error
+	}
+
+	opener := newArchiveOpener(t)
+	store, err := opener()
+	assert.NoError(t, err)
+	date := time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)
+	assert.NoError(t, store.AddItems("test", []inbox.InboxItem{{ID: "a", Subject: "Confirm your account", Sender: &inbox.Sender{Name: "Acme"}, Date: &date}}))
+	assert.NoError(t, store.AddItems("other", []inbox.InboxItem{{ID: "b", Subject: "Confirm your order", Sender: &inbox.Sender{Name: "Shop"}, Date: &date}}))
+
+	scenarios := []scenario{
+		{
+			name:   "Words searched",
+			args:   []string{"confirm", "ACCOUNT"},
+			output: " 2024-03-02 10:30 test a Acme : Confirm your account\n",
+		},
+		{
+			name:   "Inbox filtered",
+			args:   []string{"confirm"},
+			flags:  map[string]string{"inbox": "Other@yopmail.com", "output": "csv"},
+			output: "inbox,id,sender_name,sender_mail,subject,date\nother,b,Shop,,Confirm your order,2024-03-02T10:30:00Z\n",
+		},
+		{
+			name:   "No result",
+			args:   []string{"invoice"},
+			output: "No archived email found\n",
+		},
+		{
+			name:        "Invalid output",
+			args:        []string{"invoice"},
+			flags:       map[string]string{"output": "xml"},
+			errExpected: errors.New(`output "xml" is not supported, use one of csv, json, ndjson, table, template, template-file, text, yaml`),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			addArchiveSearchFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetOut(&output)
+			err := archiveSearch(opener)(cmd, scenario.args)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.output, output.String())
+		})
+	}
+}
+
+func TestArchiveShow(t *testing.T) {
+	type scenario struct {
+		name        string
+		args        []string
+		flags       map[string]string
+		output      string
+		errExpected error
+	}
+
+	opener := newArchiveOpener(t)
+	store, err := opener()
+	assert.NoError(t, err)
+	date := time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)
+	assert.NoError(t, store.AddItems("test", []inbox.InboxItem{
+		{ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme"}, Date: &date},
+		{ID: "b", Subject: "Listed only", Date: &date},
+	}))
+	assert.NoError(t, store.AddMail("test", "a", &SourceMailMock{message: []byte("Subject: Welcome\r\n\r\nHello")}))
+
+	scenarios := []scenario{
+		{
+			name: "Email shown",
+			args: []string{"Test", "a"},
+			output: `---
+Inbox   : test
+From    : Acme
+Subject : Welcome
+Date    : 2024-03-02 10:30
+---
+[no data to display]
+---
+
+`,
+		},
+		{
+			name:   "Source shown",
+			args:   []string{"test", "a"},
+			flags:  map[string]string{"source": "true"},
+			output: "Subject: Welcome\r\n\r\nHello",
+		},
+		{
+			name:        "Source not archived",
+			args:        []string{"test", "b"},
+			flags:       map[string]string{"source": "true"},
+			errExpected: errors.New("the email source is not archived, it is recorded when the source is fetched"),
+		},
+		{
+			name:        "Email not archived",
+			args:        []string{"test", "c"},
+			errExpected: errors.New(`email "c" of inbox "test" is not archived`),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			addArchiveShowFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetOut(&output)
+			err := archiveShow(opener)(cmd, scenario.args)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.output, output.String())
+		})
+	}
+}
+
+func TestNewArchive(t *testing.T) {
+	t.Setenv("YOGO_ARCHIVE_DIR", t.TempDir())
+	t.Setenv("YOGO_ARCHIVE_RETENTION", "30")
+	_, err := newArchive()
+	assert.NoError(t, err)
+
+	t.Setenv("YOGO_ARCHIVE_RETENTION", "-1")
+	_, err = newArchive()
+	assert.EqualError(t, err, "YOGO_ARCHIVE_RETENTION must be a positive number of days")
+}
+
+func TestSharedArchive(t *testing.T) {
+	t.Parallel()
+	store, err := archive.Open(t.TempDir(), 0)
+	require.NoError(t, err)
+	calls := 0
+	opener := sharedArchive(func() (*archive.Store, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("archive error")
+		}
+		return store, nil
+	})
+
+	_, err = opener()
+	assert.EqualError(t, err, "archive error")
+	for i := 0; i < 2; i++ {
+		s, err := opener()
+		assert.NoError(t, err)
+		assert.Same(t, store, s)
+	}
+	assert.Equal(t, 2, calls)
+}
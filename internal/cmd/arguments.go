@@ -25,6 +25,7 @@ func parseOffset(offset string) (int, error) {
 }
 
 func normalizeInboxName(inboxName string) string {
+	inboxName = resolveInboxAlias(inboxName)
 	// Providing an uppercased email triggers a panic.
 	// In the web interface there is a redirection to
 	// the inbox with the address lowercased so we mimic
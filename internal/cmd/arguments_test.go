@@ -107,3 +107,11 @@ func TestCheckOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeInboxNameWithAlias(t *testing.T) {
+	inboxAliases = map[string]string{"staging-signup": "QA-Signup-7f3k2@yopmail.com"}
+	defer func() { inboxAliases = map[string]string{} }()
+
+	assert.Equal(t, "qa-signup-7f3k2", normalizeInboxName("staging-signup"))
+	assert.Equal(t, "test", normalizeInboxName("Test"))
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultCacheMaxAge  = 30
+	defaultCacheMaxSize = 100
+)
+
+var disableCache = false
+var offlineMode = false
+
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&disableCache, "no-cache", false, "Fetch the email without using the cache")
+	cmd.Flags().BoolVar(&offlineMode, "offline", false, "Read the inbox and the email from the cache without sending any request")
+}
+
+// clientOptions configures the cache of the client from the flags and
+// the YOGO_CACHE, YOGO_CACHE_DIR, YOGO_CACHE_MAX_AGE (days) and
+// YOGO_CACHE_MAX_SIZE (megabytes) environment variables
+func clientOptions() ([]client.Option, error) {
+	if disableCache && offlineMode {
+		return nil, errors.New("offline mode requires the cache, --offline can't be combined with --no-cache")
+	}
+	if disableCache {
+		return nil, nil
+	}
+	if enabled := os.Getenv("YOGO_CACHE"); enabled != "" {
+		ok, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("YOGO_CACHE must be a boolean : %w", err)
+		}
+		if !ok && offlineMode {
+			return nil, errors.New("offline mode requires the cache, it is disabled with YOGO_CACHE")
+		}
+		if !ok {
+			return nil, nil
+		}
+	}
+	dir := os.Getenv("YOGO_CACHE_DIR")
+	if dir == "" {
+		var err error
+		if dir, err = client.DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	maxAge, err := positiveIntEnv("YOGO_CACHE_MAX_AGE", defaultCacheMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := positiveIntEnv("YOGO_CACHE_MAX_SIZE", defaultCacheMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	opts := []client.Option{client.WithCache(client.NewCache(dir, time.Duration(maxAge)*24*time.Hour, int64(maxSize)*1024*1024))}
+	if offlineMode {
+		opts = append(opts, client.WithOffline())
+	}
+	return opts, nil
+}
+
+func positiveIntEnv(name string, defaultValue int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return i, nil
+}
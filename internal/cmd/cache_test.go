@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientOptions(t *testing.T) {
+	type scenario struct {
+		name         string
+		noCache      bool
+		offline      bool
+		envs         map[string]string
+		optionsCount int
+		errExpected  error
+	}
+
+	scenarios := []scenario{
+		{
+			name:         "Cache enabled by default",
+			optionsCount: 1,
+		},
+		{
+			name:         "Offline",
+			offline:      true,
+			optionsCount: 2,
+		},
+		{
+			name:    "Cache disabled with the flag",
+			noCache: true,
+		},
+		{
+			name: "Cache disabled with the environment",
+			envs: map[string]string{"YOGO_CACHE": "false"},
+		},
+		{
+			name:        "Offline without cache",
+			noCache:     true,
+			offline:     true,
+			errExpected: errors.New("offline mode requires the cache, --offline can't be combined with --no-cache"),
+		},
+		{
+			name:        "Offline with the cache disabled in the environment",
+			offline:     true,
+			envs:        map[string]string{"YOGO_CACHE": "0"},
+			errExpected: errors.New("offline mode requires the cache, it is disabled with YOGO_CACHE"),
+		},
+		{
+			name:        "Invalid boolean",
+			envs:        map[string]string{"YOGO_CACHE": "maybe"},
+			errExpected: errors.New(`YOGO_CACHE must be a boolean : strconv.ParseBool: parsing "maybe": invalid syntax`),
+		},
+		{
+			name:        "Invalid maximum age",
+			envs:        map[string]string{"YOGO_CACHE_MAX_AGE": "-1"},
+			errExpected: errors.New("YOGO_CACHE_MAX_AGE must be a positive integer"),
+		},
+		{
+			name:        "Invalid maximum size",
+			envs:        map[string]string{"YOGO_CACHE_MAX_SIZE": "big"},
+			errExpected: errors.New("YOGO_CACHE_MAX_SIZE must be a positive integer"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			for _, env := range []string{"YOGO_CACHE", "YOGO_CACHE_MAX_AGE", "YOGO_CACHE_MAX_SIZE"} {
+				t.Setenv(env, "")
+			}
+			t.Setenv("YOGO_CACHE_DIR", t.TempDir())
+			for k, v := range scenario.envs {
+				t.Setenv(k, v)
+			}
+			disableCache, offlineMode = scenario.noCache, scenario.offline
+			defer func() { disableCache, offlineMode = false, false }()
+
+			opts, err := clientOptions()
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, opts, scenario.optionsCount)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type configPathGetter func() (string, error)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Handle the configuration file",
+	// the settings are displayed as defined by the user,
+	// they must not be merged with the configuration
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the settings with their origin",
+	RunE:  configList(config.Path),
+	Args:  cobra.NoArgs,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Get the value of a setting",
+	RunE:  configGet(config.Path),
+	Args:  cobra.ExactArgs(1),
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a setting in the configuration file, in the selected profile if any, an empty value removes it",
+	RunE:  configSet(config.Path),
+	Args:  cobra.ExactArgs(2),
+}
+
+type setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// selectedProfile returns the profile chosen with the
+// profile flag or the YOGO_PROFILE environment variable
+func selectedProfile(cmd *cobra.Command) (string, error) {
+	if cmd.Flags().Lookup("profile") != nil {
+		profile, err := cmd.Flags().GetString("profile")
+		if err != nil || profile != "" {
+			return profile, err
+		}
+	}
+	return os.Getenv("YOGO_PROFILE"), nil
+}
+
+func loadConfig(cmd *cobra.Command, configPath configPathGetter) (config.Config, string, string, error) {
+	path, err := configPath()
+	if err != nil {
+		return config.Config{}, "", "", err
+	}
+	c, err := config.Load(path)
+	if err != nil {
+		return config.Config{}, "", "", err
+	}
+	profile, err := selectedProfile(cmd)
+	if err != nil {
+		return config.Config{}, "", "", err
+	}
+	return c, path, profile, nil
+}
+
+// applyConfig defines the environment variables not provided by the user
+// from the configuration file, the precedence is flag > env > profile > defaults
+func applyConfig(configPath configPathGetter) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		c, _, profile, err := loadConfig(cmd, configPath)
+		if err != nil {
+			return err
+		}
+		settings, err := c.Resolve(profile)
+		if err != nil {
+			return err
+		}
+		inboxAliases = c.Aliases
+		return config.Apply(settings)
+	}
+}
+
+func resolveSetting(c config.Config, profile string, key config.Key) setting {
+	s := setting{Key: key.Name}
+	if v, ok := config.Env(key); ok {
+		s.Value, s.Source = v, "env"
+		return s
+	}
+	if v, ok := c.Profiles[profile][key.Name]; ok && profile != "" {
+		s.Value, s.Source = v, fmt.Sprintf("profile %s", profile)
+		return s
+	}
+	if v, ok := c.Defaults[key.Name]; ok {
+		s.Value, s.Source = v, "config"
+		return s
+	}
+	s.Source = "default"
+	return s
+}
+
+func configList(configPath configPathGetter) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		c, path, profile, err := loadConfig(cmd, configPath)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Resolve(profile); err != nil {
+			return err
+		}
+		settings := []setting{}
+		for _, key := range config.Keys {
+			settings = append(settings, resolveSetting(c, profile, key))
+		}
+		if dumpJSON {
+			data, err := json.Marshal(settings)
+			if err != nil {
+				return err
+			}
+			cmd.Println(string(data))
+			return nil
+		}
+		cmd.Println(info(fmt.Sprintf("Configuration file: %s", path)))
+		for _, s := range settings {
+			cmd.Println(fmt.Sprintf(" %-18s %s %s", s.Key, color.CyanString(s.Value), color.YellowString("(%s)", s.Source)))
+		}
+		if profiles := c.ProfileNames(); len(profiles) > 0 {
+			cmd.Println(info(fmt.Sprintf("Profiles: %v", profiles)))
+		}
+		return nil
+	}
+}
+
+func configGet(configPath configPathGetter) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		key, err := config.GetKey(args[0])
+		if err != nil {
+			return err
+		}
+		c, _, profile, err := loadConfig(cmd, configPath)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Resolve(profile); err != nil {
+			return err
+		}
+		cmd.Println(resolveSetting(c, profile, key).Value)
+		return nil
+	}
+}
+
+func configSet(configPath configPathGetter) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		c, path, profile, err := loadConfig(cmd, configPath)
+		if err != nil {
+			return err
+		}
+		if err := c.Set(profile, args[0], args[1]); err != nil {
+			return err
+		}
+		if err := c.Save(path); err != nil {
+			return err
+		}
+		if args[1] == "" {
+			cmd.Println(success(fmt.Sprintf(`Key "%s" removed`, args[0])))
+			return nil
+		}
+		cmd.Println(success(fmt.Sprintf(`Key "%s" set`, args[0])))
+		return nil
+	}
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	RootCmd.AddCommand(configCmd)
+}
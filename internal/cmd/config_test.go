@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+const configContent = `timeout: 20
+output: json
+profiles:
+  work:
+    timeout: 30
+`
+
+func newConfigTestCmd(t *testing.T, profile string) (*cobra.Command, *bytes.Buffer) {
+	var output bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.Flags().String("profile", "", "")
+	assert.NoError(t, cmd.Flags().Set("profile", profile))
+	cmd.SetOut(&output)
+	return cmd, &output
+}
+
+func writeConfig(t *testing.T, content string) configPathGetter {
+	path := filepath.Join(t.TempDir(), "yogo", "config.yaml")
+	if content != "" {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return func() (string, error) {
+		return path, nil
+	}
+}
+
+func unsetConfigEnvs(t *testing.T) {
+	for _, env := range []string{"YOGO_PROFILE", "YOGO_REQUEST_TIMEOUT", "YOGO_USER_AGENT", "YOGO_OUTPUT", "YOGO_REQUEST_INTERVAL", "YOGO_ARCHIVE", "YOGO_ARCHIVE_DIR", "YOGO_ARCHIVE_RETENTION", "YOGO_CACHE", "YOGO_CACHE_DIR", "YOGO_CACHE_MAX_AGE", "YOGO_CACHE_MAX_SIZE", "HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	type scenario struct {
+		name        string
+		profile     string
+		envs        map[string]string
+		expected    map[string]string
+		errExpected error
+	}
+
+	scenarios := []scenario{
+		{
+			name:     "Top level settings",
+			expected: map[string]string{"YOGO_REQUEST_TIMEOUT": "20", "YOGO_OUTPUT": "json"},
+		},
+		{
+			name:     "Profile overrides the top level settings",
+			profile:  "work",
+			expected: map[string]string{"YOGO_REQUEST_TIMEOUT": "30", "YOGO_OUTPUT": "json"},
+		},
+		{
+			name:     "Profile selected through the environment",
+			envs:     map[string]string{"YOGO_PROFILE": "work"},
+			expected: map[string]string{"YOGO_REQUEST_TIMEOUT": "30"},
+		},
+		{
+			name:     "Environment overrides the configuration",
+			profile:  "work",
+			envs:     map[string]string{"YOGO_REQUEST_TIMEOUT": "5"},
+			expected: map[string]string{"YOGO_REQUEST_TIMEOUT": "5"},
+		},
+		{
+			name:        "Unknown profile",
+			profile:     "home",
+			errExpected: errors.New(`profile "home" is not defined`),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			unsetConfigEnvs(t)
+			for k, v := range scenario.envs {
+				t.Setenv(k, v)
+			}
+			cmd, _ := newConfigTestCmd(t, scenario.profile)
+			err := applyConfig(writeConfig(t, configContent))(cmd, []string{})
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+				return
+			}
+			assert.NoError(t, err)
+			for k, v := range scenario.expected {
+				assert.Equal(t, v, os.Getenv(k))
+			}
+		})
+	}
+}
+
+func TestConfigList(t *testing.T) {
+	unsetConfigEnvs(t)
+	t.Setenv("YOGO_USER_AGENT", "yogo")
+	path := writeConfig(t, configContent)
+	p, err := path()
+	assert.NoError(t, err)
+
+	cmd, output := newConfigTestCmd(t, "work")
+	assert.NoError(t, configList(path)(cmd, []string{}))
+	assert.Equal(t, `Configuration file: `+p+`
+ timeout            30 (profile work)
+ user-agent         yogo (env)
+ proxy               (default)
+ output             json (config)
+ request-interval    (default)
+ archive             (default)
+ archive-dir         (default)
+ archive-retention   (default)
+ cache               (default)
+ cache-dir           (default)
+ cache-max-age       (default)
+ cache-max-size      (default)
+Profiles: [work]
+`, output.String())
+
+	dumpJSON = true
+	defer func() { dumpJSON = false }()
+	cmd, output = newConfigTestCmd(t, "")
+	assert.NoError(t, configList(path)(cmd, []string{}))
+	assert.Equal(t, `[{"key":"timeout","value":"20","source":"config"},{"key":"user-agent","value":"yogo","source":"env"},{"key":"proxy","value":"","source":"default"},{"key":"output","value":"json","source":"config"},{"key":"request-interval","value":"","source":"default"},{"key":"archive","value":"","source":"default"},{"key":"archive-dir","value":"","source":"default"},{"key":"archive-retention","value":"","source":"default"},{"key":"cache","value":"","source":"default"},{"key":"cache-dir","value":"","source":"default"},{"key":"cache-max-age","value":"","source":"default"},{"key":"cache-max-size","value":"","source":"default"}]
+`, output.String())
+}
+
+func TestConfigGet(t *testing.T) {
+	unsetConfigEnvs(t)
+	path := writeConfig(t, configContent)
+
+	cmd, output := newConfigTestCmd(t, "work")
+	assert.NoError(t, configGet(path)(cmd, []string{"timeout"}))
+	assert.Equal(t, "30\n", output.String())
+
+	cmd, _ = newConfigTestCmd(t, "")
+	assert.EqualError(t, configGet(path)(cmd, []string{"colour"}), `key "colour" is not supported, use one of timeout, user-agent, proxy, output, request-interval, archive, archive-dir, archive-retention, cache, cache-dir, cache-max-age, cache-max-size`)
+}
+
+func TestConfigSet(t *testing.T) {
+	unsetConfigEnvs(t)
+	path := writeConfig(t, "")
+	p, err := path()
+	assert.NoError(t, err)
+
+	cmd, output := newConfigTestCmd(t, "")
+	assert.NoError(t, configSet(path)(cmd, []string{"output", "yaml"}))
+	assert.Equal(t, "Key \"output\" set\n", output.String())
+
+	cmd, _ = newConfigTestCmd(t, "ci")
+	assert.NoError(t, configSet(path)(cmd, []string{"timeout", "60"}))
+
+	cmd, _ = newConfigTestCmd(t, "")
+	assert.EqualError(t, configSet(path)(cmd, []string{"timeout", "-1"}), `value "-1" of key "timeout" is invalid : a positive integer is expected`)
+
+	data, err := os.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, `output: yaml
+profiles:
+  ci:
+    timeout: 60
+`, string(data))
+
+	cmd, output = newConfigTestCmd(t, "")
+	assert.NoError(t, configSet(path)(cmd, []string{"output", ""}))
+	assert.Equal(t, "Key \"output\" removed\n", output.String())
+	data, err = os.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, `profiles:
+  ci:
+    timeout: 60
+`, string(data))
+}
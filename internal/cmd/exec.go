@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/antham/yogo/v4/internal/render"
+	"github.com/spf13/cobra"
+)
+
+// Actions applied once a hook has run
+const (
+	hookContinue = "continue"
+	hookStop     = "stop"
+	hookDelete   = "delete"
+)
+
+// execHook runs a shell command for every new email,
+// the email is fetched and given as JSON on the standard input
+type execHook struct {
+	command   string
+	timeout   time.Duration
+	onSuccess string
+	onFailure string
+	slots     chan struct{}
+	running   sync.WaitGroup
+	shell     []string
+}
+
+// addExecFlags adds the hook flags, the default actions depend on
+// the command, the concurrency is only relevant for commands running
+// several hooks at once
+func addExecFlags(cmd *cobra.Command, onSuccess string, onFailure string, concurrency bool) {
+	cmd.Flags().String("exec", "", "Run this shell command for every new email, with the email as JSON on stdin")
+	cmd.Flags().Duration("exec-timeout", time.Minute, "Maximum duration of a command before it is killed")
+	cmd.Flags().String("exec-on-success", onSuccess, "Action when the command succeeds: continue, stop or delete the email")
+	cmd.Flags().String("exec-on-failure", onFailure, "Action when the command fails: continue or stop")
+	if concurrency {
+		cmd.Flags().Int("exec-concurrency", 1, "Maximum number of commands running at the same time")
+	}
+}
+
+// newExecHookFromFlags returns the hook configured on the command,
+// or nil when no command is provided
+func newExecHookFromFlags(cmd *cobra.Command) (*execHook, error) {
+	flags := cmd.Flags()
+	command, err := flags.GetString("exec")
+	if err != nil {
+		return nil, err
+	}
+	if command == "" {
+		for _, name := range []string{"exec-timeout", "exec-on-success", "exec-on-failure", "exec-concurrency"} {
+			if flags.Changed(name) {
+				return nil, fmt.Errorf(`flag "--%s" requires "--exec"`, name)
+			}
+		}
+		return nil, nil
+	}
+
+	h := &execHook{command: command, shell: []string{"sh", "-c"}}
+	if runtime.GOOS == "windows" {
+		h.shell = []string{"cmd", "/C"}
+	}
+	if h.timeout, err = flags.GetDuration("exec-timeout"); err != nil {
+		return nil, err
+	}
+	if h.timeout <= 0 {
+		return nil, fmt.Errorf(`"--exec-timeout" must be positive, got %s`, h.timeout)
+	}
+	if h.onSuccess, err = flags.GetString("exec-on-success"); err != nil {
+		return nil, err
+	}
+	if h.onSuccess != hookContinue && h.onSuccess != hookStop && h.onSuccess != hookDelete {
+		return nil, fmt.Errorf(`"--exec-on-success" must be one of continue, stop or delete, got "%s"`, h.onSuccess)
+	}
+	if h.onFailure, err = flags.GetString("exec-on-failure"); err != nil {
+		return nil, err
+	}
+	if h.onFailure != hookContinue && h.onFailure != hookStop {
+		return nil, fmt.Errorf(`"--exec-on-failure" must be one of continue or stop, got "%s"`, h.onFailure)
+	}
+	concurrency := 1
+	if flags.Lookup("exec-concurrency") != nil {
+		if concurrency, err = flags.GetInt("exec-concurrency"); err != nil {
+			return nil, err
+		}
+		if concurrency < 1 {
+			return nil, fmt.Errorf(`"--exec-concurrency" must be at least 1, got %d`, concurrency)
+		}
+	}
+	h.slots = make(chan struct{}, concurrency)
+	return h, nil
+}
+
+// start runs the hook in the background as soon as a slot is free,
+// done receives the action to apply and the error stopping the command,
+// the outputs must be safe for concurrent use
+func (h *execHook) start(ctx context.Context, out io.Writer, errOut io.Writer, in Inbox, name string, p polledItem, done func(string, error)) {
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	h.running.Add(1)
+	go func() {
+		defer func() {
+			<-h.slots
+			h.running.Done()
+		}()
+		done(h.run(ctx, out, errOut, in, name, p))
+	}()
+}
+
+// wait blocks until all the started hooks are over
+func (h *execHook) wait() {
+	h.running.Wait()
+}
+
+// run executes the hook and applies its outcome, it returns the
+// action taken and an error when a failure must stop the command
+func (h *execHook) run(ctx context.Context, out io.Writer, errOut io.Writer, in Inbox, name string, p polledItem) (string, error) {
+	if err := h.execute(ctx, out, errOut, in, name, p); err != nil {
+		if ctx.Err() != nil {
+			return hookStop, nil
+		}
+		if h.onFailure == hookStop {
+			return hookStop, fmt.Errorf(`command failed for email "%s" : %w`, p.item.ID, err)
+		}
+		fmt.Fprintf(errOut, "Command failed for email \"%s\" : %s\n", p.item.ID, err)
+		return hookContinue, nil
+	}
+	if h.onSuccess == hookDelete {
+		if err := in.DeleteByID(p.item.ID); err != nil {
+			fmt.Fprintf(errOut, "Email \"%s\" can't be deleted : %s\n", p.item.ID, err)
+		}
+	}
+	return h.onSuccess, nil
+}
+
+func (h *execHook) execute(ctx context.Context, out io.Writer, errOut io.Writer, in Inbox, name string, p polledItem) error {
+	mail, err := in.FetchByID(p.item.ID)
+	if err != nil {
+		return err
+	}
+	data, err := mailJSON(mail)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, h.shell[0], append(h.shell[1:], h.command)...)
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	c.WaitDelay = time.Second
+	c.Env = append(os.Environ(),
+		"YOGO_INBOX="+name,
+		"YOGO_MAIL_ID="+p.item.ID,
+		"YOGO_SUBJECT="+p.item.Subject,
+		"YOGO_FROM="+p.item.Sender.String(),
+	)
+	err = c.Run()
+	// Write the whole output at once so outputs of concurrent commands don't mix
+	_, _ = out.Write(stdout.Bytes())
+	_, _ = errOut.Write(stderr.Bytes())
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", h.timeout)
+	}
+	return err
+}
+
+// mailJSON returns the JSON document of an email as rendered with --json
+func mailJSON(mail inbox.Mail) ([]byte, error) {
+	if j, ok := mail.(render.JSONer); ok {
+		s, err := j.JSON()
+		return []byte(s), err
+	}
+	return json.Marshal(mail)
+}
+
+// syncWriter serializes the writes made to the underlying writer,
+// the lock can be shared between several writers
+type syncWriter struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// syncWriters wraps the outputs of a command written from
+// several goroutines, both outputs share the same lock
+func syncWriters(out io.Writer, errOut io.Writer) (io.Writer, io.Writer) {
+	mu := &sync.Mutex{}
+	return &syncWriter{w: out, mu: mu}, &syncWriter{w: errOut, mu: mu}
+}
+
+// lockedInbox serializes the calls made to an inbox
+// shared between the poller and the running hooks
+type lockedInbox struct {
+	in Inbox
+	mu sync.Mutex
+}
+
+func (l *lockedInbox) ParseInboxPages(limit int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.ParseInboxPages(limit)
+}
+
+func (l *lockedInbox) Refresh(limit int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.Refresh(limit)
+}
+
+func (l *lockedInbox) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.Count()
+}
+
+// GetMails returns a copy of the emails, deleting an email
+// compacts the emails of the inbox while they are read
+func (l *lockedInbox) GetMails() []inbox.InboxItem {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.in.GetMails())
+}
+
+func (l *lockedInbox) Fetch(offset int) (inbox.Mail, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.Fetch(offset)
+}
+
+func (l *lockedInbox) FetchByID(ID string) (inbox.Mail, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.FetchByID(ID)
+}
+
+func (l *lockedInbox) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.Flush()
+}
+
+func (l *lockedInbox) Delete(offset int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.Delete(offset)
+}
+
+func (l *lockedInbox) DeleteByID(ID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.in.DeleteByID(ID)
+}
@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExecHookFromFlags(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		nilExpected bool
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:        "No command",
+			nilExpected: true,
+		},
+		{
+			name:        "Option without command",
+			flags:       map[string]string{"exec-concurrency": "2"},
+			errExpected: `flag "--exec-concurrency" requires "--exec"`,
+		},
+		{
+			name:        "Invalid timeout",
+			flags:       map[string]string{"exec": "true", "exec-timeout": "0s"},
+			errExpected: `"--exec-timeout" must be positive, got 0s`,
+		},
+		{
+			name:        "Invalid success action",
+			flags:       map[string]string{"exec": "true", "exec-on-success": "flush"},
+			errExpected: `"--exec-on-success" must be one of continue, stop or delete, got "flush"`,
+		},
+		{
+			name:        "Invalid failure action",
+			flags:       map[string]string{"exec": "true", "exec-on-failure": "delete"},
+			errExpected: `"--exec-on-failure" must be one of continue or stop, got "delete"`,
+		},
+		{
+			name:        "Invalid concurrency",
+			flags:       map[string]string{"exec": "true", "exec-concurrency": "0"},
+			errExpected: `"--exec-concurrency" must be at least 1, got 0`,
+		},
+		{
+			name:  "Valid command",
+			flags: map[string]string{"exec": "true", "exec-on-success": "delete", "exec-concurrency": "4"},
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addExecFlags(cmd, hookContinue, hookContinue, true)
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			h, err := newExecHookFromFlags(cmd)
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.nilExpected, h == nil)
+		})
+	}
+}
+
+func TestExecHookRun(t *testing.T) {
+	type scenario struct {
+		name           string
+		command        string
+		flags          map[string]string
+		fetchError     error
+		deleteError    error
+		actionExpected string
+		errExpected    string
+		output         string
+		outputErr      string
+		deletedIDs     []string
+	}
+	scenarios := []scenario{
+		{
+			name:           "Email given on stdin and in the environment",
+			command:        `cat; echo " $YOGO_INBOX|$YOGO_MAIL_ID|$YOGO_SUBJECT|$YOGO_FROM"`,
+			actionExpected: hookContinue,
+			output:         `{"id":"a"} test|a|Welcome|Acme <hello@acme.com>` + "\n",
+		},
+		{
+			name:           "Failure reported",
+			command:        "echo oops >&2; exit 3",
+			actionExpected: hookContinue,
+			outputErr:      "oops\nCommand failed for email \"a\" : exit status 3\n",
+		},
+		{
+			name:           "Failure stops",
+			command:        "exit 3",
+			flags:          map[string]string{"exec-on-failure": "stop"},
+			actionExpected: hookStop,
+			errExpected:    `command failed for email "a" : exit status 3`,
+		},
+		{
+			name:           "Timeout reached",
+			command:        "sleep 5",
+			flags:          map[string]string{"exec-timeout": "100ms"},
+			actionExpected: hookContinue,
+			outputErr:      "Command failed for email \"a\" : timed out after 100ms\n",
+		},
+		{
+			name:           "Email can't be fetched",
+			command:        "true",
+			fetchError:     errors.New("fetch error"),
+			actionExpected: hookContinue,
+			outputErr:      "Command failed for email \"a\" : fetch error\n",
+		},
+		{
+			name:           "Success stops",
+			command:        "true",
+			flags:          map[string]string{"exec-on-success": "stop"},
+			actionExpected: hookStop,
+		},
+		{
+			name:           "Email deleted on success",
+			command:        "true",
+			flags:          map[string]string{"exec-on-success": "delete"},
+			actionExpected: hookDelete,
+			deletedIDs:     []string{"a"},
+		},
+		{
+			name:           "Email deletion fails",
+			command:        "true",
+			flags:          map[string]string{"exec-on-success": "delete"},
+			deleteError:    errors.New("delete error"),
+			actionExpected: hookDelete,
+			outputErr:      "Email \"a\" can't be deleted : delete error\n",
+			deletedIDs:     []string{"a"},
+		},
+		{
+			name:           "Email not deleted on failure",
+			command:        "false",
+			flags:          map[string]string{"exec-on-success": "delete"},
+			actionExpected: hookContinue,
+			outputErr:      "Command failed for email \"a\" : exit status 1\n",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output, outputErr bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&output)
+			cmd.SetErr(&outputErr)
+			addExecFlags(cmd, hookContinue, hookContinue, true)
+			require.NoError(t, cmd.Flags().Set("exec", scenario.command))
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			h, err := newExecHookFromFlags(cmd)
+			require.NoError(t, err)
+			// The command outputs are left untouched
+			assert.Same(t, &output, cmd.OutOrStdout())
+			assert.Same(t, &outputErr, cmd.ErrOrStderr())
+
+			in := &InboxMock{
+				fetchMail:   MailMock{json: `{"id":"a"}`},
+				fetchError:  scenario.fetchError,
+				deleteError: scenario.deleteError,
+			}
+			item := polledItem{offset: 1, item: inbox.InboxItem{ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme", Mail: "hello@acme.com"}}}
+			action, err := h.run(context.Background(), &output, &outputErr, in, "test", item)
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.actionExpected, action)
+			assert.Equal(t, scenario.output, output.String())
+			assert.Equal(t, scenario.outputErr, outputErr.String())
+			assert.Equal(t, scenario.deletedIDs, in.deletedIDs)
+		})
+	}
+}
+
+func TestInboxWatchExec(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		errExpected string
+		hookOutput  []string
+	}
+	scenarios := []scenario{
+		{
+			name:       "Commands run concurrently",
+			flags:      map[string]string{"exec": "sleep 0.2; echo hook $YOGO_MAIL_ID", "exec-concurrency": "2"},
+			hookOutput: []string{"hook a", "hook b"},
+		},
+		{
+			name:        "Failure stops the watch",
+			flags:       map[string]string{"exec": "exit 2", "exec-on-failure": "stop"},
+			errExpected: `command failed for email "a" : exit status 2`,
+			hookOutput:  []string{},
+		},
+		{
+			name:       "Success stops the watch",
+			flags:      map[string]string{"exec": "echo hook $YOGO_MAIL_ID", "exec-on-success": "stop"},
+			hookOutput: []string{"hook a"},
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cmd := &cobra.Command{}
+			cmd.SetContext(ctx)
+			addInboxWatchFlags(cmd)
+			require.NoError(t, cmd.Flags().Set("interval", "1s"))
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			cmd.SetOut(&output)
+			cmd.SetErr(&output)
+			err := inboxWatch(func(name string) (Inbox, error) {
+				return &InboxMock{
+					refreshItems: [][]inbox.InboxItem{{}, {{ID: "b", Subject: "Second"}, {ID: "a", Subject: "First"}}},
+					fetchMail:    MailMock{json: "{}"},
+					onRefresh: func(count int) {
+						// Leaves time to the commands started at the previous refresh
+						if count == 4 {
+							cancel()
+						}
+					},
+				}, nil
+			})(cmd, []string{"test"})
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+			} else {
+				assert.NoError(t, err)
+			}
+			hookOutput := []string{}
+			for _, line := range strings.Split(output.String(), "\n") {
+				if strings.HasPrefix(line, "hook ") {
+					hookOutput = append(hookOutput, line)
+				}
+			}
+			sort.Strings(hookOutput)
+			assert.Equal(t, scenario.hookOutput, hookOutput)
+		})
+	}
+}
+
+func TestInboxWaitExec(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		errExpected string
+		output      string
+		deletedIDs  []string
+	}
+	expected := inbox.InboxItem{ID: "c", Subject: "Verify your account"}
+	scenarios := []scenario{
+		{
+			name:       "Email deleted once the command succeeded",
+			flags:      map[string]string{"exec": "echo $YOGO_SUBJECT", "exec-on-success": "delete"},
+			output:     "mail\nVerify your account\n",
+			deletedIDs: []string{"c"},
+		},
+		{
+			name:        "Command failure",
+			flags:       map[string]string{"exec": "exit 1"},
+			errExpected: `command failed for email "c" : exit status 1`,
+			output:      "mail\n",
+		},
+		{
+			name:        "Command failure lets the wait go on",
+			flags:       map[string]string{"exec": "exit 1", "exec-on-failure": "continue", "timeout": "1500ms"},
+			errExpected: "no matching email received after 1.5s",
+			output:      "mail\nCommand failed for email \"c\" : exit status 1\n",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			addInboxWaitFlags(cmd)
+			require.NoError(t, cmd.Flags().Set("interval", "1s"))
+			require.NoError(t, cmd.Flags().Set("include-existing", "true"))
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			cmd.SetOut(&output)
+			cmd.SetErr(&output)
+			in := &InboxMock{
+				refreshItems: [][]inbox.InboxItem{{expected}},
+				fetchMail:    MailMock{coloured: "mail", json: "{}"},
+			}
+			start := time.Now()
+			err := inboxWait(func(name string) (Inbox, error) {
+				return in, nil
+			})(cmd, []string{"test"})
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Less(t, time.Since(start), 5*time.Second)
+			assert.Equal(t, scenario.output, output.String())
+			assert.Equal(t, scenario.deletedIDs, in.deletedIDs)
+		})
+	}
+}
+
+func TestLockedInboxGetMails(t *testing.T) {
+	in := &InboxMock{items: []inbox.InboxItem{{ID: "a"}, {ID: "b"}}}
+	l := &lockedInbox{in: in}
+
+	mails := l.GetMails()
+	// a deletion compacts the emails of the inbox in place
+	in.items = append(in.items[:0], in.items[1:]...)
+	assert.Equal(t, []inbox.InboxItem{{ID: "a"}, {ID: "b"}}, mails)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	gomail "net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const mboxDefaultSender = "MAILER-DAEMON"
+
+// mboxFromLine matches the lines quoted in an mboxrd archive,
+// a line starting with "From " would be read as a new message
+var mboxFromLine = regexp.MustCompile(`^>*From `)
+
+// writeMbox appends a message to an mbox archive using the mboxrd format,
+// date is used for the separator line when the message has no valid Date
+func writeMbox(w io.Writer, message []byte, date *time.Time) error {
+	sender, received := mboxEnvelope(message, date)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "From %s %s\n", sender, received.UTC().Format(time.ANSIC))
+	content := strings.TrimSuffix(strings.ReplaceAll(string(message), "\r\n", "\n"), "\n")
+	for _, line := range strings.Split(content, "\n") {
+		if mboxFromLine.MatchString(line) {
+			bw.WriteString(">")
+		}
+		bw.WriteString(line)
+		bw.WriteString("\n")
+	}
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+func mboxEnvelope(message []byte, date *time.Time) (string, time.Time) {
+	sender := mboxDefaultSender
+	received := time.Now()
+	if date != nil {
+		received = *date
+	}
+	msg, err := gomail.ReadMessage(bytes.NewReader(message))
+	if err != nil {
+		return sender, received
+	}
+	if address, err := gomail.ParseAddress(msg.Header.Get("From")); err == nil {
+		sender = address.Address
+	}
+	if d, err := msg.Header.Date(); err == nil {
+		received = d
+	}
+	return sender, received
+}
+
+// emlFilename returns a filename safe to write on disk for a mail ID
+func emlFilename(ID string) string {
+	return strings.ReplaceAll(ID, "/", "_") + ".eml"
+}
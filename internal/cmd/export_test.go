@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMbox(t *testing.T) {
+	date := time.Date(2023, 8, 13, 22, 45, 9, 0, time.UTC)
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeMbox(&buf, []byte("From: Ola <ola@acme.com>\r\nDate: Mon, 14 Aug 2023 01:00:00 +0200\r\nSubject: test\r\n\r\nFrom the start\r\n>From quoted\r\nnot From\r\n"), &date))
+	assert.Equal(t, `From ola@acme.com Sun Aug 13 23:00:00 2023
+From: Ola <ola@acme.com>
+Date: Mon, 14 Aug 2023 01:00:00 +0200
+Subject: test
+
+>From the start
+>>From quoted
+not From
+
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeMbox(&buf, []byte("Subject: test\r\n\r\nbody"), &date))
+	assert.Equal(t, "From MAILER-DAEMON Sun Aug 13 22:45:09 2023\nSubject: test\n\nbody\n\n", buf.String())
+}
+
+func TestEmlFilename(t *testing.T) {
+	assert.Equal(t, "e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt==.eml", emlFilename("e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt=="))
+	assert.Equal(t, "e_Zw_jBQR+ZwZk.eml", emlFilename("e_Zw/jBQR+ZwZk"))
+}
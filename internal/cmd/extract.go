@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+var urlRegexp = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+// extractLinks returns the links of an email, the ones only present
+// in the text body are appended, when hosts are provided only the links
+// pointing to one of them or to one of their subdomains are kept
+func extractLinks(mail inbox.Mail, hosts []string) []inbox.Link {
+	links := []inbox.Link{}
+	seen := map[string]bool{}
+	add := func(link inbox.Link) {
+		if seen[link.URL] || !matchHost(link.URL, hosts) {
+			return
+		}
+		seen[link.URL] = true
+		links = append(links, link)
+	}
+	for _, link := range mail.GetLinks() {
+		add(link)
+	}
+	for _, URL := range urlRegexp.FindAllString(mail.Content(), -1) {
+		add(inbox.Link{URL: strings.TrimRight(URL, ".,;:!?")})
+	}
+	return links
+}
+
+func matchHost(URL string, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	u, err := url.Parse(URL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range hosts {
+		h = strings.ToLower(h)
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractCodes returns the numeric codes with the given number of digits,
+// URLs are discarded first as they often contain long digit sequences
+func extractCodes(text string, length int) []string {
+	re := regexp.MustCompile(fmt.Sprintf(`\b\d{%d}\b`, length))
+	return unique(re.FindAllString(urlRegexp.ReplaceAllString(text, " "), -1))
+}
+
+// extractMatches returns all the matches of a regexp, when the regexp
+// defines a capture group the content of the first one is returned instead
+func extractMatches(text string, re *regexp.Regexp) []string {
+	matches := []string{}
+	for _, match := range re.FindAllStringSubmatch(text, -1) {
+		if len(match) > 1 {
+			matches = append(matches, match[1])
+			continue
+		}
+		matches = append(matches, match[0])
+	}
+	return unique(matches)
+}
+
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractLinks(t *testing.T) {
+	mail := MailMock{
+		links: []inbox.Link{
+			{URL: "https://app.acme.com/confirm?token=abc", Text: "Confirm"},
+			{URL: "https://tracking.net/open"},
+		},
+		content: "Confirm ( https://app.acme.com/confirm?token=abc ), or go to https://acme.com/help.",
+	}
+
+	assert.Equal(t, []inbox.Link{
+		{URL: "https://app.acme.com/confirm?token=abc", Text: "Confirm"},
+		{URL: "https://tracking.net/open"},
+		{URL: "https://acme.com/help"},
+	}, extractLinks(mail, []string{}))
+	assert.Equal(t, []inbox.Link{
+		{URL: "https://app.acme.com/confirm?token=abc", Text: "Confirm"},
+		{URL: "https://acme.com/help"},
+	}, extractLinks(mail, []string{"ACME.com"}))
+	assert.Equal(t, []inbox.Link{}, extractLinks(mail, []string{"cme.com"}))
+}
+
+func TestExtractCodes(t *testing.T) {
+	text := "Your code is 123456. Previous code 654321 expired, ref A1234567 https://acme.com/c/987654"
+	assert.Equal(t, []string{"123456", "654321"}, extractCodes(text, 6))
+	assert.Equal(t, []string{}, extractCodes(text, 4))
+}
+
+func TestExtractMatches(t *testing.T) {
+	text := "token: abc-123 then token: def-456 and token: abc-123"
+	assert.Equal(t, []string{"token: abc-123", "token: def-456"}, extractMatches(text, regexp.MustCompile(`token: \w+-\d+`)))
+	assert.Equal(t, []string{"abc", "def"}, extractMatches(text, regexp.MustCompile(`token: (\w+)-\d+`)))
+}
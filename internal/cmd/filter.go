@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/pflag"
+)
+
+// mailFilter selects the inbox items matching all defined criteria
+type mailFilter struct {
+	from      *regexp.Regexp
+	subject   *regexp.Regexp
+	olderThan time.Duration
+	spam      bool
+}
+
+func addMailFilterFlags(flags *pflag.FlagSet) {
+	flags.String("from", "", "Select emails whose sender matches this regexp")
+	flags.String("subject", "", "Select emails whose subject matches this regexp")
+}
+
+// addReceivedFilterFlags adds the filters that only make
+// sense for the emails already received
+func addReceivedFilterFlags(flags *pflag.FlagSet) {
+	flags.Duration("older-than", 0, "Select emails received before this duration (e.g. 2h)")
+	flags.Bool("spam", false, "Select emails flagged as SPAM")
+}
+
+func newMailFilter(flags *pflag.FlagSet) (mailFilter, error) {
+	var filter mailFilter
+	var err error
+	if filter.from, err = compileFlagRegexp(flags, "from"); err != nil {
+		return mailFilter{}, err
+	}
+	if filter.subject, err = compileFlagRegexp(flags, "subject"); err != nil {
+		return mailFilter{}, err
+	}
+	// the commands waiting for new emails don't have the received filters
+	if flags.Lookup("older-than") == nil {
+		return filter, nil
+	}
+	if filter.olderThan, err = flags.GetDuration("older-than"); err != nil {
+		return mailFilter{}, err
+	}
+	if filter.spam, err = flags.GetBool("spam"); err != nil {
+		return mailFilter{}, err
+	}
+	return filter, nil
+}
+
+func compileFlagRegexp(flags *pflag.FlagSet, name string) (*regexp.Regexp, error) {
+	s, err := flags.GetString(name)
+	if err != nil || s == "" {
+		return nil, err
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf(`--%s regexp "%s" is invalid : %w`, name, s, err)
+	}
+	return re, nil
+}
+
+func (f mailFilter) isEmpty() bool {
+	return f.from == nil && f.subject == nil && f.olderThan == 0 && !f.spam
+}
+
+func (f mailFilter) match(item inbox.InboxItem, now time.Time) bool {
+	if f.from != nil && !f.from.MatchString(item.Sender.String()) {
+		return false
+	}
+	if f.subject != nil && !f.subject.MatchString(item.Subject) {
+		return false
+	}
+	if f.olderThan > 0 && (item.Date == nil || now.Sub(*item.Date) < f.olderThan) {
+		return false
+	}
+	if f.spam && !item.IsSPAM {
+		return false
+	}
+	return true
+}
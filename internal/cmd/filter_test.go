@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailFilter(t *testing.T) {
+	type scenario struct {
+		name          string
+		flags         map[string]string
+		item          inbox.InboxItem
+		matchExpected bool
+	}
+
+	now := time.Now()
+	old := now.Add(-3 * time.Hour)
+	item := inbox.InboxItem{
+		ID:      "abcdefg",
+		Subject: "Your verification code",
+		Sender:  &inbox.Sender{Name: "Acme", Mail: "noreply@acme.com"},
+		Date:    &old,
+	}
+
+	scenarios := []scenario{
+		{
+			name:          "No filter",
+			item:          item,
+			matchExpected: true,
+		},
+		{
+			name:          "Sender name matches",
+			flags:         map[string]string{"from": "^Acme"},
+			item:          item,
+			matchExpected: true,
+		},
+		{
+			name:          "Sender mail matches",
+			flags:         map[string]string{"from": "@acme\\.com>$"},
+			item:          item,
+			matchExpected: true,
+		},
+		{
+			name:  "Subject doesn't match",
+			flags: map[string]string{"subject": "password"},
+			item:  item,
+		},
+		{
+			name:          "Email old enough",
+			flags:         map[string]string{"older-than": "2h"},
+			item:          item,
+			matchExpected: true,
+		},
+		{
+			name:  "Email too recent",
+			flags: map[string]string{"older-than": "4h"},
+			item:  item,
+		},
+		{
+			name:  "Email without date",
+			flags: map[string]string{"older-than": "1m"},
+			item:  inbox.InboxItem{ID: "abcdefg"},
+		},
+		{
+			name:  "Email is not a SPAM",
+			flags: map[string]string{"spam": "true"},
+			item:  item,
+		},
+		{
+			name:          "All criteria match",
+			flags:         map[string]string{"from": "acme", "subject": "code$", "older-than": "1h", "spam": "true"},
+			item:          inbox.InboxItem{Subject: "code", Sender: &inbox.Sender{Mail: "acme"}, Date: &old, IsSPAM: true},
+			matchExpected: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			addMailFilterFlags(flags)
+			addReceivedFilterFlags(flags)
+			for k, v := range scenario.flags {
+				assert.NoError(t, flags.Set(k, v))
+			}
+			filter, err := newMailFilter(flags)
+			assert.NoError(t, err)
+			assert.Equal(t, len(scenario.flags) == 0, filter.isEmpty())
+			assert.Equal(t, scenario.matchExpected, filter.match(scenario.item, now))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/imap"
+	"github.com/spf13/cobra"
+)
+
+var imapCmd = &cobra.Command{
+	Use:   "imap",
+	Short: "Serve the inboxes to mail clients through a read-only IMAP server",
+	Long: `Serve the inboxes to mail clients through a read-only IMAP server.
+
+The login name selects the inbox and the password is ignored, so the server
+must only listen on a local address. The INBOX mailbox holds the last emails,
+deleting an email from the client deletes it from yopmail.`,
+	RunE: serveIMAP(newServerInboxBuilder, listen),
+	Args: cobra.NoArgs,
+}
+
+func addIMAPFlags(cmd *cobra.Command) {
+	cmd.Flags().String("listen", "127.0.0.1:1143", "Address the server listens on")
+	cmd.Flags().Int("limit", 50, "Number of emails available in a mailbox")
+	cmd.Flags().Duration("refresh-interval", 30*time.Second, "Minimum delay between two checks of an inbox")
+}
+
+func serveIMAP(serverInboxBuilder serverInboxBuilder, listener listener) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		address, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return err
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+		if limit < 1 {
+			return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+		}
+		interval, err := cmd.Flags().GetDuration("refresh-interval")
+		if err != nil {
+			return err
+		}
+		if interval < minPollInterval {
+			return fmt.Errorf(`refresh interval "%s" must be at least %s`, interval, minPollInterval)
+		}
+
+		build, err := serverInboxBuilder()
+		if err != nil {
+			return err
+		}
+		l, err := listener(address)
+		if err != nil {
+			return err
+		}
+		s := imap.New(build, imap.Config{
+			Limit:           limit,
+			RefreshInterval: interval,
+			Name:            normalizeInboxName,
+		})
+
+		ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Serve(l)
+		}()
+		cmd.Println(info(fmt.Sprintf("IMAP server listening on %s, log in with the inbox name and any password", l.Addr())))
+
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+		}
+		if err := s.Close(); err != nil {
+			return err
+		}
+		return <-errs
+	}
+}
+
+func init() {
+	addIMAPFlags(imapCmd)
+	RootCmd.AddCommand(imapCmd)
+}
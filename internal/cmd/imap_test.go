@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeIMAP(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		builder     serverInboxBuilder
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:        "Invalid limit",
+			flags:       map[string]string{"limit": "0"},
+			errExpected: `limit "0" must be greater than 0`,
+		},
+		{
+			name:        "Refresh interval too short",
+			flags:       map[string]string{"refresh-interval": "10ms"},
+			errExpected: `refresh interval "10ms" must be at least 1s`,
+		},
+		{
+			name: "Session can't be opened",
+			builder: func() (backend.Builder, error) {
+				return nil, errors.New("session error")
+			},
+			errExpected: "session error",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addIMAPFlags(cmd)
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			err := serveIMAP(scenario.builder, listen)(cmd, []string{})
+			assert.EqualError(t, err, scenario.errExpected)
+		})
+	}
+}
+
+func TestServeIMAPSession(t *testing.T) {
+	var output bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	cmd.SetOut(&output)
+	addIMAPFlags(cmd)
+	require.NoError(t, cmd.Flags().Set("listen", "127.0.0.1:0"))
+
+	addresses := make(chan string, 1)
+	done := make(chan error, 1)
+	names := make(chan string, 1)
+	in := &InboxMock{refreshItems: [][]inbox.InboxItem{{{ID: "a", Subject: "Welcome"}}}}
+	go func() {
+		done <- serveIMAP(
+			func() (backend.Builder, error) {
+				return func(name string, source bool) (backend.Inbox, error) {
+					names <- name
+					return in, nil
+				}, nil
+			},
+			func(address string) (net.Listener, error) {
+				l, err := net.Listen("tcp", address)
+				if err == nil {
+					addresses <- l.Addr().String()
+				}
+				return l, err
+			},
+		)(cmd, []string{})
+	}()
+
+	address := <-addresses
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	readLine := func() string {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		return line
+	}
+	assert.Contains(t, readLine(), "* OK")
+	_, err = fmt.Fprint(conn, "a1 LOGIN TEST@yopmail.com secret\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "a1 OK LOGIN completed\r\n", readLine())
+	assert.Equal(t, "test", <-names)
+	_, err = fmt.Fprint(conn, "a2 STATUS INBOX (MESSAGES)\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "* STATUS INBOX (MESSAGES 1)\r\n", readLine())
+	assert.Equal(t, "a2 OK STATUS completed\r\n", readLine())
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, "IMAP server listening on "+address+", log in with the inbox name and any password\n", output.String())
+}
@@ -20,7 +20,26 @@ func init() {
 	RootCmd.AddCommand(inboxCmd)
 }
 
+// newInbox caches the inbox pages listed to be able to work offline
 func newInbox[M client.MailDoc](name string) (Inbox, error) {
-	in, err := inbox.NewInbox[M](name, enableDebugMode)
-	return Inbox(in), err
+	return openInbox[M](name, client.WithInboxCache())
+}
+
+// newPollingInbox spaces out the requests as the inbox is polled,
+// the inbox pages are not cached as one would be written on every poll
+func newPollingInbox[M client.MailDoc](name string) (Inbox, error) {
+	return openInbox[M](name, client.WithMinRequestInterval(pollRequestInterval))
+}
+
+func openInbox[M client.MailDoc](name string, extraOpts ...client.Option) (Inbox, error) {
+	opts, err := clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, extraOpts...)
+	in, err := inbox.NewInbox[M](name, enableDebugMode, opts...)
+	if err != nil {
+		return Inbox(in), err
+	}
+	return withArchive(in, name, openArchive)
 }
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// inboxAliases maps the aliases defined in the configuration
+// file to their inbox, it is populated before running a command
+var inboxAliases = map[string]string{}
+
+var inboxAliasesCmd = &cobra.Command{
+	Use:   "aliases",
+	Short: "List the inbox aliases defined in the configuration file",
+	RunE:  inboxAliasesList(config.Path),
+	Args:  cobra.NoArgs,
+}
+
+type alias struct {
+	Name  string `json:"name"`
+	Inbox string `json:"inbox"`
+}
+
+type aliases []alias
+
+func (a aliases) Coloured() (string, error) {
+	if len(a) == 0 {
+		return info("No alias defined"), nil
+	}
+	lines := []string{}
+	for _, alias := range a {
+		lines = append(lines, fmt.Sprintf(" %s : %s", color.YellowString(alias.Name), color.CyanString(alias.Inbox)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (a aliases) Items() []any {
+	items := []any{}
+	for _, alias := range a {
+		items = append(items, alias)
+	}
+	return items
+}
+
+func (a aliases) Header() []string {
+	return []string{"name", "inbox"}
+}
+
+func (a aliases) Rows() [][]string {
+	rows := [][]string{}
+	for _, alias := range a {
+		rows = append(rows, []string{alias.Name, alias.Inbox})
+	}
+	return rows
+}
+
+func inboxAliasesList(configPath configPathGetter) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		renderer, err := newRenderer(cmd)
+		if err != nil {
+			return err
+		}
+		c, _, _, err := loadConfig(cmd, configPath)
+		if err != nil {
+			return err
+		}
+		a := aliases{}
+		for _, name := range c.AliasNames() {
+			a = append(a, alias{Name: name, Inbox: c.Aliases[name]})
+		}
+		output, err := renderer.Render(a)
+		if err != nil {
+			return err
+		}
+		cmd.Println(output)
+		return nil
+	}
+}
+
+// resolveInboxAlias returns the inbox targeted by an alias,
+// any other inbox name is returned untouched
+func resolveInboxAlias(inboxName string) string {
+	if name, ok := inboxAliases[inboxName]; ok {
+		return name
+	}
+	return inboxName
+}
+
+// completeInbox completes the inbox argument with the aliases, the
+// root hooks are not run during a completion so the file is read here
+func completeInbox(configPath configPathGetter) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		path, err := configPath()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		c, err := config.Load(path)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		names := []string{}
+		for _, name := range c.AliasNames() {
+			if strings.HasPrefix(name, toComplete) {
+				names = append(names, fmt.Sprintf("%s\t%s", name, c.Aliases[name]))
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func init() {
+	addOutputFlag(inboxAliasesCmd)
+	inboxCmd.AddCommand(inboxAliasesCmd)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+const aliasesContent = `aliases:
+  staging-signup: qa-signup-7f3k2
+  staging-reset: qa-reset-9d2k1
+  prod: prod-8s1l0
+`
+
+func TestInboxAliases(t *testing.T) {
+	type scenario struct {
+		name        string
+		content     string
+		output      string
+		result      string
+		errExpected error
+	}
+
+	scenarios := []scenario{
+		{
+			name:    "Aliases sorted by name",
+			content: aliasesContent,
+			result: ` prod : prod-8s1l0
+ staging-reset : qa-reset-9d2k1
+ staging-signup : qa-signup-7f3k2
+`,
+		},
+		{
+			name:   "No alias",
+			result: "No alias defined\n",
+		},
+		{
+			name:    "CSV output",
+			content: aliasesContent,
+			output:  "csv",
+			result: `name,inbox
+prod,prod-8s1l0
+staging-reset,qa-reset-9d2k1
+staging-signup,qa-signup-7f3k2
+`,
+		},
+		{
+			name:        "Invalid configuration file",
+			content:     "aliases: [",
+			errExpected: errors.New(`configuration file "%s" is invalid : yaml: line 1: did not find expected node content`),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			addOutputFlag(cmd)
+			assert.NoError(t, cmd.Flags().Set("output", scenario.output))
+			cmd.SetOut(&output)
+			path := writeConfig(t, scenario.content)
+			err := inboxAliasesList(path)(cmd, []string{})
+			if scenario.errExpected != nil {
+				p, _ := path()
+				assert.EqualError(t, err, fmt.Sprintf(scenario.errExpected.Error(), p))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.result, output.String())
+		})
+	}
+}
+
+func TestCompleteInbox(t *testing.T) {
+	t.Parallel()
+	complete := completeInbox(writeConfig(t, aliasesContent))
+
+	names, directive := complete(&cobra.Command{}, []string{}, "staging")
+	assert.Equal(t, []string{"staging-reset\tqa-reset-9d2k1", "staging-signup\tqa-signup-7f3k2"}, names)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	names, directive = complete(&cobra.Command{}, []string{"prod"}, "")
+	assert.Empty(t, names)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	_, directive = completeInbox(writeConfig(t, "aliases: ["))(&cobra.Command{}, []string{}, "")
+	assert.Equal(t, cobra.ShellCompDirectiveError, directive)
+}
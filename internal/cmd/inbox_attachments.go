@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var inboxAttachmentsCmd = &cobra.Command{
+	Use:               "attachments <inbox> <offset>",
+	Short:             "List or save the attachments of the email at given position in inbox",
+	RunE:              inboxAttachments(newInbox[client.MailSourceDoc]),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addInboxAttachmentsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("save", "", "Write the attachments in this directory")
+}
+
+func inboxAttachments(inboxBuilder inboxBuilder) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		dir, err := cmd.Flags().GetString("save")
+		if err != nil {
+			return err
+		}
+		identifier := normalizeInboxName(args[0])
+		offset, err := parseOffset(args[1])
+		if err != nil {
+			return err
+		}
+		in, err := inboxBuilder(identifier)
+		if err != nil {
+			return err
+		}
+		if err := in.ParseInboxPages(offset); err != nil {
+			return err
+		}
+		if err := checkOffset(in.Count(), offset); err != nil {
+			return err
+		}
+		mail, err := in.Fetch(offset - 1)
+		if err != nil {
+			return err
+		}
+
+		attachments := mail.GetAttachments()
+		if dir != "" {
+			return saveAttachments(cmd, dir, attachments)
+		}
+		if dumpJSON {
+			data, err := json.Marshal(attachments)
+			if err != nil {
+				return err
+			}
+			cmd.Println(string(data))
+			return nil
+		}
+		if len(attachments) == 0 {
+			cmd.Println(info("No attachments"))
+			return nil
+		}
+		for i, attachment := range attachments {
+			cmd.Println(fmt.Sprintf(" %d %s %s %s", i+1, color.YellowString(attachmentFilename(i, attachment)), color.CyanString(attachment.ContentType), color.GreenString(formatSize(attachment.Size))))
+		}
+		return nil
+	}
+}
+
+func saveAttachments(cmd *cobra.Command, dir string, attachments []inbox.Attachment) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for i, attachment := range attachments {
+		f, err := createAttachmentFile(dir, attachmentFilename(i, attachment))
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(attachment.Content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		cmd.Println(success(fmt.Sprintf(`Attachment "%s" saved`, f.Name())))
+	}
+	return nil
+}
+
+// createAttachmentFile creates a new file in dir, the existing files are
+// never overwritten, a suffix is added to the filename when it's taken,
+// e.g. image-2.png, as several attachments often share the same name
+func createAttachmentFile(dir string, filename string) (*os.File, error) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	if base == "" {
+		base, ext = filename, ""
+	}
+	for n := 1; ; n++ {
+		name := filename
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, err
+	}
+}
+
+// attachmentFilename returns a filename safe to write on disk,
+// the ones provided by the sender may contain paths
+func attachmentFilename(index int, attachment inbox.Attachment) string {
+	filename := filepath.Base(filepath.Clean("/" + filepath.FromSlash(attachment.Filename)))
+	if filename == string(filepath.Separator) || filename == "." {
+		return fmt.Sprintf("attachment-%d", index+1)
+	}
+	return filename
+}
+
+func formatSize(size int) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := unit, 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+func init() {
+	addInboxAttachmentsFlags(inboxAttachmentsCmd)
+	inboxCmd.AddCommand(inboxAttachmentsCmd)
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInboxAttachments(t *testing.T) {
+	type scenario struct {
+		name         string
+		args         []string
+		flags        map[string]string
+		errExpected  error
+		inboxBuilder inboxBuilder
+		output       string
+		files        map[string]string
+	}
+
+	mock := func(attachments []inbox.Attachment) inboxBuilder {
+		return func(name string) (Inbox, error) {
+			return &InboxMock{
+				count:     1,
+				items:     []inbox.InboxItem{{ID: "abcdefg"}},
+				fetchMail: MailMock{attachments: attachments},
+			}, nil
+		}
+	}
+	attachments := []inbox.Attachment{
+		{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 2048, Content: []byte("pdf")},
+		{Filename: "../../etc/passwd", ContentType: "text/plain", Size: 4, Content: []byte("test")},
+		{ContentType: "text/calendar", Size: 3, Content: []byte("ics")},
+	}
+	dir := t.TempDir()
+
+	scenarios := []scenario{
+		{
+			name:        "Failure when parsing offset",
+			args:        []string{"test", "0"},
+			errExpected: errors.New(`offset "0" must be greater than 0`),
+		},
+		{
+			name: "No mails found",
+			args: []string{"test", "1"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{}, nil
+			},
+			errExpected: errors.New("inbox is empty"),
+		},
+		{
+			name: "An error is thrown when fetching the mail",
+			args: []string{"test", "1"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{count: 1, fetchError: errors.New("fetch error")}, nil
+			},
+			errExpected: errors.New("fetch error"),
+		},
+		{
+			name:         "No attachments",
+			args:         []string{"test", "1"},
+			inboxBuilder: mock([]inbox.Attachment{}),
+			output:       "No attachments\n",
+		},
+		{
+			name:         "List attachments",
+			args:         []string{"test", "1"},
+			inboxBuilder: mock(attachments),
+			output: ` 1 invoice.pdf application/pdf 2.0 KiB
+ 2 passwd text/plain 4 B
+ 3 attachment-3 text/calendar 3 B
+`,
+		},
+		{
+			name:         "Save attachments",
+			args:         []string{"test", "1"},
+			flags:        map[string]string{"save": filepath.Join(dir, "mail")},
+			inboxBuilder: mock(attachments),
+			output: `Attachment "` + filepath.Join(dir, "mail", "invoice.pdf") + `" saved
+Attachment "` + filepath.Join(dir, "mail", "passwd") + `" saved
+Attachment "` + filepath.Join(dir, "mail", "attachment-3") + `" saved
+`,
+			files: map[string]string{"mail/invoice.pdf": "pdf", "mail/passwd": "test", "mail/attachment-3": "ics"},
+		},
+		{
+			name:  "Save attachments sharing a filename without overwriting the existing files",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"save": filepath.Join(dir, "images")},
+			inboxBuilder: mock([]inbox.Attachment{
+				{Filename: "image.png", ContentType: "image/png", Size: 3, Content: []byte("one")},
+				{Filename: "image.png", ContentType: "image/png", Size: 3, Content: []byte("two")},
+				{Filename: ".hidden", ContentType: "text/plain", Size: 5, Content: []byte("three")},
+			}),
+			output: `Attachment "` + filepath.Join(dir, "images", "image-2.png") + `" saved
+Attachment "` + filepath.Join(dir, "images", "image-3.png") + `" saved
+Attachment "` + filepath.Join(dir, "images", ".hidden-2") + `" saved
+`,
+			files: map[string]string{"images/image.png": "existing", "images/image-2.png": "one", "images/image-3.png": "two", "images/.hidden": "existing", "images/.hidden-2": "three"},
+		},
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "images"), 0o755))
+	for _, name := range []string{"image.png", ".hidden"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "images", name), []byte("existing"), 0o644))
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			addInboxAttachmentsFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetOut(&output)
+			err := inboxAttachments(scenario.inboxBuilder)(cmd, scenario.args)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.output, output.String())
+			for name, content := range scenario.files {
+				b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				assert.NoError(t, err)
+				assert.Equal(t, content, string(b))
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0 B", formatSize(0))
+	assert.Equal(t, "1023 B", formatSize(1023))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "2.0 MiB", formatSize(2*1024*1024))
+}
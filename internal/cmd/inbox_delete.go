@@ -1,21 +1,50 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/antham/yogo/v4/internal/inbox"
 	"github.com/spf13/cobra"
 )
 
+const defaultFilterLimit = 100
+
 var inboxDeleteCmd = &cobra.Command{
-	Use:   "delete <inbox> <offset>",
-	Short: "Delete email at given position in inbox",
-	RunE:  inboxDelete(newInbox[client.MailHTMLDoc]),
-	Args:  cobra.ExactArgs(2),
+	Use:               "delete <inbox> [<offset>]",
+	Short:             "Delete email at given position in inbox or all emails matching filters",
+	RunE:              inboxDelete(newInbox[client.MailHTMLDoc]),
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addInboxDeleteFlags(cmd *cobra.Command) {
+	addMailFilterFlags(cmd.Flags())
+	addReceivedFilterFlags(cmd.Flags())
+	cmd.Flags().Int("limit", defaultFilterLimit, "Maximum number of emails to scan when using filters")
+	cmd.Flags().Bool("dry-run", false, "Only display the emails matching the filters")
+	cmd.Flags().BoolP("yes", "y", false, "Delete matching emails without asking for confirmation")
 }
 
 func inboxDelete(inboxBuilder inboxBuilder) cobraCmd {
 	return func(cmd *cobra.Command, args []string) error {
+		filter, err := newMailFilter(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			if filter.isEmpty() {
+				return errors.New("an offset or at least one filter must be provided")
+			}
+			return inboxDeleteMatching(cmd, inboxBuilder, args[0], filter)
+		}
+		if !filter.isEmpty() {
+			return errors.New("an offset can't be combined with filters")
+		}
+
 		identifier := normalizeInboxName(args[0])
 		offset, err := parseOffset(args[1])
 		if err != nil {
@@ -39,6 +68,62 @@ func inboxDelete(inboxBuilder inboxBuilder) cobraCmd {
 	}
 }
 
+func inboxDeleteMatching(cmd *cobra.Command, inboxBuilder inboxBuilder, inboxName string, filter mailFilter) error {
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return err
+	}
+	if limit < 1 {
+		return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+	}
+	in, err := inboxBuilder(normalizeInboxName(inboxName))
+	if err != nil {
+		return err
+	}
+	if err := in.ParseInboxPages(limit); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	selection := map[int]inbox.InboxItem{}
+	offsets := []int{}
+	for index, item := range in.GetMails() {
+		if filter.match(item, now) {
+			selection[index+1] = item
+			offsets = append(offsets, index+1)
+		}
+	}
+	if len(offsets) == 0 {
+		cmd.Println(info("No email matches the given filters"))
+		return nil
+	}
+	for _, offset := range offsets {
+		cmd.Println(formatInboxItem(offset, selection[offset]))
+	}
+
+	if dryRun, err := cmd.Flags().GetBool("dry-run"); err != nil || dryRun {
+		return err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+	if !yes {
+		ok, err := confirm(cmd, fmt.Sprintf("Delete %d email(s)?", len(offsets)))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	for _, offset := range offsets {
+		if err := in.DeleteByID(selection[offset].ID); err != nil {
+			return err
+		}
+	}
+	cmd.Println(success(fmt.Sprintf(`%d email(s) successfully deleted`, len(offsets))))
+	return nil
+}
+
 func init() {
+	addInboxDeleteFlags(inboxDeleteCmd)
 	inboxCmd.AddCommand(inboxDeleteCmd)
 }
@@ -3,7 +3,9 @@ package cmd
 import (
 	"bytes"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/antham/yogo/v4/internal/inbox"
 	"github.com/spf13/cobra"
@@ -14,10 +16,42 @@ func TestInboxDelete(t *testing.T) {
 	type scenario struct {
 		name         string
 		args         []string
+		flags        map[string]string
+		input        string
 		errExpected  error
 		inboxBuilder inboxBuilder
 		output       string
 		outputErr    string
+		deletedIDs   []string
+	}
+
+	old := time.Now().Add(-3 * time.Hour)
+	recent := time.Now().Add(-10 * time.Minute)
+	mock := func() *InboxMock {
+		return &InboxMock{
+			count: 3,
+			items: []inbox.InboxItem{
+				{
+					ID:      "a",
+					Subject: "Verify your account",
+					Sender:  &inbox.Sender{Name: "Acme", Mail: "noreply@acme.com"},
+					Date:    &old,
+				},
+				{
+					ID:      "b",
+					Subject: "Win a prize",
+					Sender:  &inbox.Sender{Name: "Spammer"},
+					Date:    &old,
+					IsSPAM:  true,
+				},
+				{
+					ID:      "c",
+					Subject: "Verify your account",
+					Sender:  &inbox.Sender{Name: "Acme", Mail: "noreply@acme.com"},
+					Date:    &recent,
+				},
+			},
+		}
 	}
 
 	scenarios := []scenario{
@@ -102,6 +136,81 @@ func TestInboxDelete(t *testing.T) {
 			output: `Email "1" successfully deleted
 `,
 		},
+		{
+			name:        "Neither offset nor filter provided",
+			args:        []string{"test"},
+			errExpected: errors.New("an offset or at least one filter must be provided"),
+		},
+		{
+			name:        "Offset combined with filters",
+			args:        []string{"test", "1"},
+			flags:       map[string]string{"spam": "true"},
+			errExpected: errors.New("an offset can't be combined with filters"),
+		},
+		{
+			name:        "Invalid regexp",
+			args:        []string{"test"},
+			flags:       map[string]string{"subject": "("},
+			errExpected: errors.New("--subject regexp \"(\" is invalid : error parsing regexp: missing closing ): `(`"),
+		},
+		{
+			name:  "No email matches the filters",
+			args:  []string{"test"},
+			flags: map[string]string{"from": "nobody"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: "No email matches the given filters\n",
+		},
+		{
+			name:  "Dry run",
+			args:  []string{"test"},
+			flags: map[string]string{"subject": "^Verify", "dry-run": "true"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: ` 1 Acme <noreply@acme.com> : Verify your account
+ 3 Acme <noreply@acme.com> : Verify your account
+`,
+		},
+		{
+			name:  "Deletion refused",
+			args:  []string{"test"},
+			flags: map[string]string{"spam": "true"},
+			input: "n\n",
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: ` 2 Spammer [SPAM] : Win a prize
+Delete 1 email(s)? [y/N] `,
+		},
+		{
+			name:  "Deletion confirmed",
+			args:  []string{"test"},
+			flags: map[string]string{"from": "acme", "older-than": "1h"},
+			input: "y\n",
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: ` 1 Acme <noreply@acme.com> : Verify your account
+Delete 1 email(s)? [y/N] 1 email(s) successfully deleted
+`,
+			deletedIDs: []string{"a"},
+		},
+		{
+			name:  "Deletion without confirmation",
+			args:  []string{"test"},
+			flags: map[string]string{"subject": "Verify|prize", "yes": "true"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: ` 1 Acme <noreply@acme.com> : Verify your account
+ 2 Spammer [SPAM] : Win a prize
+ 3 Acme <noreply@acme.com> : Verify your account
+3 email(s) successfully deleted
+`,
+			deletedIDs: []string{"a", "b", "c"},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -111,10 +220,28 @@ func TestInboxDelete(t *testing.T) {
 			var output bytes.Buffer
 			var outputErr bytes.Buffer
 			cmd := &cobra.Command{}
+			addInboxDeleteFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetIn(strings.NewReader(scenario.input))
 			cmd.SetOut(&output)
 			cmd.SetErr(&outputErr)
-			err := inboxDelete(scenario.inboxBuilder)(cmd, scenario.args)
-			assert.Equal(t, scenario.errExpected, err)
+			var in Inbox
+			inboxBuilder := func(name string) (Inbox, error) {
+				var err error
+				in, err = scenario.inboxBuilder(name)
+				return in, err
+			}
+			err := inboxDelete(inboxBuilder)(cmd, scenario.args)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			if scenario.deletedIDs != nil {
+				assert.Equal(t, scenario.deletedIDs, in.(*InboxMock).deletedIDs)
+			}
 			assert.Equal(t, scenario.output, output.String())
 			assert.Equal(t, scenario.outputErr, outputErr.String())
 		})
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+)
+
+var inboxExportCmd = &cobra.Command{
+	Use:               "export <inbox>",
+	Short:             "Export emails as .eml files or as an mbox archive",
+	RunE:              inboxExport(newInbox[client.MailSourceDoc]),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addInboxExportFlags(cmd *cobra.Command) {
+	cmd.Flags().String("format", "eml", "Export format: eml (one file per email in the out directory) or mbox (a single archive)")
+	cmd.Flags().String("out", "", "Directory (eml) or file (mbox) to write")
+	cmd.Flags().Int("limit", defaultFilterLimit, "Maximum number of emails to export")
+}
+
+func inboxExport(inboxBuilder inboxBuilder) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		if format != "eml" && format != "mbox" {
+			return fmt.Errorf(`format "%s" is not supported, use eml or mbox`, format)
+		}
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			return err
+		}
+		if out == "" {
+			return errors.New("an output path must be provided with --out")
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+		if limit < 1 {
+			return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+		}
+		in, err := inboxBuilder(normalizeInboxName(args[0]))
+		if err != nil {
+			return err
+		}
+		if err := in.ParseInboxPages(limit); err != nil {
+			return err
+		}
+		items := in.GetMails()
+		if len(items) > limit {
+			items = items[:limit]
+		}
+		if len(items) == 0 {
+			cmd.Println(info("No email to export"))
+			return nil
+		}
+
+		if format == "mbox" {
+			err = exportMbox(in, items, out)
+		} else {
+			err = exportEML(in, items, out)
+		}
+		if err != nil {
+			return err
+		}
+		cmd.Println(success(fmt.Sprintf(`%d email(s) exported to "%s"`, len(items), out)))
+		return nil
+	}
+}
+
+func fetchMessage(in Inbox, offset int) ([]byte, error) {
+	mail, err := in.Fetch(offset)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := mail.(inbox.Source)
+	if !ok {
+		return nil, errors.New("the email source is not available")
+	}
+	return source.Message(), nil
+}
+
+func exportEML(in Inbox, items []inbox.InboxItem, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for offset, item := range items {
+		message, err := fetchMessage(in, offset)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, emlFilename(item.ID)), message, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportMbox writes the archive from the oldest to the newest email
+// as the mail clients expect, the inbox lists the newest first
+func exportMbox(in Inbox, items []inbox.InboxItem, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for offset := len(items) - 1; offset >= 0; offset-- {
+		message, err := fetchMessage(in, offset)
+		if err != nil {
+			return err
+		}
+		if err := writeMbox(f, message, items[offset].Date); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+func init() {
+	addInboxExportFlags(inboxExportCmd)
+	inboxCmd.AddCommand(inboxExportCmd)
+}
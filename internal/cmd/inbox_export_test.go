@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInboxExport(t *testing.T) {
+	type scenario struct {
+		name         string
+		flags        map[string]string
+		errExpected  error
+		inboxBuilder inboxBuilder
+		output       string
+		files        map[string]string
+	}
+
+	date := time.Date(2023, 8, 13, 22, 45, 9, 0, time.UTC)
+	message := "From: test@yopmail.com\r\nSubject: test\r\n\r\nFrom here\r\n"
+	mock := func(name string) (Inbox, error) {
+		return &InboxMock{
+			count:     2,
+			items:     []inbox.InboxItem{{ID: "e_Zw/abc", Date: &date}, {ID: "e_ZwZjBQ", Date: &date}},
+			fetchMail: &SourceMailMock{message: []byte(message)},
+		}, nil
+	}
+	dir := t.TempDir()
+
+	scenarios := []scenario{
+		{
+			name:        "Unsupported format",
+			flags:       map[string]string{"format": "pst", "out": dir},
+			errExpected: errors.New(`format "pst" is not supported, use eml or mbox`),
+		},
+		{
+			name:        "Missing output path",
+			errExpected: errors.New("an output path must be provided with --out"),
+		},
+		{
+			name:        "Wrong limit",
+			flags:       map[string]string{"out": dir, "limit": "0"},
+			errExpected: errors.New(`limit "0" must be greater than 0`),
+		},
+		{
+			name:  "Empty inbox",
+			flags: map[string]string{"out": dir},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{}, nil
+			},
+			output: "No email to export\n",
+		},
+		{
+			name:  "An error is thrown when fetching a mail",
+			flags: map[string]string{"out": filepath.Join(dir, "error")},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{count: 1, items: []inbox.InboxItem{{ID: "abcdefg"}}, fetchError: errors.New("fetch error")}, nil
+			},
+			errExpected: errors.New("fetch error"),
+		},
+		{
+			name:  "The source is not available",
+			flags: map[string]string{"out": filepath.Join(dir, "error")},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{count: 1, items: []inbox.InboxItem{{ID: "abcdefg"}}, fetchMail: MailMock{}}, nil
+			},
+			errExpected: errors.New("the email source is not available"),
+		},
+		{
+			name:         "Export as eml files",
+			flags:        map[string]string{"out": filepath.Join(dir, "eml")},
+			inboxBuilder: mock,
+			output:       `2 email(s) exported to "` + filepath.Join(dir, "eml") + `"` + "\n",
+			files: map[string]string{
+				filepath.Join("eml", "e_Zw_abc.eml"): message,
+				filepath.Join("eml", "e_ZwZjBQ.eml"): message,
+			},
+		},
+		{
+			name:         "Export a limited mbox archive",
+			flags:        map[string]string{"out": filepath.Join(dir, "inbox.mbox"), "format": "mbox", "limit": "1"},
+			inboxBuilder: mock,
+			output:       `1 email(s) exported to "` + filepath.Join(dir, "inbox.mbox") + `"` + "\n",
+			files: map[string]string{
+				"inbox.mbox": "From test@yopmail.com Sun Aug 13 22:45:09 2023\nFrom: test@yopmail.com\nSubject: test\n\n>From here\n\n",
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			addInboxExportFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetOut(&output)
+			err := inboxExport(scenario.inboxBuilder)(cmd, []string{"test"})
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.output, output.String())
+			for name, content := range scenario.files {
+				b, err := os.ReadFile(filepath.Join(dir, name))
+				assert.NoError(t, err)
+				assert.Equal(t, content, string(b))
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var inboxExtractCmd = &cobra.Command{
+	Use:               "extract <inbox> <offset>",
+	Short:             "Extract links, codes or any pattern from the email at given position in inbox",
+	RunE:              inboxExtract(newInbox[client.MailHTMLDoc]),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addInboxExtractFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("links", false, "Extract the links")
+	cmd.Flags().Bool("codes", false, "Extract the numeric codes (e.g. OTP)")
+	cmd.Flags().String("regex", "", "Extract the matches of this regexp, or of its first capture group")
+	cmd.Flags().Int("code-length", 6, "Number of digits of the codes to extract")
+	cmd.Flags().StringSlice("host", []string{}, "Only keep the links pointing to these hosts and their subdomains")
+}
+
+func inboxExtract(inboxBuilder inboxBuilder) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		links, err := cmd.Flags().GetBool("links")
+		if err != nil {
+			return err
+		}
+		codes, err := cmd.Flags().GetBool("codes")
+		if err != nil {
+			return err
+		}
+		re, err := compileFlagRegexp(cmd.Flags(), "regex")
+		if err != nil {
+			return err
+		}
+		modes := 0
+		for _, enabled := range []bool{links, codes, re != nil} {
+			if enabled {
+				modes++
+			}
+		}
+		if modes != 1 {
+			return errors.New("exactly one of --links, --codes or --regex must be provided")
+		}
+		codeLength, err := cmd.Flags().GetInt("code-length")
+		if err != nil {
+			return err
+		}
+		if codeLength < 1 {
+			return fmt.Errorf(`code length "%d" must be greater than 0`, codeLength)
+		}
+		hosts, err := cmd.Flags().GetStringSlice("host")
+		if err != nil {
+			return err
+		}
+
+		identifier := normalizeInboxName(args[0])
+		offset, err := parseOffset(args[1])
+		if err != nil {
+			return err
+		}
+		in, err := inboxBuilder(identifier)
+		if err != nil {
+			return err
+		}
+		if err := in.ParseInboxPages(offset); err != nil {
+			return err
+		}
+		if err := checkOffset(in.Count(), offset); err != nil {
+			return err
+		}
+		mail, err := in.Fetch(offset - 1)
+		if err != nil {
+			return err
+		}
+
+		var data any
+		var lines []string
+		switch {
+		case links:
+			l := extractLinks(mail, hosts)
+			for _, link := range l {
+				lines = append(lines, link.URL)
+			}
+			data = l
+		case codes:
+			lines = extractCodes(mail.Content(), codeLength)
+			data = lines
+		default:
+			lines = extractMatches(mail.Content(), re)
+			data = lines
+		}
+		if len(lines) == 0 {
+			return errors.New("nothing found")
+		}
+
+		if dumpJSON {
+			b, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			cmd.Println(string(b))
+			return nil
+		}
+		for _, line := range lines {
+			cmd.Println(line)
+		}
+		return nil
+	}
+}
+
+func init() {
+	addInboxExtractFlags(inboxExtractCmd)
+	inboxCmd.AddCommand(inboxExtractCmd)
+}
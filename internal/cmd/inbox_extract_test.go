@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInboxExtract(t *testing.T) {
+	type scenario struct {
+		name         string
+		args         []string
+		flags        map[string]string
+		errExpected  error
+		inboxBuilder inboxBuilder
+		output       string
+	}
+
+	mock := func() *InboxMock {
+		return &InboxMock{
+			count: 1,
+			items: []inbox.InboxItem{{ID: "abcdefg"}},
+			fetchMail: MailMock{
+				links:   []inbox.Link{{URL: "https://acme.com/confirm", Text: "Confirm"}},
+				content: "Confirm ( https://acme.com/confirm ) with the code 123456, your id is ID-42",
+			},
+		}
+	}
+
+	scenarios := []scenario{
+		{
+			name:        "No mode provided",
+			args:        []string{"test", "1"},
+			errExpected: errors.New("exactly one of --links, --codes or --regex must be provided"),
+		},
+		{
+			name:        "Several modes provided",
+			args:        []string{"test", "1"},
+			flags:       map[string]string{"links": "true", "codes": "true"},
+			errExpected: errors.New("exactly one of --links, --codes or --regex must be provided"),
+		},
+		{
+			name:        "Invalid code length",
+			args:        []string{"test", "1"},
+			flags:       map[string]string{"codes": "true", "code-length": "0"},
+			errExpected: errors.New(`code length "0" must be greater than 0`),
+		},
+		{
+			name:        "Failure when parsing offset",
+			args:        []string{"test", "a"},
+			flags:       map[string]string{"codes": "true"},
+			errExpected: errors.New(`offset "a" must be an integer`),
+		},
+		{
+			name:  "No mails found",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"codes": "true"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{}, nil
+			},
+			errExpected: errors.New("inbox is empty"),
+		},
+		{
+			name:  "An error is thrown when fetching the mail",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"codes": "true"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := mock()
+				mock.fetchError = errors.New("fetch error")
+				return mock, nil
+			},
+			errExpected: errors.New("fetch error"),
+		},
+		{
+			name:  "Extract links",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"links": "true", "host": "acme.com"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: "https://acme.com/confirm\n",
+		},
+		{
+			name:  "Extract codes",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"codes": "true"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: "123456\n",
+		},
+		{
+			name:  "Extract regexp matches",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"regex": `ID-(\d+)`},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			output: "42\n",
+		},
+		{
+			name:  "Nothing found",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"codes": "true", "code-length": "8"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return mock(), nil
+			},
+			errExpected: errors.New("nothing found"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			addInboxExtractFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetOut(&output)
+			err := inboxExtract(scenario.inboxBuilder)(cmd, scenario.args)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.output, output.String())
+		})
+	}
+}
@@ -4,14 +4,16 @@ import (
 	"fmt"
 
 	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var inboxFlushCmd = &cobra.Command{
-	Use:   "flush <inbox>",
-	Short: "Flush all emails in an inbox",
-	RunE:  inboxFlush(newInbox[client.MailHTMLDoc]),
-	Args:  cobra.ExactArgs(1),
+	Use:               "flush <inbox>",
+	Short:             "Flush all emails in an inbox",
+	RunE:              inboxFlush(newInbox[client.MailHTMLDoc]),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInbox(config.Path),
 }
 
 func inboxFlush(inboxBuilder inboxBuilder) cobraCmd {
@@ -2,18 +2,24 @@ package cmd
 
 import (
 	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var inboxListCmd = &cobra.Command{
-	Use:   "list <inbox> <offset>",
-	Short: "Get all emails from an inbox",
-	RunE:  inboxList(newInbox[client.MailHTMLDoc]),
-	Args:  cobra.ExactArgs(2),
+	Use:               "list <inbox> <offset>",
+	Short:             "Get all emails from an inbox",
+	RunE:              inboxList(newInbox[client.MailHTMLDoc]),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeInbox(config.Path),
 }
 
 func inboxList(inboxBuilder inboxBuilder) cobraCmd {
 	return func(cmd *cobra.Command, args []string) error {
+		renderer, err := newRenderer(cmd)
+		if err != nil {
+			return err
+		}
 		identifier := normalizeInboxName(args[0])
 		offset, err := parseOffset(args[1])
 		if err != nil {
@@ -27,24 +33,16 @@ func inboxList(inboxBuilder inboxBuilder) cobraCmd {
 			return err
 		}
 
-		var output string
-		if dumpJSON {
-			output, err = in.JSON()
-			if err != nil {
-				return err
-			}
-		} else {
-			output, err = in.Coloured()
-			if err != nil {
-				return err
-			}
+		output, err := renderer.Render(unwrapInbox(in))
+		if err != nil {
+			return err
 		}
-
 		cmd.Println(output)
 		return nil
 	}
 }
 
 func init() {
+	addOutputFlag(inboxListCmd)
 	inboxCmd.AddCommand(inboxListCmd)
 }
@@ -7,7 +7,9 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/antham/yogo/v4/internal/client"
 	"github.com/antham/yogo/v4/internal/inbox"
 )
 
@@ -16,12 +18,18 @@ type InboxMock struct {
 	items                      []inbox.InboxItem
 	parseInboxPagesIntArgument int
 	parseInboxPagesError       error
+	refreshItems               [][]inbox.InboxItem
+	refreshCount               int
+	refreshError               error
+	onRefresh                  func(int)
 	fetchIntArgument           int
-	fetchMail                  inbox.Render
+	fetchMail                  inbox.Mail
 	fetchError                 error
+	fetchedIDs                 []string
 	flushError                 error
 	deleteIntArgument          int
 	deleteError                error
+	deletedIDs                 []string
 	coloured                   string
 	colouredErr                error
 	json                       string
@@ -41,11 +49,31 @@ func (i *InboxMock) ParseInboxPages(parseInboxPagesIntArgument int) error {
 	return i.parseInboxPagesError
 }
 
-func (i *InboxMock) Fetch(fetchIntArgument int) (inbox.Render, error) {
+func (i *InboxMock) Refresh(int) error {
+	if i.refreshError != nil {
+		return i.refreshError
+	}
+	if i.refreshCount < len(i.refreshItems) {
+		i.items = i.refreshItems[i.refreshCount]
+		i.count = len(i.items)
+	}
+	i.refreshCount++
+	if i.onRefresh != nil {
+		i.onRefresh(i.refreshCount)
+	}
+	return nil
+}
+
+func (i *InboxMock) Fetch(fetchIntArgument int) (inbox.Mail, error) {
 	i.fetchIntArgument = fetchIntArgument
 	return i.fetchMail, i.fetchError
 }
 
+func (i *InboxMock) FetchByID(ID string) (inbox.Mail, error) {
+	i.fetchedIDs = append(i.fetchedIDs, ID)
+	return i.fetchMail, i.fetchError
+}
+
 func (i *InboxMock) Flush() error {
 	return i.flushError
 }
@@ -55,6 +83,11 @@ func (i *InboxMock) Delete(deleteIntArgument int) error {
 	return i.deleteError
 }
 
+func (i *InboxMock) DeleteByID(ID string) error {
+	i.deletedIDs = append(i.deletedIDs, ID)
+	return i.deleteError
+}
+
 func (i *InboxMock) Coloured() (string, error) {
 	return i.coloured, i.colouredErr
 }
@@ -67,6 +100,7 @@ func TestInboxList(t *testing.T) {
 	type scenario struct {
 		name         string
 		args         []string
+		flags        map[string]string
 		errExpected  error
 		inboxBuilder inboxBuilder
 		output       string
@@ -74,6 +108,12 @@ func TestInboxList(t *testing.T) {
 	}
 
 	scenarios := []scenario{
+		{
+			name:        "Unsupported output",
+			args:        []string{"test", "1"},
+			flags:       map[string]string{"output": "xml"},
+			errExpected: errors.New(`output "xml" is not supported, use one of csv, json, ndjson, table, template, template-file, text, yaml`),
+		},
 		{
 			name:        "No mails found",
 			args:        []string{"test", "1"},
@@ -123,6 +163,22 @@ func TestInboxList(t *testing.T) {
 			},
 			output: ` 1 name123 <test123@protonmail.com>
    title
+`,
+		},
+		{
+			name:  "Render inbox as YAML",
+			args:  []string{"test", "1"},
+			flags: map[string]string{"output": "yaml"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.json = `{"name":"test","mails":[{"id":"abcdefg","subject":"title","isSPAM":false}]}`
+				return mock, nil
+			},
+			output: `name: test
+mails:
+  - id: abcdefg
+    subject: title
+    isSPAM: false
 `,
 		},
 	}
@@ -134,6 +190,10 @@ func TestInboxList(t *testing.T) {
 			var output bytes.Buffer
 			var outputErr bytes.Buffer
 			cmd := &cobra.Command{}
+			addOutputFlag(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
 			cmd.SetOut(&output)
 			cmd.SetErr(&outputErr)
 			err := inboxList(scenario.inboxBuilder)(cmd, scenario.args)
@@ -143,3 +203,70 @@ func TestInboxList(t *testing.T) {
 		})
 	}
 }
+
+// listedInbox is an inbox already listed, it is rendered by the inbox package
+type listedInbox struct {
+	*inbox.Inbox[client.MailHTMLDoc]
+}
+
+func (l listedInbox) ParseInboxPages(int) error {
+	return nil
+}
+
+func TestInboxListArchived(t *testing.T) {
+	type scenario struct {
+		name   string
+		output string
+		// expected renders the listed inbox, the colours depend on the terminal
+		expected func(listedInbox) (string, error)
+	}
+
+	scenarios := []scenario{
+		{
+			name:     "Text",
+			expected: func(in listedInbox) (string, error) { return in.Coloured() },
+		},
+		{
+			name:   "JSON",
+			output: "json",
+			expected: func(listedInbox) (string, error) {
+				return `{"name":"test","mails":[{"id":"a","subject":"Welcome","isSPAM":false}]}`, nil
+			},
+		},
+		{
+			name:   "CSV",
+			output: "csv",
+			expected: func(listedInbox) (string, error) {
+				return "offset,id,sender_name,sender_mail,subject,date,spam\n1,a,,,Welcome,,false", nil
+			},
+		},
+	}
+
+	t.Setenv("YOGO_ARCHIVE", "true")
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			opener := newArchiveOpener(t)
+			in := listedInbox{&inbox.Inbox[client.MailHTMLDoc]{Name: "test", InboxItems: []inbox.InboxItem{{ID: "a", Subject: "Welcome"}}}}
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			addOutputFlag(cmd)
+			require.NoError(t, cmd.Flags().Set("output", scenario.output))
+			cmd.SetOut(&output)
+
+			err := inboxList(func(name string) (Inbox, error) {
+				return withArchive(in, name, opener)
+			})(cmd, []string{"test", "1"})
+			require.NoError(t, err)
+			expected, err := scenario.expected(in)
+			require.NoError(t, err)
+			assert.Equal(t, expected+"\n", output.String())
+
+			store, err := opener()
+			require.NoError(t, err)
+			r, err := store.Get("test", "a")
+			require.NoError(t, err)
+			assert.Equal(t, "Welcome", r.Subject)
+		})
+	}
+}
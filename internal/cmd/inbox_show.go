@@ -1,26 +1,54 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
 	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/antham/yogo/v4/internal/inbox"
 	"github.com/spf13/cobra"
 )
 
 var inboxShowCmd = &cobra.Command{
-	Use:   "show <inbox> <offset>",
-	Short: "Show full email at given position in inbox",
-	RunE:  inboxShow(newInbox[client.MailHTMLDoc]),
-	Args:  cobra.ExactArgs(2),
+	Use:               "show <inbox> <offset>",
+	Short:             "Show full email at given position in inbox",
+	RunE:              inboxShow(newInbox[client.MailHTMLDoc]),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeInbox(config.Path),
 }
 
 var inboxSourceCmd = &cobra.Command{
-	Use:   "source <inbox> <offset>",
-	Short: "Show the email source at given position in inbox",
-	RunE:  inboxShow(newInbox[client.MailSourceDoc]),
-	Args:  cobra.ExactArgs(2),
+	Use:               "source <inbox> <offset>",
+	Short:             "Show the email source at given position in inbox",
+	RunE:              inboxShow(newInbox[client.MailSourceDoc]),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addInboxShowFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("html", false, "Output the original HTML of the email")
+	cmd.Flags().String("save-html", "", "Write the email as a standalone HTML document in this file")
+	cmd.Flags().Bool("open", false, "Open the HTML document with $BROWSER")
+	cmd.Flags().Bool("hyperlinks", false, "Render links as terminal hyperlinks instead of numbered footnotes")
+}
+
+func addInboxSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("raw", false, "Display the body as received, without decoding it")
+	cmd.Flags().StringSlice("headers", []string{}, "Display only these headers (e.g. From,Subject,DKIM-Signature)")
+	cmd.Flags().StringSlice("exclude-headers", []string{}, "Hide these headers (e.g. Received)")
+	cmd.Flags().Bool("headers-only", false, "Display the headers without the body")
 }
 
 func inboxShow(inboxBuilder inboxBuilder) cobraCmd {
 	return func(cmd *cobra.Command, args []string) error {
+		renderer, err := newRenderer(cmd)
+		if err != nil {
+			return err
+		}
 		identifier := normalizeInboxName(args[0])
 		offset, err := parseOffset(args[1])
 		if err != nil {
@@ -44,26 +72,131 @@ func inboxShow(inboxBuilder inboxBuilder) cobraCmd {
 		if mail == nil {
 			return nil
 		}
-
-		var output string
-		if dumpJSON {
-			output, err = mail.JSON()
+		if page, ok := mail.(inbox.HTMLPage); ok {
+			done, err := showHTML(cmd, page)
+			if err != nil || done {
+				return err
+			}
+		}
+		if source, ok := mail.(inbox.Source); ok {
+			raw, err := cmd.Flags().GetBool("raw")
 			if err != nil {
 				return err
 			}
-		} else {
-			output, err = mail.Coloured()
+			source.SetRaw(raw)
+			include, err := cmd.Flags().GetStringSlice("headers")
 			if err != nil {
 				return err
 			}
+			exclude, err := cmd.Flags().GetStringSlice("exclude-headers")
+			if err != nil {
+				return err
+			}
+			source.FilterHeaders(include, exclude)
+			headersOnly, err := cmd.Flags().GetBool("headers-only")
+			if err != nil {
+				return err
+			}
+			source.SetHeadersOnly(headersOnly)
 		}
 
+		output, err := renderer.Render(mail)
+		if err != nil {
+			return err
+		}
 		cmd.Println(output)
 		return nil
 	}
 }
 
+// showHTML handles the HTML flags, it reports if
+// the default output must be skipped
+func showHTML(cmd *cobra.Command, page inbox.HTMLPage) (bool, error) {
+	// the flags are only defined on the show command
+	if cmd.Flags().Lookup("html") == nil {
+		return false, nil
+	}
+	hyperlinks, err := cmd.Flags().GetBool("hyperlinks")
+	if err != nil {
+		return false, err
+	}
+	page.SetHyperlinks(hyperlinks)
+	html, err := cmd.Flags().GetBool("html")
+	if err != nil {
+		return false, err
+	}
+	path, err := cmd.Flags().GetString("save-html")
+	if err != nil {
+		return false, err
+	}
+	open, err := cmd.Flags().GetBool("open")
+	if err != nil {
+		return false, err
+	}
+	if !html && path == "" && !open {
+		return false, nil
+	}
+
+	if path != "" || open {
+		doc, err := page.Document()
+		if err != nil {
+			return false, err
+		}
+		if path == "" {
+			f, err := os.CreateTemp("", "yogo-*.html")
+			if err != nil {
+				return false, err
+			}
+			path = f.Name()
+			if err := f.Close(); err != nil {
+				return false, err
+			}
+		}
+		if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+			return false, err
+		}
+		cmd.Println(success(fmt.Sprintf(`HTML document saved to "%s"`, path)))
+		if open {
+			if err := openBrowser(path); err != nil {
+				return false, err
+			}
+		}
+	}
+	if html {
+		cmd.Println(page.HTML())
+	}
+	return true, nil
+}
+
+// openBrowser launches the first browser of the $BROWSER list that can be started
+func openBrowser(path string) error {
+	browsers := strings.Split(os.Getenv("BROWSER"), ":")
+	errs := []error{}
+	for _, browser := range browsers {
+		args := strings.Fields(browser)
+		if len(args) == 0 {
+			continue
+		}
+		c := exec.Command(args[0], append(args[1:], path)...)
+		if err := c.Start(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return c.Process.Release()
+	}
+	if len(errs) == 0 {
+		return errors.New("no browser defined, set the BROWSER environment variable")
+	}
+	return fmt.Errorf("failure when launching the browser : %w", errors.Join(errs...))
+}
+
 func init() {
+	addInboxShowFlags(inboxShowCmd)
+	addOutputFlag(inboxShowCmd)
+	addCacheFlags(inboxShowCmd)
 	inboxCmd.AddCommand(inboxShowCmd)
+	addInboxSourceFlags(inboxSourceCmd)
+	addOutputFlag(inboxSourceCmd)
+	addCacheFlags(inboxSourceCmd)
 	inboxCmd.AddCommand(inboxSourceCmd)
 }
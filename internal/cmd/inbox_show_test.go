@@ -3,6 +3,9 @@ package cmd
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -12,12 +15,27 @@ import (
 )
 
 type MailMock struct {
+	content     string
+	links       []inbox.Link
+	attachments []inbox.Attachment
 	coloured    string
 	colouredErr error
 	json        string
 	jsonErr     error
 }
 
+func (m MailMock) Content() string {
+	return m.content
+}
+
+func (m MailMock) GetLinks() []inbox.Link {
+	return m.links
+}
+
+func (m MailMock) GetAttachments() []inbox.Attachment {
+	return m.attachments
+}
+
 func (m MailMock) Coloured() (string, error) {
 	return m.coloured, m.colouredErr
 }
@@ -26,16 +44,82 @@ func (m MailMock) JSON() (string, error) {
 	return m.json, m.jsonErr
 }
 
+type SourceMailMock struct {
+	MailMock
+	raw         bool
+	include     []string
+	exclude     []string
+	headersOnly bool
+	message     []byte
+}
+
+func (m *SourceMailMock) Message() []byte {
+	return m.message
+}
+
+func (m *SourceMailMock) SetRaw(raw bool) {
+	m.raw = raw
+}
+
+func (m *SourceMailMock) FilterHeaders(include []string, exclude []string) {
+	m.include = include
+	m.exclude = exclude
+}
+
+func (m *SourceMailMock) SetHeadersOnly(headersOnly bool) {
+	m.headersOnly = headersOnly
+}
+
+func (m *SourceMailMock) Coloured() (string, error) {
+	switch {
+	case m.raw:
+		return "raw", nil
+	case m.headersOnly:
+		return fmt.Sprintf("headers only %v %v", m.include, m.exclude), nil
+	}
+	return m.coloured, m.colouredErr
+}
+
+type HTMLMailMock struct {
+	MailMock
+	html        string
+	document    string
+	documentErr error
+	width       int
+}
+
+func (m HTMLMailMock) HTML() string {
+	return m.html
+}
+
+func (m HTMLMailMock) Document() (string, error) {
+	return m.document, m.documentErr
+}
+
+func (m *HTMLMailMock) SetWidth(width int) {
+	m.width = width
+}
+
+func (m *HTMLMailMock) SetHyperlinks(hyperlinks bool) {
+	if hyperlinks {
+		m.coloured = "hyperlinks"
+	}
+}
+
 func TestInboxShow(t *testing.T) {
 	type scenario struct {
 		name         string
 		args         []string
+		flags        map[string]string
 		errExpected  error
 		inboxBuilder inboxBuilder
 		output       string
 		outputErr    string
+		files        map[string]string
 	}
 
+	dir := t.TempDir()
+
 	scenarios := []scenario{
 		{
 			name:        "No mails found",
@@ -166,6 +250,97 @@ body
 				return mock, nil
 			},
 		},
+		{
+			name:   "Output the HTML fragment",
+			args:   []string{"test", "1"},
+			flags:  map[string]string{"html": "true"},
+			output: "<p>body</p>\n",
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.count = 1
+				mock.items = []inbox.InboxItem{{ID: "abcdefg"}}
+				mock.fetchMail = &HTMLMailMock{MailMock: MailMock{coloured: "text"}, html: "<p>body</p>"}
+				return mock, nil
+			},
+		},
+		{
+			name:   "Output links as hyperlinks",
+			args:   []string{"test", "1"},
+			flags:  map[string]string{"hyperlinks": "true"},
+			output: "hyperlinks\n",
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.count = 1
+				mock.items = []inbox.InboxItem{{ID: "abcdefg"}}
+				mock.fetchMail = &HTMLMailMock{MailMock: MailMock{coloured: "text"}}
+				return mock, nil
+			},
+		},
+		{
+			name:        "An error is thrown when rendering the HTML document",
+			args:        []string{"test", "1"},
+			flags:       map[string]string{"save-html": filepath.Join(dir, "error.html")},
+			errExpected: errors.New("document error"),
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.count = 1
+				mock.items = []inbox.InboxItem{{ID: "abcdefg"}}
+				mock.fetchMail = &HTMLMailMock{documentErr: errors.New("document error")}
+				return mock, nil
+			},
+		},
+		{
+			name:   "Save the HTML document",
+			args:   []string{"test", "1"},
+			flags:  map[string]string{"save-html": filepath.Join(dir, "mail.html")},
+			output: `HTML document saved to "` + filepath.Join(dir, "mail.html") + `"` + "\n",
+			files:  map[string]string{filepath.Join(dir, "mail.html"): "<html></html>"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.count = 1
+				mock.items = []inbox.InboxItem{{ID: "abcdefg"}}
+				mock.fetchMail = &HTMLMailMock{MailMock: MailMock{coloured: "text"}, document: "<html></html>"}
+				return mock, nil
+			},
+		},
+		{
+			name:   "Output the decoded source",
+			args:   []string{"test", "1"},
+			output: "decoded\n",
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.count = 1
+				mock.items = []inbox.InboxItem{{ID: "abcdefg"}}
+				mock.fetchMail = &SourceMailMock{MailMock: MailMock{coloured: "decoded"}}
+				return mock, nil
+			},
+		},
+		{
+			name:   "Output the raw source",
+			args:   []string{"test", "1"},
+			flags:  map[string]string{"raw": "true"},
+			output: "raw\n",
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.count = 1
+				mock.items = []inbox.InboxItem{{ID: "abcdefg"}}
+				mock.fetchMail = &SourceMailMock{MailMock: MailMock{coloured: "decoded"}}
+				return mock, nil
+			},
+		},
+		{
+			name:   "Output filtered source headers",
+			args:   []string{"test", "1"},
+			flags:  map[string]string{"headers": "From,Subject", "exclude-headers": "Received", "headers-only": "true"},
+			output: "headers only [From Subject] [Received]\n",
+			inboxBuilder: func(name string) (Inbox, error) {
+				mock := &InboxMock{}
+				mock.count = 1
+				mock.items = []inbox.InboxItem{{ID: "abcdefg"}}
+				mock.fetchMail = &SourceMailMock{MailMock: MailMock{coloured: "decoded"}}
+				return mock, nil
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -175,12 +350,47 @@ body
 			var output bytes.Buffer
 			var outputErr bytes.Buffer
 			cmd := &cobra.Command{}
+			addInboxShowFlags(cmd)
+			addInboxSourceFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
 			cmd.SetOut(&output)
 			cmd.SetErr(&outputErr)
 			err := inboxShow(scenario.inboxBuilder)(cmd, scenario.args)
 			assert.Equal(t, scenario.errExpected, err)
 			assert.Equal(t, scenario.output, output.String())
 			assert.Equal(t, scenario.outputErr, outputErr.String())
+			for path, content := range scenario.files {
+				b, err := os.ReadFile(path)
+				assert.NoError(t, err)
+				assert.Equal(t, content, string(b))
+				// the email can be private, only its reader can read it
+				info, err := os.Stat(path)
+				assert.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+			}
 		})
 	}
 }
+
+func TestOpenBrowser(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "browser")
+	assert.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncp \"$1\" \""+filepath.Join(dir, "opened.html")+"\"\n"), 0o755))
+	page := filepath.Join(dir, "mail.html")
+	assert.NoError(t, os.WriteFile(page, []byte("<html></html>"), 0o644))
+
+	t.Setenv("BROWSER", "")
+	assert.EqualError(t, openBrowser(page), "no browser defined, set the BROWSER environment variable")
+
+	t.Setenv("BROWSER", filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, openBrowser(page), "failure when launching the browser : ")
+
+	t.Setenv("BROWSER", filepath.Join(dir, "missing")+":"+script)
+	assert.NoError(t, openBrowser(page))
+	assert.Eventually(t, func() bool {
+		b, err := os.ReadFile(filepath.Join(dir, "opened.html"))
+		return err == nil && string(b) == "<html></html>"
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var errMailFound = errors.New("email found")
+
+var inboxWaitCmd = &cobra.Command{
+	Use:               "wait <inbox>",
+	Short:             "Wait until an email matching the filters arrives and display it",
+	RunE:              inboxWait(newPollingInbox[client.MailHTMLDoc]),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addInboxWaitFlags(cmd *cobra.Command) {
+	addMailFilterFlags(cmd.Flags())
+	addPollerFlags(cmd, 5*time.Second)
+	cmd.Flags().String("body", "", "Select emails whose body matches this regexp")
+	cmd.Flags().Duration("timeout", 2*time.Minute, "Maximum time to wait for the email")
+	cmd.Flags().Bool("include-existing", false, "Also consider the emails received before the command started")
+	addExecFlags(cmd, hookStop, hookStop, false)
+}
+
+func inboxWait(inboxBuilder inboxBuilder) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		filter, err := newMailFilter(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		body, err := compileFlagRegexp(cmd.Flags(), "body")
+		if err != nil {
+			return err
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+		includeExisting, err := cmd.Flags().GetBool("include-existing")
+		if err != nil {
+			return err
+		}
+		p, err := newPollerFromFlags(cmd, inboxBuilder, args[0])
+		if err != nil {
+			return err
+		}
+		execHook, err := newExecHookFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		name := normalizeInboxName(args[0])
+		ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		onNew := func(item polledItem) error {
+			if !filter.match(item.item, time.Now()) {
+				return nil
+			}
+			mail, err := p.in.Fetch(item.offset - 1)
+			if err != nil {
+				return err
+			}
+			if body != nil && !body.MatchString(mail.Content()) {
+				return nil
+			}
+			renderer, err := newRenderer(cmd)
+			if err != nil {
+				return err
+			}
+			output, err := renderer.Render(mail)
+			if err != nil {
+				return err
+			}
+			cmd.Println(output)
+			if execHook == nil {
+				return errMailFound
+			}
+			// The wait goes on when the outcome of the command asks to continue
+			action, err := execHook.run(ctx, cmd.OutOrStdout(), cmd.ErrOrStderr(), p.in, name, item)
+			if err != nil {
+				return err
+			}
+			if action == hookContinue {
+				return nil
+			}
+			return errMailFound
+		}
+
+		items, err := p.poll()
+		if err != nil {
+			return err
+		}
+		if includeExisting {
+			for _, item := range items {
+				if err := onNew(item); err != nil {
+					return ignoreMailFound(err)
+				}
+			}
+		}
+		if err := p.run(ctx, onNew); err != nil {
+			return ignoreMailFound(err)
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("no matching email received after %s", timeout)
+		}
+		return errors.New("interrupted before a matching email was received")
+	}
+}
+
+func ignoreMailFound(err error) error {
+	if errors.Is(err, errMailFound) {
+		return nil
+	}
+	return err
+}
+
+func init() {
+	addInboxWaitFlags(inboxWaitCmd)
+	inboxCmd.AddCommand(inboxWaitCmd)
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInboxWait(t *testing.T) {
+	type scenario struct {
+		name         string
+		args         []string
+		flags        map[string]string
+		errExpected  error
+		inboxBuilder inboxBuilder
+		output       string
+	}
+
+	existing := inbox.InboxItem{ID: "a", Subject: "Verify your account", Sender: &inbox.Sender{Name: "Acme"}}
+	other := inbox.InboxItem{ID: "b", Subject: "Newsletter", Sender: &inbox.Sender{Name: "Acme"}}
+	expected := inbox.InboxItem{ID: "c", Subject: "Verify your account", Sender: &inbox.Sender{Name: "Acme"}}
+
+	scenarios := []scenario{
+		{
+			name:        "Invalid body regexp",
+			args:        []string{"test"},
+			flags:       map[string]string{"body": "["},
+			errExpected: errors.New("--body regexp \"[\" is invalid : error parsing regexp: missing closing ]: `[`"),
+		},
+		{
+			name:        "Interval too short",
+			args:        []string{"test"},
+			flags:       map[string]string{"interval": "1ms"},
+			errExpected: errors.New(`interval "1ms" must be at least 1s`),
+		},
+		{
+			name:  "An error is thrown when refreshing the inbox",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{refreshError: errors.New("refresh error")}, nil
+			},
+			errExpected: errors.New("refresh error"),
+		},
+		{
+			name:  "Timeout reached",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s", "timeout": "1500ms", "subject": "Verify"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{
+					refreshItems: [][]inbox.InboxItem{{existing}, {other, existing}},
+					fetchMail:    MailMock{coloured: "mail"},
+				}, nil
+			},
+			errExpected: errors.New("no matching email received after 1.5s"),
+		},
+		{
+			name:  "Existing email included",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s", "subject": "Verify", "include-existing": "true"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{
+					refreshItems: [][]inbox.InboxItem{{existing}},
+					fetchMail:    MailMock{coloured: "mail"},
+				}, nil
+			},
+			output: "mail\n",
+		},
+		{
+			name:  "New email received",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s", "subject": "Verify", "body": "code: \\d{6}"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{
+					refreshItems: [][]inbox.InboxItem{{existing}, {other, existing}, {expected, other, existing}},
+					fetchMail:    MailMock{coloured: "mail", content: "code: 123456"},
+				}, nil
+			},
+			output: "mail\n",
+		},
+		{
+			name:  "Body doesn't match",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s", "timeout": "1500ms", "body": "code: \\d{6}"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{
+					refreshItems: [][]inbox.InboxItem{{}, {expected}},
+					fetchMail:    MailMock{coloured: "mail", content: "no code"},
+				}, nil
+			},
+			errExpected: errors.New("no matching email received after 1.5s"),
+		},
+		{
+			name:  "An error is thrown when fetching the email",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s", "include-existing": "true"},
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{
+					refreshItems: [][]inbox.InboxItem{{existing}},
+					fetchError:   errors.New("fetch error"),
+				}, nil
+			},
+			errExpected: errors.New("fetch error"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			addInboxWaitFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetOut(&output)
+			err := inboxWait(scenario.inboxBuilder)(cmd, scenario.args)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.output, output.String())
+		})
+	}
+}
+
+func TestInboxWaitFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	addInboxWaitFlags(cmd)
+	assert.NotNil(t, cmd.Flags().Lookup("from"))
+	assert.NotNil(t, cmd.Flags().Lookup("subject"))
+	// a new email can't be older than a duration or already flagged as SPAM
+	assert.Nil(t, cmd.Flags().Lookup("older-than"))
+	assert.Nil(t, cmd.Flags().Lookup("spam"))
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+)
+
+var inboxWatchCmd = &cobra.Command{
+	Use:               "watch <inbox>",
+	Short:             "Follow an inbox and display new emails as they arrive",
+	RunE:              inboxWatch(newPollingInbox[client.MailHTMLDoc]),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addInboxWatchFlags(cmd *cobra.Command) {
+	addPollerFlags(cmd, 10*time.Second)
+	cmd.Flags().Bool("show", false, "Fetch and display the full content of new emails")
+	addWebhookFlags(cmd)
+	addExecFlags(cmd, hookContinue, hookContinue, true)
+}
+
+func inboxWatch(inboxBuilder inboxBuilder) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		p, err := newPollerFromFlags(cmd, inboxBuilder, args[0])
+		if err != nil {
+			return err
+		}
+		show, err := cmd.Flags().GetBool("show")
+		if err != nil {
+			return err
+		}
+		webhook, err := newWebhookFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		execHook, err := newExecHookFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		if _, err := p.poll(); err != nil {
+			return err
+		}
+		name := normalizeInboxName(args[0])
+		var hookErr error
+		var stopOnce sync.Once
+		out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+		if execHook != nil {
+			// The commands run and write concurrently with the watch
+			p.in = &lockedInbox{in: p.in}
+			out, errOut = syncWriters(out, errOut)
+		}
+		err = p.run(ctx, func(item polledItem) error {
+			mail, err := printPolledItem(cmd, out, p.in, item, show)
+			if err != nil {
+				return err
+			}
+			if webhook != nil {
+				// A receiver being down must not stop the watch
+				if err := webhook.notify(ctx, p.in, name, item, mail); err != nil && ctx.Err() == nil {
+					fmt.Fprintf(errOut, "Webhook delivery failed for email \"%s\" : %s\n", item.item.ID, err)
+				}
+			}
+			if execHook != nil {
+				execHook.start(ctx, out, errOut, p.in, name, item, func(action string, err error) {
+					if action == hookStop {
+						stopOnce.Do(func() {
+							hookErr = err
+							cancel()
+						})
+					}
+				})
+			}
+			return nil
+		})
+		if execHook != nil {
+			execHook.wait()
+		}
+		if err != nil {
+			return err
+		}
+		return hookErr
+	}
+}
+
+// printPolledItem outputs an email summary as a coloured line or as a JSON line,
+// when show is enabled the full email is fetched, rendered and returned
+func printPolledItem(cmd *cobra.Command, out io.Writer, in Inbox, p polledItem, show bool) (inbox.Mail, error) {
+	if !show {
+		if !dumpJSON {
+			fmt.Fprintln(out, formatInboxItem(p.offset, p.item))
+			return nil, nil
+		}
+		data, err := json.Marshal(p.item)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil, nil
+	}
+
+	mail, err := in.FetchByID(p.item.ID)
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := newRenderer(cmd)
+	if err != nil {
+		return nil, err
+	}
+	output, err := renderer.Render(mail)
+	if err != nil {
+		return nil, err
+	}
+	if dumpJSON {
+		fmt.Fprintln(out, output)
+		return mail, nil
+	}
+	fmt.Fprintln(out, formatInboxItem(p.offset, p.item))
+	fmt.Fprintln(out, output)
+	return mail, nil
+}
+
+func init() {
+	addInboxWatchFlags(inboxWatchCmd)
+	inboxCmd.AddCommand(inboxWatchCmd)
+}
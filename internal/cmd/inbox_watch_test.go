@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInboxWatch(t *testing.T) {
+	type scenario struct {
+		name         string
+		args         []string
+		flags        map[string]string
+		errExpected  error
+		inboxBuilder func(context.CancelFunc) inboxBuilder
+		output       string
+	}
+
+	first := inbox.InboxItem{ID: "a", Subject: "first", Sender: &inbox.Sender{Name: "Acme"}}
+	second := inbox.InboxItem{ID: "b", Subject: "second", Sender: &inbox.Sender{Name: "Acme"}}
+	third := inbox.InboxItem{ID: "c", Subject: "third", Sender: &inbox.Sender{Mail: "test@protonmail.com"}, IsSPAM: true}
+
+	scenarios := []scenario{
+		{
+			name:        "Interval too short",
+			args:        []string{"test"},
+			flags:       map[string]string{"interval": "10ms"},
+			errExpected: errors.New(`interval "10ms" must be at least 1s`),
+		},
+		{
+			name:        "Invalid limit",
+			args:        []string{"test"},
+			flags:       map[string]string{"interval": "1s", "limit": "0"},
+			errExpected: errors.New(`limit "0" must be greater than 0`),
+		},
+		{
+			name:  "An error is thrown in inbox builder",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s"},
+			inboxBuilder: func(cancel context.CancelFunc) inboxBuilder {
+				return func(name string) (Inbox, error) {
+					return nil, errors.New("inbox builder error")
+				}
+			},
+			errExpected: errors.New("inbox builder error"),
+		},
+		{
+			name:  "An error is thrown when refreshing the inbox",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s"},
+			inboxBuilder: func(cancel context.CancelFunc) inboxBuilder {
+				return func(name string) (Inbox, error) {
+					return &InboxMock{refreshError: errors.New("refresh error")}, nil
+				}
+			},
+			errExpected: errors.New("refresh error"),
+		},
+		{
+			name:  "Display new emails only",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s"},
+			inboxBuilder: func(cancel context.CancelFunc) inboxBuilder {
+				return func(name string) (Inbox, error) {
+					return &InboxMock{
+						refreshItems: [][]inbox.InboxItem{
+							{first},
+							{third, second, first},
+						},
+						onRefresh: func(count int) {
+							if count == 2 {
+								cancel()
+							}
+						},
+					}, nil
+				}
+			},
+			output: ` 2 Acme : second
+ 1 test@protonmail.com [SPAM] : third
+`,
+		},
+		{
+			name:  "Display the full content of new emails",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s", "show": "true"},
+			inboxBuilder: func(cancel context.CancelFunc) inboxBuilder {
+				return func(name string) (Inbox, error) {
+					return &InboxMock{
+						refreshItems: [][]inbox.InboxItem{
+							{},
+							{second},
+						},
+						fetchMail: MailMock{coloured: "---\nbody\n---"},
+						onRefresh: func(count int) {
+							if count == 2 {
+								cancel()
+							}
+						},
+					}, nil
+				}
+			},
+			output: ` 1 Acme : second
+---
+body
+---
+`,
+		},
+		{
+			name:  "An error is thrown when fetching a new email",
+			args:  []string{"test"},
+			flags: map[string]string{"interval": "1s", "show": "true"},
+			inboxBuilder: func(cancel context.CancelFunc) inboxBuilder {
+				return func(name string) (Inbox, error) {
+					return &InboxMock{
+						refreshItems: [][]inbox.InboxItem{{}, {second}},
+						fetchError:   errors.New("fetch error"),
+					}, nil
+				}
+			},
+			errExpected: errors.New("fetch error"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cmd := &cobra.Command{}
+			cmd.SetContext(ctx)
+			addInboxWatchFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			cmd.SetOut(&output)
+			var builder inboxBuilder
+			if scenario.inboxBuilder != nil {
+				builder = scenario.inboxBuilder(cancel)
+			}
+			err := inboxWatch(builder)(cmd, scenario.args)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.output, output.String())
+		})
+	}
+}
+
+func TestInboxWatchJSON(t *testing.T) {
+	dumpJSON = true
+	defer func() { dumpJSON = false }()
+
+	var output bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	addInboxWatchFlags(cmd)
+	assert.NoError(t, cmd.Flags().Set("interval", "1s"))
+	cmd.SetOut(&output)
+	err := inboxWatch(func(name string) (Inbox, error) {
+		return &InboxMock{
+			refreshItems: [][]inbox.InboxItem{{}, {{ID: "a", Subject: "subject", Sender: &inbox.Sender{Name: "Acme"}}}},
+			onRefresh: func(count int) {
+				if count == 2 {
+					cancel()
+				}
+			},
+		}, nil
+	})(cmd, []string{"test"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a","sender":{"name":"Acme"},"subject":"subject","isSPAM":false}`, output.String())
+}
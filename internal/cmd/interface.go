@@ -5,11 +5,13 @@ import (
 )
 
 type Inbox interface {
-	inbox.Render
 	ParseInboxPages(int) error
+	Refresh(int) error
 	Count() int
 	GetMails() []inbox.InboxItem
-	Fetch(int) (inbox.Render, error)
+	Fetch(int) (inbox.Mail, error)
+	FetchByID(string) (inbox.Mail, error)
 	Flush() error
 	Delete(int) error
+	DeleteByID(string) error
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run a Model Context Protocol server over stdio giving access to the inboxes",
+	Long: `Run a Model Context Protocol server over stdio giving access to the inboxes.
+
+The tools list_inbox, read_mail, read_source, wait_for_mail and extract_links
+are provided, delete_mail is only provided with --allow-delete.`,
+	RunE: serveMCP(newMCPInboxBuilder),
+	Args: cobra.NoArgs,
+}
+
+func addMCPFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("allow-delete", false, "Provide the tool deleting emails")
+	cmd.Flags().Int("limit", defaultPollLimit, "Number of emails listed when the tool doesn't define a limit")
+	cmd.Flags().Duration("poll-interval", 5*time.Second, "Delay between two checks of an inbox while waiting for an email")
+	cmd.Flags().Duration("max-wait", 2*time.Minute, "Maximum time a tool waits for an email")
+}
+
+// newMCPInboxBuilder uses the session shared by the REST server
+func newMCPInboxBuilder() (backend.Builder, error) {
+	// The debug output would be mixed with the messages sent to the client
+	if enableDebugMode {
+		return nil, errors.New("debug mode can't be enabled, the standard output is used by the protocol")
+	}
+	return newServerInboxBuilder()
+}
+
+func serveMCP(serverInboxBuilder serverInboxBuilder) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		allowDelete, err := cmd.Flags().GetBool("allow-delete")
+		if err != nil {
+			return err
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+		if limit < 1 {
+			return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+		}
+		interval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return err
+		}
+		if interval < minPollInterval {
+			return fmt.Errorf(`poll interval "%s" must be at least %s`, interval, minPollInterval)
+		}
+		maxWait, err := cmd.Flags().GetDuration("max-wait")
+		if err != nil {
+			return err
+		}
+		if maxWait <= 0 {
+			return fmt.Errorf(`max wait "%s" must be positive`, maxWait)
+		}
+
+		build, err := serverInboxBuilder()
+		if err != nil {
+			return err
+		}
+		s := mcp.New(build, mcp.Config{
+			Limit:        limit,
+			PollInterval: interval,
+			MaxWait:      maxWait,
+			AllowDelete:  allowDelete,
+			Name:         normalizeInboxName,
+			Version:      version,
+		})
+
+		ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return s.Serve(ctx, cmd.InOrStdin(), cmd.OutOrStdout())
+	}
+}
+
+func init() {
+	addMCPFlags(mcpCmd)
+	RootCmd.AddCommand(mcpCmd)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeMCP(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		builder     serverInboxBuilder
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:        "Invalid limit",
+			flags:       map[string]string{"limit": "0"},
+			errExpected: `limit "0" must be greater than 0`,
+		},
+		{
+			name:        "Poll interval too short",
+			flags:       map[string]string{"poll-interval": "10ms"},
+			errExpected: `poll interval "10ms" must be at least 1s`,
+		},
+		{
+			name:        "Invalid max wait",
+			flags:       map[string]string{"max-wait": "0s"},
+			errExpected: `max wait "0s" must be positive`,
+		},
+		{
+			name: "Session can't be opened",
+			builder: func() (backend.Builder, error) {
+				return nil, errors.New("session error")
+			},
+			errExpected: "session error",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addMCPFlags(cmd)
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			err := serveMCP(scenario.builder)(cmd, []string{})
+			assert.EqualError(t, err, scenario.errExpected)
+		})
+	}
+}
+
+func TestServeMCPSession(t *testing.T) {
+	type scenario struct {
+		name        string
+		allowDelete bool
+		expected    string
+		deletedIDs  []string
+	}
+	scenarios := []scenario{
+		{
+			name:     "Delete tool not provided",
+			expected: `{"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"unknown tool \"delete_mail\""}}`,
+		},
+		{
+			name:        "Delete tool provided",
+			allowDelete: true,
+			expected:    `{"jsonrpc":"2.0","id":2,"result":{"content":[{"text":"Email \"a\" deleted","type":"text"}],"isError":false}}`,
+			deletedIDs:  []string{"a"},
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetIn(strings.NewReader(
+				`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_inbox","arguments":{"inbox":"TEST@yopmail.com"}}}` + "\n" +
+					`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"delete_mail","arguments":{"inbox":"test","id":"a"}}}` + "\n",
+			))
+			cmd.SetOut(&output)
+			addMCPFlags(cmd)
+			if scenario.allowDelete {
+				require.NoError(t, cmd.Flags().Set("allow-delete", "true"))
+			}
+
+			names := []string{}
+			in := &InboxMock{refreshItems: [][]inbox.InboxItem{{{ID: "a", Subject: "Welcome"}}, {{ID: "a", Subject: "Welcome"}}}}
+			err := serveMCP(func() (backend.Builder, error) {
+				return func(name string, source bool) (backend.Inbox, error) {
+					names = append(names, name)
+					return in, nil
+				}, nil
+			})(cmd, []string{})
+			require.NoError(t, err)
+
+			lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+			require.Len(t, lines, 2)
+			assert.Contains(t, lines, `{"jsonrpc":"2.0","id":1,"result":{"content":[{"text":"{\"name\":\"test\",\"mails\":[{\"id\":\"a\",\"subject\":\"Welcome\",\"isSPAM\":false}]}","type":"text"}],"isError":false}}`)
+			assert.Contains(t, lines, scenario.expected)
+			assert.Equal(t, scenario.deletedIDs, in.deletedIDs)
+			assert.Contains(t, names, "test")
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/antham/yogo/v4/internal/render"
+	"github.com/spf13/cobra"
+)
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "", "Output format: text, json, ndjson, yaml, csv, table, template=<template> or template-file=<path>")
+}
+
+// newRenderer returns the renderer selected with the output flag, the
+// json flag remains a shortcut for the json output, YOGO_OUTPUT comes next
+func newRenderer(cmd *cobra.Command) (render.Renderer, error) {
+	var output string
+	if cmd.Flags().Lookup("output") != nil {
+		var err error
+		if output, err = cmd.Flags().GetString("output"); err != nil {
+			return nil, err
+		}
+	}
+	if output == "" && dumpJSON {
+		output = "json"
+	}
+	if output == "" {
+		output = os.Getenv("YOGO_OUTPUT")
+	}
+	return render.New(output)
+}
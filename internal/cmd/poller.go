@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+)
+
+const minPollInterval = time.Second
+
+// pollRequestInterval spaces out the requests of the commands polling
+// yopmail, they run for a long time and would trigger a CAPTCHA otherwise
+const pollRequestInterval = 500 * time.Millisecond
+
+// defaultPollLimit matches the number of emails in an inbox page
+const defaultPollLimit = 15
+
+// polledItem is an email found while polling with its offset in the
+// inbox at the time it was listed, the email is fetched by its ID as
+// the offsets change when emails are deleted by the hooks or by other
+// commands
+type polledItem struct {
+	offset int
+	item   inbox.InboxItem
+}
+
+// poller lists an inbox at regular intervals and
+// reports the emails it has not seen before
+type poller struct {
+	in       Inbox
+	interval time.Duration
+	limit    int
+	seen     map[string]bool
+}
+
+func newPoller(in Inbox, interval time.Duration, limit int) *poller {
+	return &poller{
+		in:       in,
+		interval: interval,
+		limit:    limit,
+		seen:     map[string]bool{},
+	}
+}
+
+// poll refreshes the inbox and returns the emails
+// not seen yet, the oldest one first
+func (p *poller) poll() ([]polledItem, error) {
+	if err := p.in.Refresh(p.limit); err != nil {
+		return nil, err
+	}
+	items := []polledItem{}
+	mails := p.in.GetMails()
+	for index := len(mails) - 1; index >= 0; index-- {
+		if p.seen[mails[index].ID] {
+			continue
+		}
+		p.seen[mails[index].ID] = true
+		items = append(items, polledItem{offset: index + 1, item: mails[index]})
+	}
+	return items, nil
+}
+
+// run polls the inbox after each interval until the context
+// is cancelled and calls onNew for every new email
+func (p *poller) run(ctx context.Context, onNew func(polledItem) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(p.interval):
+		}
+		items, err := p.poll()
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := onNew(item); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func addPollerFlags(cmd *cobra.Command, interval time.Duration) {
+	cmd.Flags().Duration("interval", interval, "Delay between two checks of the inbox")
+	cmd.Flags().Int("limit", defaultPollLimit, "Number of emails to check at each poll")
+}
+
+func newPollerFromFlags(cmd *cobra.Command, inboxBuilder inboxBuilder, inboxName string) (*poller, error) {
+	interval, err := cmd.Flags().GetDuration("interval")
+	if err != nil {
+		return nil, err
+	}
+	if interval < minPollInterval {
+		return nil, fmt.Errorf(`interval "%s" must be at least %s`, interval, minPollInterval)
+	}
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+	}
+	in, err := inboxBuilder(normalizeInboxName(inboxName))
+	if err != nil {
+		return nil, err
+	}
+	return newPoller(in, interval, limit), nil
+}
+
+func commandContext(cmd *cobra.Command) context.Context {
+	if cmd.Context() == nil {
+		return context.Background()
+	}
+	return cmd.Context()
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/antham/yogo/v4/internal/pop3"
+	"github.com/spf13/cobra"
+)
+
+var pop3Cmd = &cobra.Command{
+	Use:   "pop3",
+	Short: "Serve the inboxes to legacy mail clients through a POP3 server",
+	Long: `Serve the inboxes to legacy mail clients through a POP3 server.
+
+The user name selects the inbox and the password is ignored, so the server
+must only listen on a local address. The maildrop holds the last emails,
+the emails deleted during a session are deleted from yopmail on QUIT.`,
+	RunE: servePOP3(newServerInboxBuilder, listen),
+	Args: cobra.NoArgs,
+}
+
+func addPOP3Flags(cmd *cobra.Command) {
+	cmd.Flags().String("listen", "127.0.0.1:1110", "Address the server listens on")
+	cmd.Flags().Int("limit", defaultPollLimit, "Number of emails available in a maildrop")
+}
+
+func servePOP3(serverInboxBuilder serverInboxBuilder, listener listener) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		address, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return err
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+		if limit < 1 {
+			return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+		}
+
+		build, err := serverInboxBuilder()
+		if err != nil {
+			return err
+		}
+		l, err := listener(address)
+		if err != nil {
+			return err
+		}
+		s := pop3.New(build, pop3.Config{
+			Limit: limit,
+			Name:  normalizeInboxName,
+		})
+
+		ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Serve(l)
+		}()
+		cmd.Println(info(fmt.Sprintf("POP3 server listening on %s, log in with the inbox name and any password", l.Addr())))
+
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+		}
+		if err := s.Close(); err != nil {
+			return err
+		}
+		return <-errs
+	}
+}
+
+func init() {
+	addPOP3Flags(pop3Cmd)
+	RootCmd.AddCommand(pop3Cmd)
+}
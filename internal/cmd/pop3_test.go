@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServePOP3(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		builder     serverInboxBuilder
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:        "Invalid limit",
+			flags:       map[string]string{"limit": "0"},
+			errExpected: `limit "0" must be greater than 0`,
+		},
+		{
+			name: "Session can't be opened",
+			builder: func() (backend.Builder, error) {
+				return nil, errors.New("session error")
+			},
+			errExpected: "session error",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addPOP3Flags(cmd)
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			err := servePOP3(scenario.builder, listen)(cmd, []string{})
+			assert.EqualError(t, err, scenario.errExpected)
+		})
+	}
+}
+
+func TestServePOP3Session(t *testing.T) {
+	var output bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	cmd.SetOut(&output)
+	addPOP3Flags(cmd)
+	require.NoError(t, cmd.Flags().Set("listen", "127.0.0.1:0"))
+
+	addresses := make(chan string, 1)
+	done := make(chan error, 1)
+	names := make(chan string, 1)
+	in := &InboxMock{refreshItems: [][]inbox.InboxItem{{{ID: "a", Subject: "Welcome"}}}}
+	go func() {
+		done <- servePOP3(
+			func() (backend.Builder, error) {
+				return func(name string, source bool) (backend.Inbox, error) {
+					names <- name
+					return in, nil
+				}, nil
+			},
+			func(address string) (net.Listener, error) {
+				l, err := net.Listen("tcp", address)
+				if err == nil {
+					addresses <- l.Addr().String()
+				}
+				return l, err
+			},
+		)(cmd, []string{})
+	}()
+
+	address := <-addresses
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	readLine := func() string {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		return line
+	}
+	assert.Equal(t, "+OK Yogo POP3 server ready\r\n", readLine())
+	_, err = fmt.Fprint(conn, "USER TEST@yopmail.com\r\nPASS secret\r\nUIDL 1\r\nQUIT\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "+OK send the password, any password is accepted\r\n", readLine())
+	assert.Equal(t, "+OK maildrop has 1 messages\r\n", readLine())
+	assert.Equal(t, "test", <-names)
+	assert.Equal(t, "+OK 1 a\r\n", readLine())
+	assert.Equal(t, "+OK Yogo POP3 server signing off\r\n", readLine())
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, "POP3 server listening on "+address+", log in with the inbox name and any password\n", output.String())
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/antham/yogo/v4/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -12,14 +13,16 @@ var dumpJSON = false
 var enableDebugMode = false
 
 var RootCmd = &cobra.Command{
-	Use:   "yogo",
-	Short: "Interact with yopmail from command-line",
-	Long:  `Check yopmail mails from command line.`,
+	Use:               "yogo",
+	Short:             "Interact with yopmail from command-line",
+	Long:              `Check yopmail mails from command line.`,
+	PersistentPreRunE: applyConfig(config.Path),
 }
 
 func Execute() {
 	RootCmd.PersistentFlags().BoolVar(&dumpJSON, "json", false, "Dump the output as json")
 	RootCmd.PersistentFlags().BoolVar(&enableDebugMode, "debug", false, "Log all requests/responses")
+	RootCmd.PersistentFlags().String("profile", "", "Use the settings of this profile from the configuration file")
 	if err := RootCmd.Execute(); err != nil {
 		os.Exit(-1)
 	}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/antham/yogo/v4/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// serverInboxBuilder creates the builder of the inboxes
+// served, it is called once when the server starts
+type serverInboxBuilder func() (backend.Builder, error)
+
+type listener func(address string) (net.Listener, error)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the inboxes through an HTTP REST API",
+	RunE:  serve(newServerInboxBuilder, listen),
+	Args:  cobra.NoArgs,
+}
+
+func addServeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("listen", ":8080", "Address the server listens on")
+	cmd.Flags().Int("limit", defaultPollLimit, "Number of emails listed when the request doesn't define a limit")
+	cmd.Flags().Duration("poll-interval", 5*time.Second, "Delay between two checks of an inbox while waiting for an email")
+	cmd.Flags().Duration("max-wait", 2*time.Minute, "Maximum time a request waits for an email")
+}
+
+func listen(address string) (net.Listener, error) {
+	return net.Listen("tcp", address)
+}
+
+// newServerInboxBuilder opens the session shared by all the inboxes,
+// the cookies and the rate limiter are common to all the requests
+func newServerInboxBuilder() (backend.Builder, error) {
+	opts, err := clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, client.WithMinRequestInterval(pollRequestInterval))
+	session, err := client.NewSession(enableDebugMode, opts...)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, client.WithSession(session))
+	return func(name string, source bool) (backend.Inbox, error) {
+		var in Inbox
+		var err error
+		if source {
+			in, err = inbox.NewInbox[client.MailSourceDoc](name, enableDebugMode, opts...)
+		} else {
+			in, err = inbox.NewInbox[client.MailHTMLDoc](name, enableDebugMode, opts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return withArchive(in, name, openArchive)
+	}, nil
+}
+
+func serve(serverInboxBuilder serverInboxBuilder, listener listener) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		address, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return err
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+		if limit < 1 {
+			return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+		}
+		interval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return err
+		}
+		if interval < minPollInterval {
+			return fmt.Errorf(`poll interval "%s" must be at least %s`, interval, minPollInterval)
+		}
+		maxWait, err := cmd.Flags().GetDuration("max-wait")
+		if err != nil {
+			return err
+		}
+		if maxWait <= 0 {
+			return fmt.Errorf(`max wait "%s" must be positive`, maxWait)
+		}
+
+		build, err := serverInboxBuilder()
+		if err != nil {
+			return err
+		}
+		l, err := listener(address)
+		if err != nil {
+			return err
+		}
+		s := &http.Server{
+			Handler: server.New(build, server.Config{
+				Limit:        limit,
+				PollInterval: interval,
+				MaxWait:      maxWait,
+				Name:         normalizeInboxName,
+			}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Serve(l)
+		}()
+		cmd.Println(info(fmt.Sprintf("Listening on http://%s, the API is described at /openapi.json", l.Addr())))
+
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+		}
+		// Requests waiting for an email would hold the shutdown, they are dropped after a while
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return nil
+	}
+}
+
+func init() {
+	addServeFlags(serveCmd)
+	RootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServe(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		builder     serverInboxBuilder
+		listener    listener
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:        "Invalid limit",
+			flags:       map[string]string{"limit": "0"},
+			errExpected: `limit "0" must be greater than 0`,
+		},
+		{
+			name:        "Poll interval too short",
+			flags:       map[string]string{"poll-interval": "10ms"},
+			errExpected: `poll interval "10ms" must be at least 1s`,
+		},
+		{
+			name:        "Invalid max wait",
+			flags:       map[string]string{"max-wait": "0s"},
+			errExpected: `max wait "0s" must be positive`,
+		},
+		{
+			name: "Session can't be opened",
+			builder: func() (backend.Builder, error) {
+				return nil, errors.New("session error")
+			},
+			errExpected: "session error",
+		},
+		{
+			name: "Address already in use",
+			builder: func() (backend.Builder, error) {
+				return nil, nil
+			},
+			listener: func(address string) (net.Listener, error) {
+				return nil, errors.New("address already in use")
+			},
+			errExpected: "address already in use",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addServeFlags(cmd)
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			err := serve(scenario.builder, scenario.listener)(cmd, []string{})
+			assert.EqualError(t, err, scenario.errExpected)
+		})
+	}
+}
+
+func TestServeRequests(t *testing.T) {
+	var output bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	cmd.SetOut(&output)
+	addServeFlags(cmd)
+	require.NoError(t, cmd.Flags().Set("listen", "127.0.0.1:0"))
+
+	built := []string{}
+	addresses := make(chan string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(
+			func() (backend.Builder, error) {
+				return func(name string, source bool) (backend.Inbox, error) {
+					built = append(built, name)
+					return &InboxMock{refreshItems: [][]inbox.InboxItem{{{ID: "a", Subject: "Welcome"}}}}, nil
+				}, nil
+			},
+			func(address string) (net.Listener, error) {
+				l, err := net.Listen("tcp", address)
+				if err == nil {
+					addresses <- l.Addr().String()
+				}
+				return l, err
+			},
+		)(cmd, []string{})
+	}()
+
+	address := <-addresses
+	resp, err := http.Get("http://" + address + "/inboxes/TEST@yopmail.com/mails")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"name":"test","mails":[{"id":"a","subject":"Welcome","isSPAM":false}]}`, string(body))
+	assert.Equal(t, []string{"test"}, built)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, "Listening on http://"+address+", the API is described at /openapi.json\n", output.String())
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var streamCmd = &cobra.Command{
+	Use:   "stream",
+	Short: "Stream the new emails of the inboxes as server-sent events",
+	RunE:  stream(newServerInboxBuilder, listen),
+	Args:  cobra.NoArgs,
+}
+
+func addStreamFlags(cmd *cobra.Command) {
+	cmd.Flags().String("listen", ":8080", "Address the server listens on")
+	cmd.Flags().Int("limit", defaultPollLimit, "Number of emails listed at each check of an inbox")
+	cmd.Flags().Duration("poll-interval", 10*time.Second, "Delay between two checks of an inbox")
+}
+
+func stream(serverInboxBuilder serverInboxBuilder, listener listener) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		address, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return err
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+		if limit < 1 {
+			return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+		}
+		interval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return err
+		}
+		if interval < minPollInterval {
+			return fmt.Errorf(`poll interval "%s" must be at least %s`, interval, minPollInterval)
+		}
+
+		build, err := serverInboxBuilder()
+		if err != nil {
+			return err
+		}
+		l, err := listener(address)
+		if err != nil {
+			return err
+		}
+		s := &http.Server{
+			Handler: server.NewStream(build, server.StreamConfig{
+				Limit:        limit,
+				PollInterval: interval,
+				Name:         normalizeInboxName,
+			}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Serve(l)
+		}()
+		cmd.Println(info(fmt.Sprintf("Streaming on http://%s/inboxes/{name}/events", l.Addr())))
+
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+		}
+		// The streams never end by themselves, they are dropped after a while
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := s.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return nil
+	}
+}
+
+func init() {
+	addStreamFlags(streamCmd)
+	RootCmd.AddCommand(streamCmd)
+}
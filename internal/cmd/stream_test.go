@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream(t *testing.T) {
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		builder     serverInboxBuilder
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:        "Invalid limit",
+			flags:       map[string]string{"limit": "0"},
+			errExpected: `limit "0" must be greater than 0`,
+		},
+		{
+			name:        "Poll interval too short",
+			flags:       map[string]string{"poll-interval": "10ms"},
+			errExpected: `poll interval "10ms" must be at least 1s`,
+		},
+		{
+			name: "Session can't be opened",
+			builder: func() (backend.Builder, error) {
+				return nil, errors.New("session error")
+			},
+			errExpected: "session error",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addStreamFlags(cmd)
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			err := stream(scenario.builder, listen)(cmd, []string{})
+			assert.EqualError(t, err, scenario.errExpected)
+		})
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	var output bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	cmd.SetOut(&output)
+	addStreamFlags(cmd)
+	require.NoError(t, cmd.Flags().Set("listen", "127.0.0.1:0"))
+	require.NoError(t, cmd.Flags().Set("poll-interval", "1s"))
+
+	addresses := make(chan string, 1)
+	done := make(chan error, 1)
+	in := &InboxMock{refreshItems: [][]inbox.InboxItem{{}, {{ID: "a", Subject: "Welcome"}}}}
+	go func() {
+		done <- stream(
+			func() (backend.Builder, error) {
+				return func(name string, source bool) (backend.Inbox, error) {
+					return in, nil
+				}, nil
+			},
+			func(address string) (net.Listener, error) {
+				l, err := net.Listen("tcp", address)
+				if err == nil {
+					addresses <- l.Addr().String()
+				}
+				return l, err
+			},
+		)(cmd, []string{})
+	}()
+
+	address := <-addresses
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+"/inboxes/TEST@yopmail.com/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	lines := []string{}
+	for len(lines) < 3 {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line = strings.TrimSuffix(line, "\n"); line != "" && !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, []string{"id: a", "event: mail", `data: {"id":"a","subject":"Welcome","isSPAM":false}`}, lines)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, "Streaming on http://"+address+"/inboxes/{name}/events\n", output.String())
+}
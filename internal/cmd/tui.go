@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/config"
+	"github.com/antham/yogo/v4/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+type terminalOpener func() (tui.Terminal, func() error, error)
+
+var tuiCmd = &cobra.Command{
+	Use:               "tui <inbox>",
+	Short:             "Browse an inbox in an interactive terminal interface",
+	RunE:              runTUI(newInbox[client.MailHTMLDoc], newInbox[client.MailSourceDoc], openTerminal),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInbox(config.Path),
+}
+
+func addTUIFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("limit", defaultPollLimit, "Number of emails to list")
+}
+
+func openTerminal() (tui.Terminal, func() error, error) {
+	t, err := tui.OpenTerminal(os.Stdin, os.Stdout)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, t.Close, nil
+}
+
+// runTUI browses an inbox, the sources are fetched through
+// a dedicated inbox as they are served by another page
+func runTUI(inboxBuilder inboxBuilder, sourceInboxBuilder inboxBuilder, terminalOpener terminalOpener) cobraCmd {
+	return func(cmd *cobra.Command, args []string) error {
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+		if limit < 1 {
+			return fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+		}
+		identifier := normalizeInboxName(args[0])
+		in, err := inboxBuilder(identifier)
+		if err != nil {
+			return err
+		}
+		sources, err := sourceInboxBuilder(identifier)
+		if err != nil {
+			return err
+		}
+		terminal, closeTerminal, err := terminalOpener()
+		if err != nil {
+			return err
+		}
+		err = tui.New(identifier, in, sources, limit, terminal).Run()
+		if closeErr := closeTerminal(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+func init() {
+	addTUIFlags(tuiCmd)
+	RootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/antham/yogo/v4/internal/tui"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTUI(t *testing.T) {
+	type scenario struct {
+		name               string
+		flags              map[string]string
+		inboxBuilder       inboxBuilder
+		sourceInboxBuilder inboxBuilder
+		openErr            error
+		closeErr           error
+		errExpected        error
+		screen             string
+		closed             bool
+	}
+
+	newInboxMock := func(name string) (Inbox, error) {
+		return &InboxMock{items: []inbox.InboxItem{{ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme"}}}}, nil
+	}
+
+	scenarios := []scenario{
+		{
+			name:               "Inbox browsed",
+			inboxBuilder:       newInboxMock,
+			sourceInboxBuilder: newInboxMock,
+			screen:             " test - 1 email(s)\n> Acme : Welcome",
+			closed:             true,
+		},
+		{
+			name:        "Invalid limit",
+			flags:       map[string]string{"limit": "0"},
+			errExpected: errors.New(`limit "0" must be greater than 0`),
+		},
+		{
+			name: "An error is thrown in inbox builder",
+			inboxBuilder: func(name string) (Inbox, error) {
+				return nil, errors.New("inbox builder error")
+			},
+			errExpected: errors.New("inbox builder error"),
+		},
+		{
+			name:         "An error is thrown in source inbox builder",
+			inboxBuilder: newInboxMock,
+			sourceInboxBuilder: func(name string) (Inbox, error) {
+				return nil, errors.New("source inbox builder error")
+			},
+			errExpected: errors.New("source inbox builder error"),
+		},
+		{
+			name:               "Not a terminal",
+			inboxBuilder:       newInboxMock,
+			sourceInboxBuilder: newInboxMock,
+			openErr:            errors.New("an interactive terminal is required"),
+			errExpected:        errors.New("an interactive terminal is required"),
+		},
+		{
+			name: "The terminal is restored on failure",
+			inboxBuilder: func(name string) (Inbox, error) {
+				return &InboxMock{refreshError: errors.New("refresh error")}, nil
+			},
+			sourceInboxBuilder: newInboxMock,
+			errExpected:        errors.New("refresh error"),
+			closed:             true,
+		},
+		{
+			name:               "Failure when restoring the terminal",
+			inboxBuilder:       newInboxMock,
+			sourceInboxBuilder: newInboxMock,
+			closeErr:           errors.New("restore error"),
+			errExpected:        errors.New("restore error"),
+			closed:             true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addTUIFlags(cmd)
+			for k, v := range scenario.flags {
+				assert.NoError(t, cmd.Flags().Set(k, v))
+			}
+			terminal := tui.NewVirtualTerminal(60, 12)
+			closed := false
+			opener := func() (tui.Terminal, func() error, error) {
+				if scenario.openErr != nil {
+					return nil, nil, scenario.openErr
+				}
+				return terminal, func() error {
+					closed = true
+					return scenario.closeErr
+				}, nil
+			}
+			err := runTUI(scenario.inboxBuilder, scenario.sourceInboxBuilder, opener)(cmd, []string{"Test@yopmail.com"})
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.closed, closed)
+			if scenario.screen != "" {
+				assert.Contains(t, terminal.Screen(), scenario.screen)
+			}
+		})
+	}
+}
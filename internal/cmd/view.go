@@ -1,9 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/antham/yogo/v4/internal/inbox"
 	"github.com/fatih/color"
+	"github.com/spf13/cobra"
 )
 
+const noDataToDisplayMsg = "[no data to display]"
+
 // info outputs a blue info message
 func info(message string) string {
 	return color.CyanString(message)
@@ -13,3 +21,34 @@ func info(message string) string {
 func success(message string) string {
 	return color.GreenString(message)
 }
+
+// formatInboxItem outputs a one line summary of an email
+func formatInboxItem(offset int, item inbox.InboxItem) string {
+	sender := item.Sender.String()
+	if sender == "" {
+		sender = noDataToDisplayMsg
+	}
+	subject := item.Subject
+	if subject == "" {
+		subject = noDataToDisplayMsg
+	}
+	s := fmt.Sprintf(" %d %s", offset, color.YellowString(sender))
+	if item.IsSPAM {
+		s += " " + color.RedString("[SPAM]")
+	}
+	return s + " : " + color.CyanString(subject)
+}
+
+// confirm asks a yes/no question, anything else than yes is a no
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	cmd.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
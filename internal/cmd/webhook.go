@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// defaultWebhookMaxTime bounds a delivery, retries included,
+// so a receiver being down doesn't hold the watch for long
+const defaultWebhookMaxTime = 30 * time.Second
+
+// webhookSignatureHeader carries the HMAC-SHA256 of the request body
+// computed with the secret shared with the receiver
+const webhookSignatureHeader = "X-Yogo-Signature"
+
+// webhookPayload is the data posted for every new email, it is
+// also the value the payload template is executed against
+type webhookPayload struct {
+	Inbox string          `json:"inbox"`
+	Item  inbox.InboxItem `json:"item"`
+	Mail  inbox.Mail      `json:"mail,omitempty"`
+}
+
+// MarshalJSON renders the email as the --json flag and the exec hooks do
+func (p webhookPayload) MarshalJSON() ([]byte, error) {
+	doc := struct {
+		Inbox string          `json:"inbox"`
+		Item  inbox.InboxItem `json:"item"`
+		Mail  json.RawMessage `json:"mail,omitempty"`
+	}{Inbox: p.Inbox, Item: p.Item}
+	if p.Mail != nil {
+		data, err := mailJSON(p.Mail)
+		if err != nil {
+			return nil, err
+		}
+		doc.Mail = data
+	}
+	return json.Marshal(doc)
+}
+
+// webhook posts the new emails to an HTTP endpoint
+type webhook struct {
+	url      string
+	headers  http.Header
+	secret   string
+	retries  int
+	backoff  time.Duration
+	maxTime  time.Duration
+	template *template.Template
+	withBody bool
+	client   *http.Client
+	sleep    func(context.Context, time.Duration) error
+}
+
+func addWebhookFlags(cmd *cobra.Command) {
+	cmd.Flags().String("webhook", "", "Post every new email as JSON to this URL")
+	cmd.Flags().Bool("with-body", false, "Include the full content of the email in the webhook payload")
+	cmd.Flags().StringArray("webhook-header", []string{}, `Add a header to the webhook requests, formatted as "Name: value" (repeatable)`)
+	cmd.Flags().String("webhook-secret", "", "Sign the webhook requests with HMAC-SHA256 using this secret (env YOGO_WEBHOOK_SECRET)")
+	cmd.Flags().Int("webhook-retries", 3, "Number of retries when a webhook request fails")
+	cmd.Flags().Duration("webhook-backoff", time.Second, "Delay before the first retry, doubled after each attempt")
+	cmd.Flags().Duration("webhook-max-time", defaultWebhookMaxTime, "Maximum duration of a delivery, retries included")
+	cmd.Flags().String("webhook-template", "", "Go template producing the webhook payload instead of the default JSON")
+	cmd.Flags().String("webhook-template-file", "", "File containing the Go template producing the webhook payload")
+}
+
+// newWebhookFromFlags returns the webhook configured on the command,
+// or nil when no webhook URL is provided
+func newWebhookFromFlags(cmd *cobra.Command) (*webhook, error) {
+	flags := cmd.Flags()
+	URL, err := flags.GetString("webhook")
+	if err != nil {
+		return nil, err
+	}
+	if URL == "" {
+		for _, name := range []string{"with-body", "webhook-header", "webhook-secret", "webhook-retries", "webhook-backoff", "webhook-max-time", "webhook-template", "webhook-template-file"} {
+			if flags.Changed(name) {
+				return nil, fmt.Errorf(`flag "--%s" requires "--webhook"`, name)
+			}
+		}
+		return nil, nil
+	}
+	u, err := url.Parse(URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf(`webhook "%s" must be an http or https URL`, URL)
+	}
+
+	w := &webhook{
+		url:     URL,
+		headers: http.Header{"Content-Type": []string{"application/json"}},
+		client:  &http.Client{Timeout: webhookTimeout},
+		sleep:   sleepContext,
+	}
+	if w.withBody, err = flags.GetBool("with-body"); err != nil {
+		return nil, err
+	}
+	headers, err := flags.GetStringArray("webhook-header")
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf(`header "%s" must be formatted as "Name: value"`, header)
+		}
+		w.headers.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if w.secret, err = flags.GetString("webhook-secret"); err != nil {
+		return nil, err
+	}
+	if w.secret == "" {
+		w.secret = os.Getenv("YOGO_WEBHOOK_SECRET")
+	}
+	if w.retries, err = flags.GetInt("webhook-retries"); err != nil {
+		return nil, err
+	}
+	if w.retries < 0 {
+		return nil, fmt.Errorf(`"--webhook-retries" must be a positive number, got %d`, w.retries)
+	}
+	if w.backoff, err = flags.GetDuration("webhook-backoff"); err != nil {
+		return nil, err
+	}
+	if w.maxTime, err = flags.GetDuration("webhook-max-time"); err != nil {
+		return nil, err
+	}
+	if w.maxTime <= 0 {
+		return nil, fmt.Errorf(`"--webhook-max-time" must be positive, got %s`, w.maxTime)
+	}
+	if w.template, err = parseWebhookTemplate(cmd); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func parseWebhookTemplate(cmd *cobra.Command) (*template.Template, error) {
+	text, err := cmd.Flags().GetString("webhook-template")
+	if err != nil {
+		return nil, err
+	}
+	file, err := cmd.Flags().GetString("webhook-template-file")
+	if err != nil {
+		return nil, err
+	}
+	if text != "" && file != "" {
+		return nil, fmt.Errorf(`"--webhook-template" and "--webhook-template-file" can't be used together`)
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("can't read webhook template : %w", err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("webhook").Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("webhook template is invalid : %w", err)
+	}
+	return tmpl, nil
+}
+
+// notify posts a new email to the webhook, the email is fetched first
+// when its content must be part of the payload and was not already
+// fetched, mail is nil in that case
+func (w *webhook) notify(ctx context.Context, in Inbox, name string, p polledItem, mail inbox.Mail) error {
+	payload := webhookPayload{Inbox: name, Item: p.item}
+	if w.withBody {
+		if mail == nil {
+			var err error
+			if mail, err = in.FetchByID(p.item.ID); err != nil {
+				return err
+			}
+		}
+		payload.Mail = mail
+	}
+	body, err := w.payload(payload)
+	if err != nil {
+		return err
+	}
+	return w.send(ctx, body)
+}
+
+func (w *webhook) payload(payload webhookPayload) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(payload)
+	}
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("can't render webhook template : %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// send posts the body, retrying with an exponential backoff on network
+// errors, rate limiting and server errors until the delivery times out
+func (w *webhook) send(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, w.maxTime)
+	defer cancel()
+	delay := w.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("delivery timed out after %s : %w", w.maxTime, err)
+		}
+		if !retry || attempt >= w.retries {
+			return err
+		}
+		if err := w.sleep(ctx, delay); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("delivery timed out after %s", w.maxTime)
+			}
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// post sends a single request and reports whether it is worth retrying
+func (w *webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header = w.headers.Clone()
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
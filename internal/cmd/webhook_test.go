@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookRequest struct {
+	header http.Header
+	body   string
+}
+
+// webhookReceiver records the requests it gets and answers
+// with the given statuses, the last one being repeated
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []webhookRequest
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, webhookRequest{header: req.Header, body: string(body)})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status = r.statuses[0]
+		if len(r.statuses) > 1 {
+			r.statuses = r.statuses[1:]
+		}
+	}
+	w.WriteHeader(status)
+}
+
+func TestNewWebhookFromFlags(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "payload.tmpl")
+	require.NoError(t, os.WriteFile(templateFile, []byte(`{{ .Item.ID }}`), 0o600))
+
+	type scenario struct {
+		name        string
+		flags       map[string]string
+		nilExpected bool
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:        "No webhook",
+			nilExpected: true,
+		},
+		{
+			name:        "Webhook option without webhook",
+			flags:       map[string]string{"with-body": "true"},
+			errExpected: `flag "--with-body" requires "--webhook"`,
+		},
+		{
+			name:        "Not an http URL",
+			flags:       map[string]string{"webhook": "ftp://example.com"},
+			errExpected: `webhook "ftp://example.com" must be an http or https URL`,
+		},
+		{
+			name:        "Malformed header",
+			flags:       map[string]string{"webhook": "https://example.com", "webhook-header": "Authorization"},
+			errExpected: `header "Authorization" must be formatted as "Name: value"`,
+		},
+		{
+			name:        "Negative retries",
+			flags:       map[string]string{"webhook": "https://example.com", "webhook-retries": "-1"},
+			errExpected: `"--webhook-retries" must be a positive number, got -1`,
+		},
+		{
+			name:        "Invalid max time",
+			flags:       map[string]string{"webhook": "https://example.com", "webhook-max-time": "0s"},
+			errExpected: `"--webhook-max-time" must be positive, got 0s`,
+		},
+		{
+			name:        "Invalid template",
+			flags:       map[string]string{"webhook": "https://example.com", "webhook-template": "{{ .Item"},
+			errExpected: `webhook template is invalid : template: webhook:1: unclosed action`,
+		},
+		{
+			name:        "Both template flags",
+			flags:       map[string]string{"webhook": "https://example.com", "webhook-template": "{{ .Item.ID }}", "webhook-template-file": templateFile},
+			errExpected: `"--webhook-template" and "--webhook-template-file" can't be used together`,
+		},
+		{
+			name:  "Template file",
+			flags: map[string]string{"webhook": "https://example.com", "webhook-template-file": templateFile},
+		},
+		{
+			name:  "Valid webhook",
+			flags: map[string]string{"webhook": "https://example.com", "webhook-header": "Authorization: Bearer token"},
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := &cobra.Command{}
+			addWebhookFlags(cmd)
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			w, err := newWebhookFromFlags(cmd)
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.nilExpected, w == nil)
+		})
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	type scenario struct {
+		name        string
+		statuses    []int
+		retries     int
+		requests    int
+		delays      []time.Duration
+		errExpected string
+	}
+	scenarios := []scenario{
+		{
+			name:     "Delivered at first attempt",
+			retries:  3,
+			requests: 1,
+			delays:   []time.Duration{},
+		},
+		{
+			name:     "Delivered after server errors",
+			statuses: []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusNoContent},
+			retries:  3,
+			requests: 3,
+			delays:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:        "Client errors are not retried",
+			statuses:    []int{http.StatusBadRequest},
+			retries:     3,
+			requests:    1,
+			delays:      []time.Duration{},
+			errExpected: "webhook answered with status 400",
+		},
+		{
+			name:        "Retries exhausted",
+			statuses:    []int{http.StatusBadGateway},
+			retries:     2,
+			requests:    3,
+			delays:      []time.Duration{time.Second, 2 * time.Second},
+			errExpected: "webhook answered with status 502",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			receiver := &webhookReceiver{statuses: scenario.statuses}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			delays := []time.Duration{}
+			w := &webhook{
+				url:     server.URL,
+				headers: http.Header{"Content-Type": []string{"application/json"}, "Authorization": []string{"Bearer token"}},
+				secret:  "secret",
+				retries: scenario.retries,
+				backoff: time.Second,
+				maxTime: time.Minute,
+				client:  server.Client(),
+				sleep: func(ctx context.Context, d time.Duration) error {
+					delays = append(delays, d)
+					return nil
+				},
+			}
+			err := w.send(context.Background(), []byte(`{"inbox":"test"}`))
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.delays, delays)
+			require.Len(t, receiver.requests, scenario.requests)
+
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write([]byte(`{"inbox":"test"}`))
+			request := receiver.requests[0]
+			assert.Equal(t, `{"inbox":"test"}`, request.body)
+			assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+			assert.Equal(t, "Bearer token", request.header.Get("Authorization"))
+			assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), request.header.Get(webhookSignatureHeader))
+		})
+	}
+}
+
+func TestWebhookSendCancelled(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &webhook{
+		url:     server.URL,
+		headers: http.Header{},
+		retries: 5,
+		backoff: time.Hour,
+		maxTime: time.Hour,
+		client:  server.Client(),
+		sleep: func(ctx context.Context, d time.Duration) error {
+			cancel()
+			return sleepContext(ctx, d)
+		},
+	}
+	assert.ErrorIs(t, w.send(ctx, []byte("{}")), context.Canceled)
+	assert.Len(t, receiver.requests, 1)
+}
+
+func TestWebhookSendTimeout(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	w := &webhook{
+		url:     server.URL,
+		headers: http.Header{},
+		retries: 5,
+		backoff: time.Hour,
+		maxTime: 50 * time.Millisecond,
+		client:  server.Client(),
+		sleep:   sleepContext,
+	}
+	assert.EqualError(t, w.send(context.Background(), []byte("{}")), "delivery timed out after 50ms")
+	assert.Len(t, receiver.requests, 1)
+}
+
+func TestInboxWatchWebhook(t *testing.T) {
+	type scenario struct {
+		name      string
+		flags     map[string]string
+		fetchMail inbox.Mail
+		fetches   int
+		statuses  []int
+		body      string
+		outputErr string
+	}
+	scenarios := []scenario{
+		{
+			name: "Default payload",
+			body: `{"inbox":"test","item":{"id":"a","sender":{"mail":"hello@acme.com","name":"Acme"},"subject":"Welcome","isSPAM":false}}`,
+		},
+		{
+			name:      "Payload with body",
+			flags:     map[string]string{"with-body": "true"},
+			fetchMail: MailMock{json: `{"id":"a", "body":"Your code is 1234"}`},
+			fetches:   1,
+			body:      `{"inbox":"test","item":{"id":"a","sender":{"mail":"hello@acme.com","name":"Acme"},"subject":"Welcome","isSPAM":false},"mail":{"id":"a","body":"Your code is 1234"}}`,
+		},
+		{
+			name:      "Payload reusing the displayed email",
+			flags:     map[string]string{"with-body": "true", "show": "true"},
+			fetchMail: MailMock{coloured: "Your code is 1234", json: `{"id":"a","body":"Your code is 1234"}`},
+			fetches:   1,
+			body:      `{"inbox":"test","item":{"id":"a","sender":{"mail":"hello@acme.com","name":"Acme"},"subject":"Welcome","isSPAM":false},"mail":{"id":"a","body":"Your code is 1234"}}`,
+		},
+		{
+			name:      "Templated payload",
+			flags:     map[string]string{"with-body": "true", "webhook-template": `{"text":{{ json (printf "%s: %s" .Item.Subject .Mail.Content) }}}`},
+			fetchMail: MailMock{content: "Your code is 1234"},
+			fetches:   1,
+			body:      `{"text":"Welcome: Your code is 1234"}`,
+		},
+		{
+			name:      "Delivery failure doesn't stop the watch",
+			flags:     map[string]string{"webhook-retries": "0"},
+			statuses:  []int{http.StatusInternalServerError},
+			body:      `{"inbox":"test","item":{"id":"a","sender":{"mail":"hello@acme.com","name":"Acme"},"subject":"Welcome","isSPAM":false}}`,
+			outputErr: "Webhook delivery failed for email \"a\" : webhook answered with status 500\n",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			receiver := &webhookReceiver{statuses: scenario.statuses}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			var output, outputErr bytes.Buffer
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cmd := &cobra.Command{}
+			cmd.SetContext(ctx)
+			addInboxWatchFlags(cmd)
+			require.NoError(t, cmd.Flags().Set("interval", "1s"))
+			require.NoError(t, cmd.Flags().Set("webhook", server.URL))
+			for name, value := range scenario.flags {
+				require.NoError(t, cmd.Flags().Set(name, value))
+			}
+			cmd.SetOut(&output)
+			cmd.SetErr(&outputErr)
+			in := &InboxMock{
+				refreshItems: [][]inbox.InboxItem{{}, {{ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme", Mail: "hello@acme.com"}}}},
+				fetchMail:    scenario.fetchMail,
+				onRefresh: func(count int) {
+					if count == 3 {
+						cancel()
+					}
+				},
+			}
+			err := inboxWatch(func(name string) (Inbox, error) {
+				return in, nil
+			})(cmd, []string{"TEST@yopmail.com"})
+			assert.NoError(t, err)
+			assert.Len(t, in.fetchedIDs, scenario.fetches)
+			require.Len(t, receiver.requests, 1)
+			assert.Equal(t, scenario.body, receiver.requests[0].body)
+			assert.Equal(t, scenario.outputErr, outputErr.String())
+		})
+	}
+}
+
+func TestInboxWatchWebhookFetchError(t *testing.T) {
+	var outputErr bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	addInboxWatchFlags(cmd)
+	require.NoError(t, cmd.Flags().Set("interval", "1s"))
+	require.NoError(t, cmd.Flags().Set("webhook", "http://127.0.0.1:1"))
+	require.NoError(t, cmd.Flags().Set("with-body", "true"))
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(&outputErr)
+	err := inboxWatch(func(name string) (Inbox, error) {
+		return &InboxMock{
+			refreshItems: [][]inbox.InboxItem{{}, {{ID: "a", Subject: "Welcome"}}},
+			fetchError:   errors.New("fetch error"),
+			onRefresh: func(count int) {
+				if count == 3 {
+					cancel()
+				}
+			},
+		}, nil
+	})(cmd, []string{"test"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Webhook delivery failed for email \"a\" : fetch error\n", outputErr.String())
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/antham/yogo/v4/internal/render"
+	"gopkg.in/yaml.v3"
+)
+
+// Key is a setting of the configuration file, a setting provides
+// the default value of the environment variables it is bound to
+type Key struct {
+	Name        string
+	Envs        []string
+	Description string
+	validate    func(string) error
+}
+
+// Keys are the supported settings
+var Keys = []Key{
+	{Name: "timeout", Envs: []string{"YOGO_REQUEST_TIMEOUT"}, Description: "Duration of a request before reaching the timeout in seconds", validate: validatePositiveInt},
+	{Name: "user-agent", Envs: []string{"YOGO_USER_AGENT"}, Description: "The user agent used to perform the requests"},
+	{Name: "proxy", Envs: []string{"HTTP_PROXY", "HTTPS_PROXY"}, Description: "The proxy used for the requests", validate: validateURL},
+	{Name: "output", Envs: []string{"YOGO_OUTPUT"}, Description: "The default output format", validate: validateOutput},
+	{Name: "request-interval", Envs: []string{"YOGO_REQUEST_INTERVAL"}, Description: "Minimal delay between two requests in milliseconds", validate: validatePositiveInt},
+	{Name: "archive", Envs: []string{"YOGO_ARCHIVE"}, Description: "Record the listed and fetched emails in the local archive", validate: validateBool},
+	{Name: "archive-dir", Envs: []string{"YOGO_ARCHIVE_DIR"}, Description: "Directory of the local archive"},
+	{Name: "archive-retention", Envs: []string{"YOGO_ARCHIVE_RETENTION"}, Description: "Number of days the archived emails are kept, 0 keeps them forever", validate: validatePositiveInt},
+	{Name: "cache", Envs: []string{"YOGO_CACHE"}, Description: "Keep the fetched emails in the cache", validate: validateBool},
+	{Name: "cache-dir", Envs: []string{"YOGO_CACHE_DIR"}, Description: "Directory of the cache"},
+	{Name: "cache-max-age", Envs: []string{"YOGO_CACHE_MAX_AGE"}, Description: "Number of days a cached email is kept, 0 disables the limit", validate: validatePositiveInt},
+	{Name: "cache-max-size", Envs: []string{"YOGO_CACHE_MAX_SIZE"}, Description: "Maximum size of the cache in megabytes, 0 disables the limit", validate: validatePositiveInt},
+}
+
+// Settings maps a key name to its value
+type Settings map[string]string
+
+// Config is the content of the configuration file, the
+// profiles override the settings defined at the top level
+type Config struct {
+	Defaults Settings            `yaml:",inline"`
+	Aliases  map[string]string   `yaml:"aliases,omitempty"`
+	Profiles map[string]Settings `yaml:"profiles,omitempty"`
+}
+
+// Path returns the configuration file path, the
+// XDG_CONFIG_HOME directory is used when defined
+func Path() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "yogo", "config.yaml"), nil
+}
+
+// Load reads a configuration file, a missing file is an empty configuration
+func Load(path string) (Config, error) {
+	config := Config{Defaults: Settings{}, Aliases: map[string]string{}, Profiles: map[string]Settings{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf(`configuration file "%s" is invalid : %w`, path, err)
+	}
+	if config.Defaults == nil {
+		config.Defaults = Settings{}
+	}
+	if config.Aliases == nil {
+		config.Aliases = map[string]string{}
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]Settings{}
+	}
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf(`configuration file "%s" is invalid : %w`, path, err)
+	}
+	return config, nil
+}
+
+// Save writes the configuration file, the missing directories are created
+func (c Config) Save(path string) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// MarshalYAML writes the settings in the order of the keys
+// definition, followed by the aliases and the profiles
+func (c Config) MarshalYAML() (any, error) {
+	node := settingsNode(c.Defaults)
+	if len(c.Aliases) > 0 {
+		aliases := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range c.AliasNames() {
+			aliases.Content = append(aliases.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, &yaml.Node{Kind: yaml.ScalarNode, Value: c.Aliases[name]})
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "aliases"}, aliases)
+	}
+	if len(c.Profiles) > 0 {
+		profiles := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range c.ProfileNames() {
+			profiles.Content = append(profiles.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, settingsNode(c.Profiles[name]))
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "profiles"}, profiles)
+	}
+	return node, nil
+}
+
+func settingsNode(settings Settings) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range Keys {
+		if value, ok := settings[key.Name]; ok {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key.Name}, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+		}
+	}
+	return node
+}
+
+// Resolve returns the settings of a profile merged with the
+// top level settings, an empty profile returns the latter
+func (c Config) Resolve(profile string) (Settings, error) {
+	settings := Settings{}
+	for k, v := range c.Defaults {
+		settings[k] = v
+	}
+	if profile == "" {
+		return settings, nil
+	}
+	p, ok := c.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf(`profile "%s" is not defined`, profile)
+	}
+	for k, v := range p {
+		settings[k] = v
+	}
+	return settings, nil
+}
+
+// Set defines a setting of a profile, or a top level setting when
+// profile is empty, an empty value removes the setting
+func (c *Config) Set(profile string, name string, value string) error {
+	key, err := GetKey(name)
+	if err != nil {
+		return err
+	}
+	if value != "" && key.validate != nil {
+		if err := key.validate(value); err != nil {
+			return fmt.Errorf(`value "%s" of key "%s" is invalid : %w`, value, name, err)
+		}
+	}
+	settings := c.Defaults
+	if profile != "" {
+		if _, ok := c.Profiles[profile]; !ok {
+			c.Profiles[profile] = Settings{}
+		}
+		settings = c.Profiles[profile]
+	}
+	if value == "" {
+		delete(settings, name)
+		if profile != "" && len(settings) == 0 {
+			delete(c.Profiles, profile)
+		}
+		return nil
+	}
+	settings[name] = value
+	return nil
+}
+
+// ProfileNames returns the defined profiles
+func (c Config) ProfileNames() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AliasNames returns the defined inbox aliases
+func (c Config) AliasNames() []string {
+	names := []string{}
+	for name := range c.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c Config) validate() error {
+	for name, inbox := range c.Aliases {
+		if strings.TrimSpace(inbox) == "" {
+			return fmt.Errorf(`alias "%s" must target an inbox`, name)
+		}
+	}
+	all := map[string]Settings{"": c.Defaults}
+	for name, settings := range c.Profiles {
+		all[name] = settings
+	}
+	for profile, settings := range all {
+		for name, value := range settings {
+			key, err := GetKey(name)
+			if err == nil && key.validate != nil {
+				err = key.validate(value)
+			}
+			if err != nil && profile != "" {
+				return fmt.Errorf(`profile "%s" : %w`, profile, err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetKey returns a supported setting
+func GetKey(name string) (Key, error) {
+	names := []string{}
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, nil
+		}
+		names = append(names, key.Name)
+	}
+	return Key{}, fmt.Errorf(`key "%s" is not supported, use one of %s`, name, strings.Join(names, ", "))
+}
+
+// Env returns the value of the first environment variable bound to a key
+func Env(key Key) (string, bool) {
+	for _, env := range key.Envs {
+		if v := lookupEnv(env); v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Apply sets the environment variables of the settings,
+// the variables already defined are left untouched
+func Apply(settings Settings) error {
+	for _, key := range Keys {
+		value, ok := settings[key.Name]
+		if !ok {
+			continue
+		}
+		if _, ok := Env(key); ok {
+			continue
+		}
+		for _, env := range key.Envs {
+			if err := os.Setenv(env, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lookupEnv reads a variable, the proxy variables are
+// commonly defined lowercased so both forms are checked
+func lookupEnv(env string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	if strings.HasSuffix(env, "_PROXY") {
+		return os.Getenv(strings.ToLower(env))
+	}
+	return ""
+}
+
+func validatePositiveInt(value string) error {
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return errors.New("a positive integer is expected")
+	}
+	return nil
+}
+
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return errors.New("a boolean is expected (true or false)")
+	}
+	return nil
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("an URL is expected (e.g. http://127.0.0.1:3128)")
+	}
+	return nil
+}
+
+func validateOutput(value string) error {
+	_, err := render.New(value)
+	return err
+}
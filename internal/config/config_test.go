@@ -0,0 +1,235 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	path, err := Path()
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/xdg/yogo/config.yaml", path)
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/tmp/home")
+	path, err = Path()
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/home/.config/yogo/config.yaml", path)
+}
+
+func TestLoad(t *testing.T) {
+	type scenario struct {
+		name        string
+		content     string
+		expected    Config
+		errExpected error
+	}
+
+	scenarios := []scenario{
+		{
+			name:     "Missing file",
+			expected: Config{Defaults: Settings{}, Aliases: map[string]string{}, Profiles: map[string]Settings{}},
+		},
+		{
+			name: "Settings and profiles",
+			content: `timeout: 20
+aliases:
+  staging-signup: qa-signup-7f3k2
+profiles:
+  work:
+    proxy: http://127.0.0.1:3128
+`,
+			expected: Config{
+				Defaults: Settings{"timeout": "20"},
+				Aliases:  map[string]string{"staging-signup": "qa-signup-7f3k2"},
+				Profiles: map[string]Settings{"work": {"proxy": "http://127.0.0.1:3128"}},
+			},
+		},
+		{
+			name:        "Unknown key",
+			content:     "colour: red\n",
+			errExpected: errors.New(`configuration file "%s" is invalid : key "colour" is not supported, use one of timeout, user-agent, proxy, output, request-interval, archive, archive-dir, archive-retention, cache, cache-dir, cache-max-age, cache-max-size`),
+		},
+		{
+			name: "Invalid value in a profile",
+			content: `profiles:
+  work:
+    timeout: soon
+`,
+			errExpected: errors.New(`configuration file "%s" is invalid : profile "work" : a positive integer is expected`),
+		},
+		{
+			name: "Alias without inbox",
+			content: `aliases:
+  staging-signup: ""
+`,
+			errExpected: errors.New(`configuration file "%s" is invalid : alias "staging-signup" must target an inbox`),
+		},
+		{
+			name:        "Invalid YAML",
+			content:     "timeout: [",
+			errExpected: errors.New(`configuration file "%s" is invalid : yaml: line 1: did not find expected node content`),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if scenario.content != "" {
+				assert.NoError(t, os.WriteFile(path, []byte(scenario.content), 0o644))
+			}
+			config, err := Load(path)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, fmt.Sprintf(scenario.errExpected.Error(), path))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expected, config)
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yogo", "config.yaml")
+	config := Config{Defaults: Settings{}, Aliases: map[string]string{"staging": "qa-7f3k2", "prod": "qa-2k9d"}, Profiles: map[string]Settings{}}
+	assert.NoError(t, config.Set("", "output", "json"))
+	assert.NoError(t, config.Set("", "timeout", "20"))
+	assert.NoError(t, config.Set("work", "proxy", "http://127.0.0.1:3128"))
+	assert.NoError(t, config.Set("ci", "output", "ndjson"))
+	assert.NoError(t, config.Save(path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `timeout: 20
+output: json
+aliases:
+  prod: qa-2k9d
+  staging: qa-7f3k2
+profiles:
+  ci:
+    output: ndjson
+  work:
+    proxy: http://127.0.0.1:3128
+`, string(data))
+
+	c, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config, c)
+}
+
+func TestSet(t *testing.T) {
+	type scenario struct {
+		name        string
+		profile     string
+		key         string
+		value       string
+		expected    Config
+		errExpected error
+	}
+
+	scenarios := []scenario{
+		{
+			name:     "Set a top level setting",
+			key:      "user-agent",
+			value:    "yogo",
+			expected: Config{Defaults: Settings{"timeout": "10", "user-agent": "yogo"}, Profiles: map[string]Settings{"work": {"timeout": "30"}}},
+		},
+		{
+			name:     "Set a setting of a new profile",
+			profile:  "ci",
+			key:      "output",
+			value:    "json",
+			expected: Config{Defaults: Settings{"timeout": "10"}, Profiles: map[string]Settings{"work": {"timeout": "30"}, "ci": {"output": "json"}}},
+		},
+		{
+			name:     "Remove the last setting of a profile",
+			profile:  "work",
+			key:      "timeout",
+			expected: Config{Defaults: Settings{"timeout": "10"}, Profiles: map[string]Settings{}},
+		},
+		{
+			name:        "Unknown key",
+			key:         "colour",
+			value:       "red",
+			errExpected: errors.New(`key "colour" is not supported, use one of timeout, user-agent, proxy, output, request-interval, archive, archive-dir, archive-retention, cache, cache-dir, cache-max-age, cache-max-size`),
+		},
+		{
+			name:        "Invalid output",
+			key:         "output",
+			value:       "xml",
+			errExpected: errors.New(`value "xml" of key "output" is invalid : output "xml" is not supported, use one of csv, json, ndjson, table, template, template-file, text, yaml`),
+		},
+		{
+			name:        "Invalid proxy",
+			key:         "proxy",
+			value:       "proxy",
+			errExpected: errors.New(`value "proxy" of key "proxy" is invalid : an URL is expected (e.g. http://127.0.0.1:3128)`),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			config := Config{Defaults: Settings{"timeout": "10"}, Profiles: map[string]Settings{"work": {"timeout": "30"}}}
+			err := config.Set(scenario.profile, scenario.key, scenario.value)
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expected, config)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	config := Config{
+		Defaults: Settings{"timeout": "10", "output": "json"},
+		Profiles: map[string]Settings{"work": {"timeout": "30"}},
+	}
+
+	settings, err := config.Resolve("")
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{"timeout": "10", "output": "json"}, settings)
+
+	settings, err = config.Resolve("work")
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{"timeout": "30", "output": "json"}, settings)
+
+	_, err = config.Resolve("home")
+	assert.EqualError(t, err, `profile "home" is not defined`)
+}
+
+func TestApply(t *testing.T) {
+	t.Setenv("YOGO_REQUEST_TIMEOUT", "5")
+	t.Setenv("YOGO_OUTPUT", "")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("http_proxy", "")
+	t.Setenv("https_proxy", "")
+
+	assert.NoError(t, Apply(Settings{"timeout": "30", "output": "yaml", "proxy": "http://127.0.0.1:3128"}))
+	assert.Equal(t, "5", os.Getenv("YOGO_REQUEST_TIMEOUT"))
+	assert.Equal(t, "yaml", os.Getenv("YOGO_OUTPUT"))
+	assert.Equal(t, "http://127.0.0.1:3128", os.Getenv("HTTP_PROXY"))
+	assert.Equal(t, "http://127.0.0.1:3128", os.Getenv("HTTPS_PROXY"))
+}
+
+func TestApplyLowercasedProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("http_proxy", "http://proxy:8080")
+
+	assert.NoError(t, Apply(Settings{"proxy": "http://127.0.0.1:3128"}))
+	assert.Equal(t, "", os.Getenv("HTTP_PROXY"))
+	assert.Equal(t, "", os.Getenv("HTTPS_PROXY"))
+}
@@ -0,0 +1,252 @@
+package imap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var sectionRegexp = regexp.MustCompile(`(?i)^(BODY(?:\.PEEK)?)\[(.*)\](?:<(\d+)\.(\d+)>)?$`)
+
+// fetchItem is a data item requested by a FETCH command
+type fetchItem struct {
+	name    string
+	section string
+	peek    bool
+	partial bool
+	offset  int
+	length  int
+}
+
+// parseFetchItems reads the data items, a single item, a macro or a list
+func parseFetchItems(v any) ([]fetchItem, error) {
+	values := []any{}
+	switch v := v.(type) {
+	case []any:
+		values = v
+	case string:
+		switch strings.ToUpper(v) {
+		case "ALL":
+			values = []any{"FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE"}
+		case "FAST":
+			values = []any{"FLAGS", "INTERNALDATE", "RFC822.SIZE"}
+		case "FULL":
+			values = []any{"FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE", "BODY"}
+		default:
+			values = []any{v}
+		}
+	}
+	items := []fetchItem{}
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, badErrorf("invalid fetch item")
+		}
+		name := strings.ToUpper(s)
+		switch name {
+		case "UID", "FLAGS", "INTERNALDATE", "RFC822.SIZE", "ENVELOPE", "BODY", "BODYSTRUCTURE":
+			items = append(items, fetchItem{name: name})
+		case "RFC822":
+			items = append(items, fetchItem{name: name})
+		case "RFC822.HEADER":
+			items = append(items, fetchItem{name: name, section: "HEADER", peek: true})
+		case "RFC822.TEXT":
+			items = append(items, fetchItem{name: name, section: "TEXT"})
+		default:
+			m := sectionRegexp.FindStringSubmatch(s)
+			if m == nil {
+				return nil, badErrorf(`unknown fetch item "%s"`, s)
+			}
+			item := fetchItem{name: "BODY[]", section: m[2], peek: strings.ToUpper(m[1]) == "BODY.PEEK"}
+			if m[3] != "" {
+				item.partial = true
+				item.offset, _ = strconv.Atoi(m[3])
+				item.length, _ = strconv.Atoi(m[4])
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// setsSeen reports whether fetching the item marks the email as seen
+func (item fetchItem) setsSeen() bool {
+	switch item.name {
+	case "RFC822", "RFC822.TEXT", "BODY[]":
+		return !item.peek
+	}
+	return false
+}
+
+// needsMessage reports whether the item requires the source of the email
+func (item fetchItem) needsMessage() bool {
+	switch item.name {
+	case "UID", "FLAGS", "INTERNALDATE":
+		return false
+	}
+	return true
+}
+
+func (c *session) fetchMessages(l *lexer, uid bool) (string, error) {
+	set, err := sequence(l)
+	if err != nil {
+		return "", err
+	}
+	v, err := l.next()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	items, err := parseFetchItems(v)
+	if err != nil {
+		return "", err
+	}
+	if uid {
+		items = append([]fetchItem{{name: "UID"}}, items...)
+	}
+
+	c.s.mu.Lock()
+	lines, err := c.fetchLines(set, uid, items)
+	c.s.mu.Unlock()
+	c.writeLines(lines)
+	return "", err
+}
+
+// fetchLines renders the responses, the server lock must be held
+func (c *session) fetchLines(set sequenceSet, uid bool, items []fetchItem) ([]string, error) {
+	lines := []string{}
+	for _, index := range c.targets(set, uid) {
+		ID := c.view[index]
+		var m *entity
+		var err error
+		fields := []string{}
+		flags, uidSent := false, false
+		seen := c.box.seen[ID]
+		for _, item := range items {
+			if m == nil && item.needsMessage() {
+				if m, err = c.box.message(ID); err != nil {
+					return lines, err
+				}
+			}
+			if item.setsSeen() && !c.readOnly {
+				c.box.seen[ID] = true
+			}
+			switch item.name {
+			case "UID":
+				if !uidSent {
+					uidSent = true
+					fields = append(fields, fmt.Sprintf("UID %d", c.box.uids[ID]))
+				}
+			case "FLAGS":
+				flags = true
+				fields = append(fields, c.box.flags(ID))
+			case "INTERNALDATE":
+				fields = append(fields, fmt.Sprintf(`INTERNALDATE "%s"`, c.internalDate(ID).Format("02-Jan-2006 15:04:05 -0700")))
+			case "RFC822.SIZE":
+				fields = append(fields, fmt.Sprintf("RFC822.SIZE %d", len(m.Raw)))
+			case "ENVELOPE":
+				fields = append(fields, "ENVELOPE "+m.envelope())
+			case "BODY", "BODYSTRUCTURE":
+				fields = append(fields, item.name+" "+m.bodyStructure())
+			case "RFC822":
+				fields = append(fields, "RFC822 "+literal(m.Raw))
+			case "RFC822.HEADER", "RFC822.TEXT":
+				data, _ := m.section(item.section)
+				fields = append(fields, item.name+" "+literal(data))
+			default:
+				data, err := m.section(item.section)
+				if err != nil {
+					return lines, badError{err.Error()}
+				}
+				label := "BODY[" + item.section + "]"
+				if item.partial {
+					label += fmt.Sprintf("<%d>", item.offset)
+					data = data[min(item.offset, len(data)):]
+					data = data[:min(item.length, len(data))]
+				}
+				fields = append(fields, label+" "+literal(data))
+			}
+		}
+		// The client must learn the flag set by the fetch
+		if !flags && !seen && c.box.seen[ID] {
+			fields = append(fields, c.box.flags(ID))
+		}
+		lines = append(lines, fmt.Sprintf("* %d FETCH (%s)", index+1, strings.Join(fields, " ")))
+	}
+	return lines, nil
+}
+
+// internalDate returns the date an email was received, the date of its
+// header is used when it isn't found in the listing
+func (c *session) internalDate(ID string) time.Time {
+	if item, ok := c.box.item(ID); ok && item.Date != nil {
+		return *item.Date
+	}
+	if m, err := c.box.message(ID); err == nil {
+		return m.date()
+	}
+	return time.Time{}
+}
+
+// storeFlags changes the flags, only \Seen and \Deleted are kept
+func (c *session) storeFlags(l *lexer, uid bool) (string, error) {
+	set, err := sequence(l)
+	if err != nil {
+		return "", err
+	}
+	operation, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	v, err := l.next()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	values, ok := v.([]any)
+	if !ok {
+		values = []any{v}
+	}
+	flags := map[string]bool{}
+	for _, value := range values {
+		if flag, ok := value.(string); ok {
+			flags[strings.ToLower(flag)] = true
+		}
+	}
+	operation = strings.ToUpper(operation)
+	silent := strings.HasSuffix(operation, ".SILENT")
+	operation = strings.TrimSuffix(operation, ".SILENT")
+	if operation != "FLAGS" && operation != "+FLAGS" && operation != "-FLAGS" {
+		return "", badErrorf(`unknown store item "%s"`, operation)
+	}
+	if c.readOnly {
+		return "", errMailboxReadOnly
+	}
+
+	c.s.mu.Lock()
+	lines := []string{}
+	for _, index := range c.targets(set, uid) {
+		ID := c.view[index]
+		for flag, state := range map[string]map[string]bool{`\seen`: c.box.seen, `\deleted`: c.box.deleted} {
+			switch operation {
+			case "FLAGS":
+				state[ID] = flags[flag]
+			case "+FLAGS":
+				state[ID] = state[ID] || flags[flag]
+			case "-FLAGS":
+				state[ID] = state[ID] && !flags[flag]
+			}
+		}
+		if silent {
+			continue
+		}
+		fields := c.box.flags(ID)
+		if uid {
+			fields = fmt.Sprintf("UID %d %s", c.box.uids[ID], fields)
+		}
+		lines = append(lines, fmt.Sprintf("* %d FETCH (%s)", index+1, fields))
+	}
+	c.s.mu.Unlock()
+	c.writeLines(lines)
+	return "", nil
+}
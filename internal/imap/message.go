@@ -0,0 +1,264 @@
+package imap
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"mime"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+var crlf = []byte("\r\n")
+
+// entity is a MIME entity of a message, the top level
+// message itself or one of its parts
+type entity inbox.Entity
+
+// parseMessage parses a message, the line endings are normalized to CRLF
+func parseMessage(raw []byte) *entity {
+	raw = bytes.ReplaceAll(raw, crlf, []byte("\n"))
+	raw = bytes.ReplaceAll(raw, []byte("\n"), crlf)
+	return (*entity)(inbox.ParseEntity(raw))
+}
+
+func (e *entity) children() []*entity {
+	children := []*entity{}
+	for _, child := range e.Children {
+		children = append(children, (*entity)(child))
+	}
+	return children
+}
+
+func (e *entity) embedded() *entity {
+	return (*entity)(e.Embedded)
+}
+
+// section returns the content designated by a section specification of
+// a fetch item, e.g. HEADER, 1.2.TEXT or HEADER.FIELDS (FROM TO)
+func (e *entity) section(spec string) ([]byte, error) {
+	current := e
+	nested := false
+	for spec != "" && spec[0] >= '0' && spec[0] <= '9' {
+		number, rest, _ := strings.Cut(spec, ".")
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf(`invalid section part "%s"`, number)
+		}
+		if current.Embedded != nil && nested {
+			current = current.embedded()
+		}
+		switch {
+		case len(current.Children) > 0:
+			if n > len(current.Children) {
+				return nil, fmt.Errorf("part %d doesn't exist", n)
+			}
+			current = (*entity)(current.Children[n-1])
+		case n != 1:
+			return nil, fmt.Errorf("part %d doesn't exist", n)
+		}
+		nested = true
+		spec = rest
+	}
+
+	upper := strings.ToUpper(spec)
+	if nested {
+		switch {
+		case upper == "":
+			return current.Body, nil
+		case upper == "MIME":
+			return current.Header, nil
+		case current.Embedded == nil:
+			return nil, fmt.Errorf(`section "%s" only applies to messages`, spec)
+		}
+		current = current.embedded()
+	}
+	switch {
+	case upper == "":
+		return current.Raw, nil
+	case upper == "HEADER":
+		return current.Header, nil
+	case upper == "TEXT":
+		return current.Body, nil
+	case strings.HasPrefix(upper, "HEADER.FIELDS.NOT "):
+		return current.headerFields(spec[len("HEADER.FIELDS.NOT "):], false)
+	case strings.HasPrefix(upper, "HEADER.FIELDS "):
+		return current.headerFields(spec[len("HEADER.FIELDS "):], true)
+	}
+	return nil, fmt.Errorf(`unsupported section "%s"`, spec)
+}
+
+// headerFields returns the header fields whose names are in
+// or out of the list, the folded lines are kept as they are
+func (e *entity) headerFields(list string, include bool) ([]byte, error) {
+	l := &lexer{s: list}
+	v, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	names, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf(`invalid header list "%s"`, list)
+	}
+	selected := map[string]bool{}
+	for _, name := range names {
+		if s, ok := name.(string); ok {
+			selected[strings.ToLower(s)] = true
+		}
+	}
+	var buf bytes.Buffer
+	keep := false
+	for _, line := range bytes.SplitAfter(e.Header, crlf) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			name, _, _ := bytes.Cut(line, []byte(":"))
+			keep = selected[strings.ToLower(string(bytes.TrimSpace(name)))] == include
+		}
+		if keep {
+			buf.Write(line)
+		}
+	}
+	buf.Write(crlf)
+	return buf.Bytes(), nil
+}
+
+// date returns the date of the message header or zero
+func (e *entity) date() time.Time {
+	d, err := mail.Header(e.Fields).Date()
+	if err != nil {
+		return time.Time{}
+	}
+	return d
+}
+
+// envelope renders the ENVELOPE structure of the message
+func (e *entity) envelope() string {
+	from := e.addresses("From")
+	sender := e.addresses("Sender")
+	if sender == "NIL" {
+		sender = from
+	}
+	replyTo := e.addresses("Reply-To")
+	if replyTo == "NIL" {
+		replyTo = from
+	}
+	return fmt.Sprintf("(%s %s %s %s %s %s %s %s %s %s)",
+		nstring(e.Fields.Get("Date")),
+		nstring(e.Fields.Get("Subject")),
+		from,
+		sender,
+		replyTo,
+		e.addresses("To"),
+		e.addresses("Cc"),
+		e.addresses("Bcc"),
+		nstring(e.Fields.Get("In-Reply-To")),
+		nstring(e.Fields.Get("Message-Id")),
+	)
+}
+
+func (e *entity) addresses(key string) string {
+	v := e.Fields.Get(key)
+	if v == "" {
+		return "NIL"
+	}
+	list, err := mail.ParseAddressList(v)
+	if err != nil || len(list) == 0 {
+		return "NIL"
+	}
+	items := []string{}
+	for _, address := range list {
+		mailbox, host, _ := strings.Cut(address.Address, "@")
+		name := address.Name
+		if name != "" {
+			name = mime.QEncoding.Encode("utf-8", name)
+		}
+		items = append(items, fmt.Sprintf("(%s NIL %s %s)", nstring(name), nstring(mailbox), nstring(host)))
+	}
+	return "(" + strings.Join(items, "") + ")"
+}
+
+// bodyStructure renders the BODYSTRUCTURE of the entity without the
+// extension data, which is also the BODY structure
+func (e *entity) bodyStructure() string {
+	mediaType, subtype, _ := strings.Cut(e.MediaType, "/")
+	if len(e.Children) > 0 {
+		var b strings.Builder
+		b.WriteString("(")
+		for _, child := range e.children() {
+			b.WriteString(child.bodyStructure())
+		}
+		b.WriteString(" " + quote(strings.ToUpper(subtype)) + ")")
+		return b.String()
+	}
+
+	values := e.Params
+	if len(values) == 0 && e.MediaType == "text/plain" {
+		values = map[string]string{"charset": "us-ascii"}
+	}
+	params := "NIL"
+	if len(values) > 0 {
+		items := []string{}
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			items = append(items, quote(strings.ToUpper(key)), quote(values[key]))
+		}
+		params = "(" + strings.Join(items, " ") + ")"
+	}
+	encoding := strings.ToUpper(e.Fields.Get("Content-Transfer-Encoding"))
+	if encoding == "" {
+		encoding = "7BIT"
+	}
+	fields := fmt.Sprintf("%s %s %s %s %s %s %d",
+		quote(strings.ToUpper(mediaType)),
+		quote(strings.ToUpper(subtype)),
+		params,
+		nstring(e.Fields.Get("Content-Id")),
+		nstring(e.Fields.Get("Content-Description")),
+		quote(encoding),
+		len(e.Body),
+	)
+	switch {
+	case e.Embedded != nil:
+		fields += fmt.Sprintf(" %s %s %d", e.embedded().envelope(), e.embedded().bodyStructure(), countLines(e.Body))
+	case mediaType == "text":
+		fields += fmt.Sprintf(" %d", countLines(e.Body))
+	}
+	return "(" + fields + ")"
+}
+
+func countLines(b []byte) int {
+	n := bytes.Count(b, crlf)
+	if len(b) > 0 && !bytes.HasSuffix(b, crlf) {
+		n++
+	}
+	return n
+}
+
+// nstring renders NIL for an empty string
+func nstring(s string) string {
+	if s == "" {
+		return "NIL"
+	}
+	return quote(s)
+}
+
+// quote renders a quoted string, or a literal when
+// the string can't be quoted
+func quote(s string) string {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '\r' || c == '\n' || c >= 0x80 || c == 0 {
+			return literal([]byte(s))
+		}
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func literal(b []byte) string {
+	return fmt.Sprintf("{%d}\r\n%s", len(b), b)
+}
@@ -0,0 +1,113 @@
+package imap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const multipartMessage = "From: \"Jöhn Doe\" <john@example.com>\n" +
+	"To: test@yopmail.com, Jane <jane@example.com>\n" +
+	"Subject: Hello\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\n" +
+	"Message-Id: <1@example.com>\n" +
+	"Content-Type: multipart/mixed; boundary=\"b1\"\n" +
+	"\n" +
+	"preamble\n" +
+	"--b1\n" +
+	"Content-Type: text/plain; charset=utf-8\n" +
+	"\n" +
+	"Hello world\n" +
+	"--b1\n" +
+	"Content-Type: message/rfc822\n" +
+	"\n" +
+	"Subject: Forwarded\n" +
+	"\n" +
+	"Inner body\n" +
+	"--b1--\n" +
+	"epilogue\n"
+
+func TestParseMessage(t *testing.T) {
+	m := parseMessage([]byte(multipartMessage))
+	assert.Equal(t, "multipart/mixed", m.MediaType)
+	require.Len(t, m.Children, 2)
+	assert.Equal(t, "Hello world", string(m.Children[0].Body))
+	assert.Equal(t, "Subject: Forwarded\r\n\r\nInner body", string(m.Children[1].Body))
+	assert.Equal(t, 2006, m.date().Year())
+}
+
+func TestSection(t *testing.T) {
+	m := parseMessage([]byte(multipartMessage))
+	type scenario struct {
+		section     string
+		expected    string
+		errExpected string
+	}
+	scenarios := []scenario{
+		{section: "1", expected: "Hello world"},
+		{section: "1.MIME", expected: "Content-Type: text/plain; charset=utf-8\r\n\r\n"},
+		{section: "2.HEADER", expected: "Subject: Forwarded\r\n\r\n"},
+		{section: "2.TEXT", expected: "Inner body"},
+		{section: "2.1", expected: "Inner body"},
+		{section: "HEADER.FIELDS (SUBJECT message-id)", expected: "Subject: Hello\r\nMessage-Id: <1@example.com>\r\n\r\n"},
+		{section: "HEADER.FIELDS.NOT (From To Date Content-Type Message-Id)", expected: "Subject: Hello\r\n\r\n"},
+		{section: "3", errExpected: "part 3 doesn't exist"},
+		{section: "1.TEXT", errExpected: `section "TEXT" only applies to messages`},
+		{section: "FOO", errExpected: `unsupported section "FOO"`},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.section, func(t *testing.T) {
+			t.Parallel()
+			data, err := m.section(scenario.section)
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, scenario.expected, string(data))
+		})
+	}
+
+	data, err := m.section("")
+	require.NoError(t, err)
+	assert.Equal(t, m.Raw, data)
+	data, err = m.section("TEXT")
+	require.NoError(t, err)
+	assert.Equal(t, m.Body, data)
+}
+
+func TestEnvelope(t *testing.T) {
+	m := parseMessage([]byte(multipartMessage))
+	assert.Equal(
+		t,
+		`("Mon, 02 Jan 2006 15:04:05 +0000" "Hello" `+
+			`(("=?utf-8?q?J=C3=B6hn_Doe?=" NIL "john" "example.com")) `+
+			`(("=?utf-8?q?J=C3=B6hn_Doe?=" NIL "john" "example.com")) `+
+			`(("=?utf-8?q?J=C3=B6hn_Doe?=" NIL "john" "example.com")) `+
+			`((NIL NIL "test" "yopmail.com")("Jane" NIL "jane" "example.com")) `+
+			`NIL NIL NIL "<1@example.com>")`,
+		m.envelope(),
+	)
+}
+
+func TestBodyStructure(t *testing.T) {
+	m := parseMessage([]byte(multipartMessage))
+	assert.Equal(
+		t,
+		`(("TEXT" "PLAIN" ("CHARSET" "utf-8") NIL NIL "7BIT" 11 1)`+
+			`("MESSAGE" "RFC822" NIL NIL NIL "7BIT" 32 (NIL "Forwarded" NIL NIL NIL NIL NIL NIL NIL NIL) ("TEXT" "PLAIN" ("CHARSET" "us-ascii") NIL NIL "7BIT" 10 1) 3)`+
+			` "MIXED")`,
+		m.bodyStructure(),
+	)
+
+	m = parseMessage([]byte("Subject: Plain\n\nLine 1\nLine 2\n"))
+	assert.Equal(t, `("TEXT" "PLAIN" ("CHARSET" "us-ascii") NIL NIL "7BIT" 16 2)`, m.bodyStructure())
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"a \"b\" \\"`, quote(`a "b" \`))
+	assert.Equal(t, "{3}\r\na\r\n", quote("a\r\n"))
+	assert.Equal(t, "NIL", nstring(""))
+}
@@ -0,0 +1,239 @@
+package imap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxLiteralSize bounds the literals sent by the clients, a
+// read-only server never receives large data from them
+const maxLiteralSize = 64 * 1024
+
+var literalRegexp = regexp.MustCompile(`\{(\d+)(\+?)\}$`)
+
+// readCommand reads a full command line, the literals announced at the
+// end of a line are read and kept inline so the lexer can decode them
+func readCommand(r *bufio.Reader, w *bufio.Writer) (string, error) {
+	var command strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command.WriteString(line)
+		m := literalRegexp.FindStringSubmatch(line)
+		if m == nil {
+			return command.String(), nil
+		}
+		size, err := strconv.Atoi(m[1])
+		if err != nil || size > maxLiteralSize {
+			return "", fmt.Errorf("literal of %s bytes is too large", m[1])
+		}
+		if m[2] == "" {
+			if _, err := w.WriteString("+ Ready for literal data\r\n"); err != nil {
+				return "", err
+			}
+			if err := w.Flush(); err != nil {
+				return "", err
+			}
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return "", err
+		}
+		command.WriteString("\r\n")
+		command.Write(data)
+	}
+}
+
+// lexer splits the arguments of a command into strings, the atoms, the
+// quoted strings and the literals, and lists of them for the parenthesized
+// lists, the sections of the fetch items are kept in their atom
+type lexer struct {
+	s   string
+	pos int
+}
+
+func (l *lexer) done() bool {
+	l.skipSpaces()
+	return l.pos >= len(l.s)
+}
+
+func (l *lexer) skipSpaces() {
+	for l.pos < len(l.s) && l.s[l.pos] == ' ' {
+		l.pos++
+	}
+}
+
+// rest returns the unread part of the command
+func (l *lexer) rest() string {
+	l.skipSpaces()
+	s := l.s[l.pos:]
+	l.pos = len(l.s)
+	return s
+}
+
+func (l *lexer) next() (any, error) {
+	l.skipSpaces()
+	if l.pos >= len(l.s) {
+		return nil, errors.New("missing argument")
+	}
+	switch l.s[l.pos] {
+	case '(':
+		l.pos++
+		list := []any{}
+		for {
+			l.skipSpaces()
+			if l.pos >= len(l.s) {
+				return nil, errors.New("unterminated list")
+			}
+			if l.s[l.pos] == ')' {
+				l.pos++
+				return list, nil
+			}
+			v, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	case '"':
+		return l.quoted()
+	case '{':
+		return l.literal()
+	}
+	return l.atom()
+}
+
+// nextString returns the next argument which must not be a list
+func (l *lexer) nextString() (string, error) {
+	v, err := l.next()
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.New("unexpected list")
+	}
+	return s, nil
+}
+
+func (l *lexer) quoted() (string, error) {
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.s); l.pos++ {
+		switch c := l.s[l.pos]; c {
+		case '\\':
+			l.pos++
+			if l.pos < len(l.s) {
+				b.WriteByte(l.s[l.pos])
+			}
+		case '"':
+			l.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated quoted string")
+}
+
+func (l *lexer) literal() (string, error) {
+	end := strings.Index(l.s[l.pos:], "}\r\n")
+	if end == -1 {
+		return "", errors.New("invalid literal")
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(l.s[l.pos+1:l.pos+end], "+"))
+	if err != nil {
+		return "", errors.New("invalid literal")
+	}
+	start := l.pos + end + 3
+	if start+size > len(l.s) {
+		return "", errors.New("truncated literal")
+	}
+	l.pos = start + size
+	return l.s[start:l.pos], nil
+}
+
+func (l *lexer) atom() (string, error) {
+	start := l.pos
+	depth := 0
+	for ; l.pos < len(l.s); l.pos++ {
+		switch l.s[l.pos] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ' ', '(', ')':
+			if depth == 0 {
+				if l.s[l.pos] == '(' && l.pos == start {
+					return "", errors.New("unexpected list")
+				}
+				return l.s[start:l.pos], nil
+			}
+		}
+	}
+	if depth != 0 {
+		return "", errors.New("unterminated section")
+	}
+	return l.s[start:l.pos], nil
+}
+
+// sequenceSet is a set of message numbers or UIDs, a zero bound stands
+// for "*", the largest number in use
+type sequenceSet []struct{ start, stop uint32 }
+
+func parseSequenceSet(s string) (sequenceSet, error) {
+	set := sequenceSet{}
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(part, ":", 2)
+		start, err := parseSequenceNumber(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid sequence set "%s"`, s)
+		}
+		stop := start
+		if len(bounds) == 2 {
+			if stop, err = parseSequenceNumber(bounds[1]); err != nil {
+				return nil, fmt.Errorf(`invalid sequence set "%s"`, s)
+			}
+		}
+		set = append(set, struct{ start, stop uint32 }{start, stop})
+	}
+	return set, nil
+}
+
+func parseSequenceNumber(s string) (uint32, error) {
+	if s == "*" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || n == 0 {
+		return 0, errors.New("invalid number")
+	}
+	return uint32(n), nil
+}
+
+// contains reports whether n is in the set, largest is the value of "*"
+func (set sequenceSet) contains(n uint32, largest uint32) bool {
+	for _, r := range set {
+		start, stop := r.start, r.stop
+		if start == 0 {
+			start = largest
+		}
+		if stop == 0 {
+			stop = largest
+		}
+		if start > stop {
+			start, stop = stop, start
+		}
+		if n >= start && n <= stop {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package imap
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCommand(t *testing.T) {
+	type scenario struct {
+		name           string
+		input          string
+		expected       string
+		outputExpected string
+		errExpected    string
+	}
+	scenarios := []scenario{
+		{
+			name:     "Single line",
+			input:    "a1 NOOP\r\n",
+			expected: "a1 NOOP",
+		},
+		{
+			name:           "Synchronizing literal",
+			input:          "a1 LOGIN {4}\r\nuser pass\r\n",
+			expected:       "a1 LOGIN {4}\r\nuser pass",
+			outputExpected: "+ Ready for literal data\r\n",
+		},
+		{
+			name:     "Non synchronizing literal",
+			input:    "a1 LOGIN {4+}\r\nuser {4+}\r\npass\r\n",
+			expected: "a1 LOGIN {4+}\r\nuser {4+}\r\npass",
+		},
+		{
+			name:        "Literal too large",
+			input:       "a1 APPEND INBOX {100000}\r\n",
+			errExpected: "literal of 100000 bytes is too large",
+		},
+		{
+			name:        "Truncated literal",
+			input:       "a1 LOGIN {10+}\r\nuser",
+			errExpected: "unexpected EOF",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			w := bufio.NewWriter(&output)
+			command, err := readCommand(bufio.NewReader(strings.NewReader(scenario.input)), w)
+			if scenario.errExpected != "" {
+				assert.EqualError(t, err, scenario.errExpected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, scenario.expected, command)
+			assert.Equal(t, scenario.outputExpected, output.String())
+		})
+	}
+}
+
+func TestLexer(t *testing.T) {
+	l := &lexer{s: `1:* (UID BODY.PEEK[HEADER.FIELDS (FROM TO)]<0.10>) "a \"quoted\" string" {5}` + "\r\nhello" + ` NIL`}
+	values := []any{}
+	for !l.done() {
+		v, err := l.next()
+		require.NoError(t, err)
+		values = append(values, v)
+	}
+	assert.Equal(t, []any{
+		"1:*",
+		[]any{"UID", "BODY.PEEK[HEADER.FIELDS (FROM TO)]<0.10>"},
+		`a "quoted" string`,
+		"hello",
+		"NIL",
+	}, values)
+
+	for _, s := range []string{"(UID", `"unterminated`, "BODY[TEXT", "{10}\r\nshort"} {
+		_, err := (&lexer{s: s}).next()
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSequenceSet(t *testing.T) {
+	set, err := parseSequenceSet("1,3:4,10:*")
+	require.NoError(t, err)
+	contained := []uint32{}
+	for n := uint32(1); n <= 12; n++ {
+		if set.contains(n, 12) {
+			contained = append(contained, n)
+		}
+	}
+	assert.Equal(t, []uint32{1, 3, 4, 10, 11, 12}, contained)
+
+	set, err = parseSequenceSet("*:2")
+	require.NoError(t, err)
+	assert.True(t, set.contains(3, 3))
+	assert.False(t, set.contains(1, 3))
+
+	for _, s := range []string{"", "0", "a:2", "1,,2"} {
+		_, err := parseSequenceSet(s)
+		assert.EqualError(t, err, `invalid sequence set "`+s+`"`)
+	}
+}
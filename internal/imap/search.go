@@ -0,0 +1,314 @@
+package imap
+
+import (
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchTarget is an email evaluated by a search
+type searchTarget struct {
+	c     *session
+	index int
+	ID    string
+}
+
+func (t searchTarget) message() (*entity, error) {
+	return t.c.box.message(t.ID)
+}
+
+// searchKey is a search criterion, the source of the email
+// is only fetched by the keys looking at it
+type searchKey func(t searchTarget) (bool, error)
+
+func (c *session) searchMessages(l *lexer, uid bool) (string, error) {
+	keys := []searchKey{}
+	for !l.done() {
+		key, err := parseSearchKey(l)
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return "", badErrorf("missing search criteria")
+	}
+	match := and(keys)
+
+	c.s.mu.Lock()
+	numbers := []string{}
+	var err error
+	for index, ID := range c.view {
+		var ok bool
+		if ok, err = match(searchTarget{c: c, index: index, ID: ID}); err != nil {
+			break
+		}
+		if !ok {
+			continue
+		}
+		if uid {
+			numbers = append(numbers, strconv.Itoa(int(c.box.uids[ID])))
+		} else {
+			numbers = append(numbers, strconv.Itoa(index+1))
+		}
+	}
+	c.s.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	c.writeLine(strings.TrimSpace("* SEARCH " + strings.Join(numbers, " ")))
+	return "", nil
+}
+
+func and(keys []searchKey) searchKey {
+	return func(t searchTarget) (bool, error) {
+		for _, key := range keys {
+			if ok, err := key(t); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+func parseSearchKey(l *lexer) (searchKey, error) {
+	v, err := l.next()
+	if err != nil {
+		return nil, badError{err.Error()}
+	}
+	if list, ok := v.([]any); ok {
+		keys := []searchKey{}
+		sub := &lexer{s: unparse(list)}
+		for !sub.done() {
+			key, err := parseSearchKey(sub)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		return and(keys), nil
+	}
+	name := v.(string)
+	constant := func(b bool) searchKey {
+		return func(t searchTarget) (bool, error) { return b, nil }
+	}
+
+	switch strings.ToUpper(name) {
+	case "ALL", "OLD", "UNANSWERED", "UNFLAGGED", "UNDRAFT":
+		return constant(true), nil
+	case "ANSWERED", "FLAGGED", "DRAFT", "RECENT", "NEW":
+		return constant(false), nil
+	case "SEEN", "UNSEEN", "DELETED", "UNDELETED":
+		upper := strings.ToUpper(name)
+		expected := !strings.HasPrefix(upper, "UN")
+		seen := strings.HasSuffix(upper, "SEEN")
+		return func(t searchTarget) (bool, error) {
+			flags := t.c.box.deleted
+			if seen {
+				flags = t.c.box.seen
+			}
+			return flags[t.ID] == expected, nil
+		}, nil
+	case "KEYWORD":
+		if _, err := l.nextString(); err != nil {
+			return nil, badError{err.Error()}
+		}
+		return constant(false), nil
+	case "UNKEYWORD":
+		if _, err := l.nextString(); err != nil {
+			return nil, badError{err.Error()}
+		}
+		return constant(true), nil
+	case "NOT":
+		key, err := parseSearchKey(l)
+		if err != nil {
+			return nil, err
+		}
+		return func(t searchTarget) (bool, error) {
+			ok, err := key(t)
+			return !ok, err
+		}, nil
+	case "OR":
+		left, err := parseSearchKey(l)
+		if err != nil {
+			return nil, err
+		}
+		right, err := parseSearchKey(l)
+		if err != nil {
+			return nil, err
+		}
+		return func(t searchTarget) (bool, error) {
+			if ok, err := left(t); err != nil || ok {
+				return ok, err
+			}
+			return right(t)
+		}, nil
+	case "CHARSET":
+		// The strings are compared as UTF-8 whatever the charset
+		if _, err := l.nextString(); err != nil {
+			return nil, badError{err.Error()}
+		}
+		return constant(true), nil
+	case "UID":
+		set, err := sequence(l)
+		if err != nil {
+			return nil, err
+		}
+		return func(t searchTarget) (bool, error) {
+			largest := uint32(0)
+			for _, ID := range t.c.view {
+				largest = max(largest, t.c.box.uids[ID])
+			}
+			return set.contains(t.c.box.uids[t.ID], largest), nil
+		}, nil
+	case "FROM":
+		return stringKey(l, func(t searchTarget) (string, error) {
+			if item, ok := t.c.box.item(t.ID); ok && item.Sender != nil {
+				return item.Sender.Name + " " + item.Sender.Mail, nil
+			}
+			return headerValue(t, "From")
+		})
+	case "SUBJECT":
+		return stringKey(l, func(t searchTarget) (string, error) {
+			if item, ok := t.c.box.item(t.ID); ok {
+				return item.Subject, nil
+			}
+			return headerValue(t, "Subject")
+		})
+	case "TO", "CC", "BCC":
+		field := name
+		return stringKey(l, func(t searchTarget) (string, error) {
+			return headerValue(t, field)
+		})
+	case "HEADER":
+		field, err := l.nextString()
+		if err != nil {
+			return nil, badError{err.Error()}
+		}
+		return stringKey(l, func(t searchTarget) (string, error) {
+			return headerValue(t, field)
+		})
+	case "BODY":
+		return stringKey(l, func(t searchTarget) (string, error) {
+			m, err := t.message()
+			if err != nil {
+				return "", err
+			}
+			return string(m.Body), nil
+		})
+	case "TEXT":
+		return stringKey(l, func(t searchTarget) (string, error) {
+			m, err := t.message()
+			if err != nil {
+				return "", err
+			}
+			return string(m.Raw), nil
+		})
+	case "LARGER", "SMALLER":
+		s, err := l.nextString()
+		if err != nil {
+			return nil, badError{err.Error()}
+		}
+		size, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, badErrorf(`invalid size "%s"`, s)
+		}
+		larger := strings.EqualFold(name, "LARGER")
+		return func(t searchTarget) (bool, error) {
+			m, err := t.message()
+			if err != nil {
+				return false, err
+			}
+			if larger {
+				return len(m.Raw) > size, nil
+			}
+			return len(m.Raw) < size, nil
+		}, nil
+	case "BEFORE", "ON", "SINCE":
+		return dateKey(l, name, func(t searchTarget) (time.Time, error) {
+			return t.c.internalDate(t.ID), nil
+		})
+	case "SENTBEFORE", "SENTON", "SENTSINCE":
+		return dateKey(l, strings.TrimPrefix(strings.ToUpper(name), "SENT"), func(t searchTarget) (time.Time, error) {
+			m, err := t.message()
+			if err != nil {
+				return time.Time{}, err
+			}
+			return m.date(), nil
+		})
+	}
+
+	set, err := parseSequenceSet(name)
+	if err != nil {
+		return nil, badErrorf(`unknown search key "%s"`, name)
+	}
+	return func(t searchTarget) (bool, error) {
+		return set.contains(uint32(t.index+1), uint32(len(t.c.view))), nil
+	}, nil
+}
+
+// stringKey matches a value containing the string read, ignoring the case
+func stringKey(l *lexer, value func(t searchTarget) (string, error)) (searchKey, error) {
+	s, err := l.nextString()
+	if err != nil {
+		return nil, badError{err.Error()}
+	}
+	s = strings.ToLower(s)
+	return func(t searchTarget) (bool, error) {
+		v, err := value(t)
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(strings.ToLower(v), s), nil
+	}, nil
+}
+
+// dateKey compares the day of a date with the date read, the time
+// and the timezone are ignored
+func dateKey(l *lexer, comparison string, value func(t searchTarget) (time.Time, error)) (searchKey, error) {
+	s, err := l.nextString()
+	if err != nil {
+		return nil, badError{err.Error()}
+	}
+	day, err := time.Parse("2-Jan-2006", s)
+	if err != nil {
+		return nil, badErrorf(`invalid date "%s"`, s)
+	}
+	return func(t searchTarget) (bool, error) {
+		v, err := value(t)
+		if err != nil {
+			return false, err
+		}
+		d := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		switch strings.ToUpper(comparison) {
+		case "BEFORE":
+			return d.Before(day), nil
+		case "ON":
+			return d.Equal(day), nil
+		}
+		return !d.Before(day), nil
+	}, nil
+}
+
+func headerValue(t searchTarget, field string) (string, error) {
+	m, err := t.message()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(m.Fields[textproto.CanonicalMIMEHeaderKey(field)], " "), nil
+}
+
+// unparse renders back a list read by the lexer so it can be read again
+func unparse(list []any) string {
+	parts := []string{}
+	for _, v := range list {
+		switch v := v.(type) {
+		case []any:
+			parts = append(parts, "("+unparse(v)+")")
+		case string:
+			parts = append(parts, quote(v))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,191 @@
+// Package imap exposes the inboxes to the mail clients through a
+// read-only IMAP4rev1 server, the login name selects the inbox
+package imap
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+// Config defines how the inboxes are served
+type Config struct {
+	// Limit is the number of emails available in a mailbox
+	Limit int
+	// RefreshInterval is the minimum delay between two listings of an inbox
+	RefreshInterval time.Duration
+	// Name normalizes the login names
+	Name func(string) string
+}
+
+// Server serves the sources of the emails of the inboxes as the INBOX
+// mailbox of a user, the listings and the emails are kept in memory to
+// limit the number of requests
+type Server struct {
+	build       backend.Builder
+	config      Config
+	uidValidity uint32
+	// mu guards the mailboxes and serializes the calls to the inboxes
+	mu        sync.Mutex
+	mailboxes map[string]*mailbox
+	conns     backend.Conns
+}
+
+// New creates a server building its inboxes with build
+func New(build backend.Builder, config Config) *Server {
+	if config.Limit == 0 {
+		config.Limit = 50
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = 30 * time.Second
+	}
+	if config.Name == nil {
+		config.Name = func(name string) string { return name }
+	}
+	return &Server{
+		build:  build,
+		config: config,
+		// The UIDs are only kept in memory, they are valid until the server restarts
+		uidValidity: uint32(time.Now().Unix()),
+		mailboxes:   map[string]*mailbox{},
+	}
+}
+
+// Serve accepts the connections until the server is closed
+func (s *Server) Serve(l net.Listener) error {
+	return s.conns.Serve(l, func(conn net.Conn) {
+		newSession(s, conn).serve()
+	})
+}
+
+// Close stops the listeners and closes the connections
+func (s *Server) Close() error {
+	return s.conns.Close()
+}
+
+// mailbox returns the mailbox of an inbox, it is shared by the sessions
+// of the same user, the server lock must be held
+func (s *Server) mailbox(name string) (*mailbox, error) {
+	if box, ok := s.mailboxes[name]; ok {
+		return box, nil
+	}
+	in, err := s.build(name, true)
+	if err != nil {
+		return nil, err
+	}
+	box := &mailbox{
+		in:       in,
+		uids:     map[string]uint32{},
+		uidNext:  1,
+		seen:     map[string]bool{},
+		deleted:  map[string]bool{},
+		messages: map[string]*entity{},
+	}
+	s.mailboxes[name] = box
+	return box, nil
+}
+
+// mailbox holds the emails of an inbox, the oldest email comes first
+type mailbox struct {
+	in       backend.Inbox
+	items    []inbox.InboxItem
+	listedAt time.Time
+	uids     map[string]uint32
+	uidNext  uint32
+	seen     map[string]bool
+	deleted  map[string]bool
+	messages map[string]*entity
+}
+
+// refresh lists the inbox again unless it has been listed recently
+func (b *mailbox) refresh(limit int, interval time.Duration) error {
+	if !b.listedAt.IsZero() && time.Since(b.listedAt) < interval {
+		return nil
+	}
+	if err := b.in.Refresh(limit); err != nil {
+		return err
+	}
+	b.listedAt = time.Now()
+	b.items = slices.Clone(b.in.GetMails())
+	slices.Reverse(b.items)
+	present := map[string]bool{}
+	for _, item := range b.items {
+		present[item.ID] = true
+		if _, ok := b.uids[item.ID]; !ok {
+			b.uids[item.ID] = b.uidNext
+			b.uidNext++
+		}
+	}
+	for ID := range b.uids {
+		if !present[ID] {
+			b.forget(ID)
+		}
+	}
+	// An email listed again after leaving the window gets a new UID,
+	// the UIDs must ascend with the sequence numbers
+	slices.SortStableFunc(b.items, func(a, c inbox.InboxItem) int {
+		return cmp.Compare(b.uids[a.ID], b.uids[c.ID])
+	})
+	return nil
+}
+
+// message returns the parsed source of an email, it is fetched once
+func (b *mailbox) message(ID string) (*entity, error) {
+	if m, ok := b.messages[ID]; ok {
+		return m, nil
+	}
+	mail, err := b.in.FetchByID(ID)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := mail.(interface{ Message() []byte })
+	if !ok {
+		return nil, fmt.Errorf(`source of email "%s" is not available`, ID)
+	}
+	m := parseMessage(source.Message())
+	b.messages[ID] = m
+	return m, nil
+}
+
+// remove deletes an email from yopmail
+func (b *mailbox) remove(ID string) error {
+	if err := b.in.DeleteByID(ID); err != nil {
+		return err
+	}
+	b.items = slices.DeleteFunc(b.items, func(item inbox.InboxItem) bool { return item.ID == ID })
+	b.forget(ID)
+	return nil
+}
+
+func (b *mailbox) forget(ID string) {
+	delete(b.uids, ID)
+	delete(b.messages, ID)
+	delete(b.seen, ID)
+	delete(b.deleted, ID)
+}
+
+func (b *mailbox) item(ID string) (inbox.InboxItem, bool) {
+	index := slices.IndexFunc(b.items, func(item inbox.InboxItem) bool { return item.ID == ID })
+	if index == -1 {
+		return inbox.InboxItem{}, false
+	}
+	return b.items[index], true
+}
+
+func (b *mailbox) flags(ID string) string {
+	flags := []string{}
+	if b.seen[ID] {
+		flags = append(flags, `\Seen`)
+	}
+	if b.deleted[ID] {
+		flags = append(flags, `\Deleted`)
+	}
+	return fmt.Sprintf("FLAGS (%s)", strings.Join(flags, " "))
+}
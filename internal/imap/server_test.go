@@ -0,0 +1,241 @@
+package imap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/backend/backendtest"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// client sends commands to the server and reads
+// the responses, the literals are kept inline
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+var literalSuffixRegexp = regexp.MustCompile(`\{(\d+)\}$`)
+
+func (c *client) readLine() string {
+	var response strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		require.NoError(c.t, err)
+		line = strings.TrimSuffix(line, "\r\n")
+		response.WriteString(line)
+		m := literalSuffixRegexp.FindStringSubmatch(line)
+		if m == nil {
+			return response.String()
+		}
+		size, _ := strconv.Atoi(m[1])
+		data := make([]byte, size)
+		_, err = io.ReadFull(c.r, data)
+		require.NoError(c.t, err)
+		response.WriteString("\r\n" + string(data))
+	}
+}
+
+// send runs a command and returns all the response lines
+func (c *client) send(tag string, command string) []string {
+	_, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command)
+	require.NoError(c.t, err)
+	lines := []string{}
+	for {
+		line := c.readLine()
+		lines = append(lines, line)
+		if strings.HasPrefix(line, tag+" ") {
+			return lines
+		}
+	}
+}
+
+const welcomeMessage = "From: Yopmail <contact@yopmail.com>\n" +
+	"To: test@yopmail.com\n" +
+	"Subject: Welcome\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\n" +
+	"\n" +
+	"Welcome to yopmail\n"
+
+const newsMessage = "From: News <news@example.com>\n" +
+	"To: test@yopmail.com\n" +
+	"Subject: News\n" +
+	"Date: Tue, 03 Jan 2006 15:04:05 +0000\n" +
+	"\n" +
+	"Latest news\n"
+
+func newTestInbox() *backendtest.Inbox {
+	date1 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	date2 := time.Date(2006, 1, 3, 15, 4, 5, 0, time.UTC)
+	return &backendtest.Inbox{
+		Lists: [][]inbox.InboxItem{{
+			{ID: "news", Subject: "News", Sender: &inbox.Sender{Name: "News", Mail: "news@example.com"}, Date: &date2},
+			{ID: "welcome", Subject: "Welcome", Sender: &inbox.Sender{Name: "Yopmail", Mail: "contact@yopmail.com"}, Date: &date1},
+		}},
+		Mails: map[string]inbox.Mail{
+			"welcome": backendtest.Source{Raw: welcomeMessage},
+			"news":    backendtest.Source{Raw: newsMessage},
+		},
+	}
+}
+
+// startServer serves the inbox and returns a connected client
+func startServer(t *testing.T, in *backendtest.Inbox, config Config) (*Server, *client) {
+	s := New(func(name string, source bool) (backend.Inbox, error) {
+		if name != "test" {
+			return nil, fmt.Errorf(`inbox "%s" doesn't exist`, name)
+		}
+		return in, nil
+	}, config)
+	conn := backendtest.Dial(t, s)
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+	assert.Equal(t, "* OK [CAPABILITY IMAP4rev1 AUTH=PLAIN UNSELECT] Yogo IMAP server ready", c.readLine())
+	return s, c
+}
+
+func TestServerSession(t *testing.T) {
+	in := newTestInbox()
+	s, c := startServer(t, in, Config{})
+
+	assert.Equal(t, []string{"a1 BAD SELECT is not allowed now"}, c.send("a1", "SELECT INBOX"))
+	assert.Equal(t, []string{"a2 NO inbox \"unknown\" doesn't exist"}, c.send("a2", "LOGIN unknown secret"))
+	assert.Equal(t, []string{"a3 OK LOGIN completed"}, c.send("a3", "LOGIN test secret"))
+	assert.Equal(t, []string{`* LIST (\HasNoChildren) "/" INBOX`, "a4 OK LIST completed"}, c.send("a4", `LIST "" *`))
+	assert.Equal(t, []string{`* LIST (\Noselect) "/" ""`, "a5 OK LIST completed"}, c.send("a5", `LIST "" ""`))
+	assert.Equal(t, []string{"a6 OK LIST completed"}, c.send("a6", `LIST "" Sent`))
+	assert.Equal(t, []string{
+		"* STATUS INBOX (MESSAGES 2 UIDNEXT 3 UNSEEN 2)",
+		"a7 OK STATUS completed",
+	}, c.send("a7", "STATUS INBOX (MESSAGES UIDNEXT UNSEEN)"))
+	assert.Equal(t, []string{
+		`* FLAGS (\Seen \Deleted)`,
+		"* 2 EXISTS",
+		"* 0 RECENT",
+		"* OK [UNSEEN 1] First unseen message",
+		`* OK [PERMANENTFLAGS (\Seen \Deleted)] Flags permitted`,
+		fmt.Sprintf("* OK [UIDVALIDITY %d] UIDs valid", s.uidValidity),
+		"* OK [UIDNEXT 3] Predicted next UID",
+		"a8 OK [READ-WRITE] SELECT completed",
+	}, c.send("a8", "SELECT INBOX"))
+
+	assert.Equal(t, []string{
+		`* 1 FETCH (FLAGS () INTERNALDATE "02-Jan-2006 15:04:05 +0000" RFC822.SIZE 138 ENVELOPE ("Mon, 02 Jan 2006 15:04:05 +0000" "Welcome" (("Yopmail" NIL "contact" "yopmail.com")) (("Yopmail" NIL "contact" "yopmail.com")) (("Yopmail" NIL "contact" "yopmail.com")) ((NIL NIL "test" "yopmail.com")) NIL NIL NIL NIL))`,
+		"a9 OK FETCH completed",
+	}, c.send("a9", "FETCH 1 ALL"))
+	assert.Equal(t, []string{
+		"* 2 FETCH (UID 2 BODY[HEADER.FIELDS (SUBJECT)] {17}\r\nSubject: News\r\n\r\n)",
+		"a10 OK UID FETCH completed",
+	}, c.send("a10", "UID FETCH 2:* (BODY.PEEK[HEADER.FIELDS (SUBJECT)])"))
+	assert.Equal(t, []string{
+		"* 2 FETCH (BODY[TEXT]<0> {6}\r\nLatest FLAGS (\\Seen))",
+		"a11 OK FETCH completed",
+	}, c.send("a11", "FETCH 2 BODY[TEXT]<0.6>"))
+	assert.Equal(t, []string{"* SEARCH 1", "a12 OK SEARCH completed"}, c.send("a12", "SEARCH UNSEEN"))
+	assert.Equal(t, []string{"* SEARCH 2", "a13 OK UID SEARCH completed"}, c.send("a13", `UID SEARCH OR FROM "news" SUBJECT "foo"`))
+	assert.Equal(t, []string{"* SEARCH 1", "a14 OK SEARCH completed"}, c.send("a14", "SEARCH CHARSET UTF-8 BODY yopmail NOT SINCE 3-Jan-2006"))
+	assert.Equal(t, []string{"* SEARCH", "a15 OK SEARCH completed"}, c.send("a15", "SEARCH (TO nobody)"))
+
+	assert.Equal(t, []string{`* 1 FETCH (FLAGS (\Deleted))`, "a16 OK STORE completed"}, c.send("a16", `STORE 1 +FLAGS (\Deleted)`))
+	assert.Equal(t, []string{"* 1 EXPUNGE", "a17 OK EXPUNGE completed"}, c.send("a17", "EXPUNGE"))
+	assert.Equal(t, []string{"welcome"}, in.DeletedIDs)
+	assert.Equal(t, []string{`* 1 FETCH (UID 2 FLAGS (\Seen))`, "a18 OK UID FETCH completed"}, c.send("a18", "UID FETCH 1:* FLAGS"))
+	assert.Equal(t, []string{"a19 NO the server is read-only"}, c.send("a19", "COPY 1 Sent"))
+	assert.Equal(t, []string{"a20 BAD Unknown command IDLE"}, c.send("a20", "IDLE"))
+	assert.Equal(t, []string{"a21 BAD unknown fetch item \"FOO\""}, c.send("a21", "FETCH 1 FOO"))
+	assert.Equal(t, []string{"* BYE Logging out", "a22 OK LOGOUT completed"}, c.send("a22", "LOGOUT"))
+
+	// The emails are only fetched once
+	assert.Equal(t, []string{"welcome", "news"}, in.FetchedIDs)
+	assert.Equal(t, 1, in.RefreshCount)
+}
+
+func TestServerExamine(t *testing.T) {
+	in := newTestInbox()
+	_, c := startServer(t, in, Config{})
+
+	assert.Equal(t, "a1 OK LOGIN completed", c.send("a1", "LOGIN {4+}\r\ntest \"\"")[0])
+	lines := c.send("a2", "EXAMINE inbox")
+	assert.Contains(t, lines, "* OK [PERMANENTFLAGS ()] Flags permitted")
+	assert.Equal(t, "a2 OK [READ-ONLY] EXAMINE completed", lines[len(lines)-1])
+	assert.Equal(t, []string{"* 1 FETCH (RFC822.TEXT {20}\r\nWelcome to yopmail\r\n)", "a3 OK FETCH completed"}, c.send("a3", "FETCH 1 RFC822.TEXT"))
+	assert.Equal(t, []string{"* SEARCH 1 2", "a4 OK SEARCH completed"}, c.send("a4", "SEARCH UNSEEN"))
+	assert.Equal(t, []string{"a5 NO mailbox is read-only"}, c.send("a5", `STORE 1 +FLAGS (\Deleted)`))
+	assert.Equal(t, []string{"a6 NO mailbox is read-only"}, c.send("a6", "EXPUNGE"))
+	assert.Equal(t, []string{"a7 OK CLOSE completed"}, c.send("a7", "CLOSE"))
+	assert.Equal(t, []string{"a8 BAD FETCH is not allowed now"}, c.send("a8", "FETCH 1 FLAGS"))
+	assert.Empty(t, in.DeletedIDs)
+}
+
+func TestServerAuthenticate(t *testing.T) {
+	in := newTestInbox()
+	_, c := startServer(t, in, Config{})
+
+	_, err := fmt.Fprint(c.conn, "a1 AUTHENTICATE PLAIN\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "+ ", c.readLine())
+	_, err = fmt.Fprint(c.conn, "AHRlc3QAc2VjcmV0\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "a1 OK AUTHENTICATE completed", c.readLine())
+	assert.Equal(t, []string{"a2 BAD already authenticated"}, c.send("a2", "AUTHENTICATE PLAIN AHRlc3QAc2VjcmV0"))
+	assert.Equal(t, []string{"a3 NO unsupported authentication mechanism \"LOGIN\""}, c.send("a3", "AUTHENTICATE LOGIN"))
+}
+
+func TestServerUpdates(t *testing.T) {
+	in := newTestInbox()
+	in.Lists = append(in.Lists, []inbox.InboxItem{
+		{ID: "last", Subject: "Last"},
+		in.Lists[0][0],
+	})
+	_, c := startServer(t, in, Config{RefreshInterval: time.Nanosecond})
+
+	c.send("a1", "LOGIN test secret")
+	c.send("a2", "SELECT INBOX")
+	assert.Equal(t, []string{"* 1 EXPUNGE", "* 2 EXISTS", "a3 OK NOOP completed"}, c.send("a3", "NOOP"))
+	assert.Equal(t, []string{"* 1 FETCH (UID 2)", "* 2 FETCH (UID 3)", "a4 OK FETCH completed"}, c.send("a4", "FETCH 1:* UID"))
+	assert.Equal(t, []string{"a5 NO failure when fetching email \"last\""}, c.send("a5", "FETCH 2 BODY.PEEK[]"))
+
+	in.Lock()
+	in.RefreshError = errors.New("captcha")
+	in.Unlock()
+	assert.Equal(t, []string{"a6 NO captcha"}, c.send("a6", "CHECK"))
+
+	c.send("a7", "STORE 1:* +FLAGS.SILENT (\\Deleted)")
+	in.Lock()
+	in.DeleteError = errors.New("delete failed")
+	in.Unlock()
+	assert.Equal(t, []string{"a8 NO delete failed"}, c.send("a8", "CLOSE"))
+}
+
+func TestMailboxRefresh(t *testing.T) {
+	in := newTestInbox()
+	news, welcome := in.Lists[0][0], in.Lists[0][1]
+	in.Lists = append(in.Lists, []inbox.InboxItem{news}, []inbox.InboxItem{news, welcome})
+	b := &mailbox{
+		in:       in,
+		uids:     map[string]uint32{},
+		uidNext:  1,
+		seen:     map[string]bool{},
+		deleted:  map[string]bool{},
+		messages: map[string]*entity{},
+	}
+
+	require.NoError(t, b.refresh(50, 0))
+	assert.Equal(t, map[string]uint32{"welcome": 1, "news": 2}, b.uids)
+	require.NoError(t, b.refresh(50, 0))
+	assert.Equal(t, map[string]uint32{"news": 2}, b.uids)
+	require.NoError(t, b.refresh(50, 0))
+	assert.Equal(t, map[string]uint32{"news": 2, "welcome": 3}, b.uids)
+	assert.Equal(t, []inbox.InboxItem{news, welcome}, b.items)
+}
@@ -0,0 +1,579 @@
+package imap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// autoLogout is the inactivity delay after which a client is disconnected
+	autoLogout = 30 * time.Minute
+	// writeTimeout bounds the time spent sending a response
+	writeTimeout = time.Minute
+)
+
+const capabilities = "IMAP4rev1 AUTH=PLAIN UNSELECT"
+
+// badError is returned by the commands when the client sent an invalid command
+type badError struct {
+	msg string
+}
+
+func (e badError) Error() string {
+	return e.msg
+}
+
+func badErrorf(format string, a ...any) error {
+	return badError{fmt.Sprintf(format, a...)}
+}
+
+var (
+	errReadOnly        = errors.New("the server is read-only")
+	errMailboxReadOnly = errors.New("mailbox is read-only")
+)
+
+type state int
+
+const (
+	stateNotAuthenticated state = iota
+	stateAuthenticated
+	stateSelected
+)
+
+// command runs a command, the returned code prefixes the completion text
+type command struct {
+	state state
+	run   func(c *session, l *lexer) (code string, err error)
+}
+
+var commands map[string]command
+
+func init() {
+	readOnly := func(c *session, l *lexer) (string, error) {
+		return "", errReadOnly
+	}
+	commands = map[string]command{
+		"CAPABILITY":   {stateNotAuthenticated, (*session).capability},
+		"NOOP":         {stateNotAuthenticated, (*session).noop},
+		"LOGOUT":       {stateNotAuthenticated, (*session).logout},
+		"LOGIN":        {stateNotAuthenticated, (*session).login},
+		"AUTHENTICATE": {stateNotAuthenticated, (*session).authenticate},
+		"SELECT":       {stateAuthenticated, (*session).selectMailbox},
+		"EXAMINE":      {stateAuthenticated, (*session).examine},
+		"LIST":         {stateAuthenticated, (*session).list},
+		"LSUB":         {stateAuthenticated, (*session).lsub},
+		"STATUS":       {stateAuthenticated, (*session).status},
+		"CREATE":       {stateAuthenticated, readOnly},
+		"DELETE":       {stateAuthenticated, readOnly},
+		"RENAME":       {stateAuthenticated, readOnly},
+		"SUBSCRIBE":    {stateAuthenticated, readOnly},
+		"UNSUBSCRIBE":  {stateAuthenticated, readOnly},
+		"APPEND":       {stateAuthenticated, readOnly},
+		"CHECK":        {stateSelected, (*session).noop},
+		"CLOSE":        {stateSelected, (*session).close},
+		"UNSELECT":     {stateSelected, (*session).unselect},
+		"EXPUNGE":      {stateSelected, (*session).expunge},
+		"SEARCH":       {stateSelected, (*session).search},
+		"FETCH":        {stateSelected, (*session).fetch},
+		"STORE":        {stateSelected, (*session).store},
+		"COPY":         {stateSelected, readOnly},
+		"UID":          {stateSelected, (*session).uid},
+	}
+}
+
+// session is the connection of a client
+type session struct {
+	s    *Server
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	// done is set when the client logged out
+	done     bool
+	box      *mailbox
+	selected bool
+	readOnly bool
+	// view holds the IDs of the emails known by the client, the sequence
+	// number of an email is its position in the view
+	view []string
+}
+
+func newSession(s *Server, conn net.Conn) *session {
+	return &session{s: s, conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+}
+
+func (c *session) serve() {
+	c.writeLine("* OK [CAPABILITY " + capabilities + "] Yogo IMAP server ready")
+	for !c.done {
+		if err := c.w.Flush(); err != nil {
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(autoLogout))
+		line, err := readCommand(c.r, c.w)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				c.writeLine("* BYE Autologout, idle for too long")
+			} else if !errors.Is(err, net.ErrClosed) {
+				c.writeLine("* BYE " + err.Error())
+			}
+			_ = c.w.Flush()
+			return
+		}
+		_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		tag, rest, _ := strings.Cut(line, " ")
+		name, args, _ := strings.Cut(rest, " ")
+		if tag == "" || name == "" {
+			c.writeLine("* BAD Invalid command")
+			continue
+		}
+		c.run(tag, strings.ToUpper(name), &lexer{s: args})
+	}
+	_ = c.w.Flush()
+}
+
+func (c *session) run(tag string, name string, l *lexer) {
+	cmd, ok := commands[name]
+	switch {
+	case !ok:
+		c.writeLine(fmt.Sprintf("%s BAD Unknown command %s", tag, name))
+		return
+	case cmd.state > c.state():
+		c.writeLine(fmt.Sprintf("%s BAD %s is not allowed now", tag, name))
+		return
+	}
+	if name == "UID" {
+		command, _, _ := strings.Cut(strings.TrimSpace(l.s), " ")
+		name += " " + strings.ToUpper(command)
+	}
+	code, err := cmd.run(c, l)
+	if err == nil && !l.done() {
+		err = badErrorf("unexpected arguments")
+	}
+	var badErr badError
+	switch {
+	case errors.As(err, &badErr):
+		c.writeLine(fmt.Sprintf("%s BAD %s", tag, err))
+	case err != nil:
+		c.writeLine(fmt.Sprintf("%s NO %s", tag, err))
+	case code != "":
+		c.writeLine(fmt.Sprintf("%s OK %s %s completed", tag, code, name))
+	default:
+		c.writeLine(fmt.Sprintf("%s OK %s completed", tag, name))
+	}
+}
+
+func (c *session) state() state {
+	switch {
+	case c.selected:
+		return stateSelected
+	case c.box != nil:
+		return stateAuthenticated
+	}
+	return stateNotAuthenticated
+}
+
+func (c *session) writeLine(line string) {
+	_, _ = c.w.WriteString(line + "\r\n")
+}
+
+func (c *session) writeLines(lines []string) {
+	for _, line := range lines {
+		c.writeLine(line)
+	}
+}
+
+func (c *session) capability(l *lexer) (string, error) {
+	c.writeLine("* CAPABILITY " + capabilities)
+	return "", nil
+}
+
+// noop sends the changes of the mailbox
+func (c *session) noop(l *lexer) (string, error) {
+	if !c.selected {
+		return "", nil
+	}
+	c.s.mu.Lock()
+	lines, err := c.update()
+	c.s.mu.Unlock()
+	c.writeLines(lines)
+	return "", err
+}
+
+func (c *session) logout(l *lexer) (string, error) {
+	c.writeLine("* BYE Logging out")
+	c.done = true
+	return "", nil
+}
+
+// login opens the inbox named after the user, the password is ignored
+func (c *session) login(l *lexer) (string, error) {
+	user, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	if _, err := l.nextString(); err != nil {
+		return "", badError{err.Error()}
+	}
+	return "", c.open(user)
+}
+
+// authenticate supports the PLAIN mechanism, with or without an initial response
+func (c *session) authenticate(l *lexer) (string, error) {
+	mechanism, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		return "", fmt.Errorf(`unsupported authentication mechanism "%s"`, mechanism)
+	}
+	response := ""
+	if !l.done() {
+		if response, err = l.nextString(); err != nil {
+			return "", badError{err.Error()}
+		}
+	} else {
+		c.writeLine("+ ")
+		if err := c.w.Flush(); err != nil {
+			return "", err
+		}
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		response = strings.TrimRight(line, "\r\n")
+	}
+	if response == "*" {
+		return "", badErrorf("authentication cancelled")
+	}
+	data, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return "", badErrorf("invalid authentication response")
+	}
+	fields := strings.Split(string(data), "\x00")
+	if len(fields) != 3 {
+		return "", badErrorf("invalid authentication response")
+	}
+	user := fields[0]
+	if user == "" {
+		user = fields[1]
+	}
+	return "", c.open(user)
+}
+
+func (c *session) open(user string) error {
+	if c.box != nil {
+		return badErrorf("already authenticated")
+	}
+	name := c.s.config.Name(user)
+	if name == "" {
+		return errors.New("invalid user")
+	}
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	box, err := c.s.mailbox(name)
+	if err != nil {
+		return err
+	}
+	c.box = box
+	return nil
+}
+
+func (c *session) selectMailbox(l *lexer) (string, error) {
+	return c.openMailbox(l, false)
+}
+
+func (c *session) examine(l *lexer) (string, error) {
+	return c.openMailbox(l, true)
+}
+
+func (c *session) openMailbox(l *lexer, readOnly bool) (string, error) {
+	name, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	c.selected, c.view = false, nil
+	if !strings.EqualFold(name, "INBOX") {
+		return "", fmt.Errorf(`mailbox "%s" doesn't exist`, name)
+	}
+	c.s.mu.Lock()
+	err = c.box.refresh(c.s.config.Limit, c.s.config.RefreshInterval)
+	lines := []string{}
+	if err == nil {
+		c.selected, c.readOnly = true, readOnly
+		unseen := 0
+		for index, item := range c.box.items {
+			c.view = append(c.view, item.ID)
+			if unseen == 0 && !c.box.seen[item.ID] {
+				unseen = index + 1
+			}
+		}
+		permanentFlags := `\Seen \Deleted`
+		if readOnly {
+			permanentFlags = ""
+		}
+		lines = append(lines,
+			`* FLAGS (\Seen \Deleted)`,
+			fmt.Sprintf("* %d EXISTS", len(c.view)),
+			"* 0 RECENT",
+		)
+		if unseen != 0 {
+			lines = append(lines, fmt.Sprintf("* OK [UNSEEN %d] First unseen message", unseen))
+		}
+		lines = append(lines,
+			fmt.Sprintf("* OK [PERMANENTFLAGS (%s)] Flags permitted", permanentFlags),
+			fmt.Sprintf("* OK [UIDVALIDITY %d] UIDs valid", c.s.uidValidity),
+			fmt.Sprintf("* OK [UIDNEXT %d] Predicted next UID", c.box.uidNext),
+		)
+	}
+	c.s.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	c.writeLines(lines)
+	if readOnly {
+		return "[READ-ONLY]", nil
+	}
+	return "[READ-WRITE]", nil
+}
+
+func (c *session) list(l *lexer) (string, error) {
+	return c.listMailboxes(l, "LIST")
+}
+
+func (c *session) lsub(l *lexer) (string, error) {
+	return c.listMailboxes(l, "LSUB")
+}
+
+// listMailboxes lists the mailboxes, INBOX is the only one
+func (c *session) listMailboxes(l *lexer, name string) (string, error) {
+	reference, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	pattern, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	switch {
+	case pattern == "":
+		c.writeLine(fmt.Sprintf(`* %s (\Noselect) "/" ""`, name))
+	case matchMailbox(reference+pattern, "INBOX"):
+		c.writeLine(fmt.Sprintf(`* %s (\HasNoChildren) "/" INBOX`, name))
+	}
+	return "", nil
+}
+
+// matchMailbox matches a mailbox name against a pattern
+// where "*" and "%" match any sequence of characters
+func matchMailbox(pattern string, name string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '%':
+			expr.WriteString("[^/]*")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(name)
+}
+
+func (c *session) status(l *lexer) (string, error) {
+	name, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	v, err := l.next()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return "", badErrorf("invalid status items")
+	}
+	if !strings.EqualFold(name, "INBOX") {
+		return "", fmt.Errorf(`mailbox "%s" doesn't exist`, name)
+	}
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if err := c.box.refresh(c.s.config.Limit, c.s.config.RefreshInterval); err != nil {
+		return "", err
+	}
+	fields := []string{}
+	for _, item := range items {
+		s, _ := item.(string)
+		var value int
+		switch strings.ToUpper(s) {
+		case "MESSAGES":
+			value = len(c.box.items)
+		case "RECENT":
+		case "UIDNEXT":
+			value = int(c.box.uidNext)
+		case "UIDVALIDITY":
+			value = int(c.s.uidValidity)
+		case "UNSEEN":
+			for _, item := range c.box.items {
+				if !c.box.seen[item.ID] {
+					value++
+				}
+			}
+		default:
+			return "", badErrorf(`unknown status item "%s"`, s)
+		}
+		fields = append(fields, strings.ToUpper(s)+" "+strconv.Itoa(value))
+	}
+	c.writeLine(fmt.Sprintf("* STATUS INBOX (%s)", strings.Join(fields, " ")))
+	return "", nil
+}
+
+// close expunges silently the emails flagged as deleted
+func (c *session) close(l *lexer) (string, error) {
+	var err error
+	if !c.readOnly {
+		c.s.mu.Lock()
+		_, err = c.remove()
+		c.s.mu.Unlock()
+	}
+	c.selected, c.view = false, nil
+	return "", err
+}
+
+func (c *session) unselect(l *lexer) (string, error) {
+	c.selected, c.view = false, nil
+	return "", nil
+}
+
+// expunge deletes from yopmail the emails flagged as deleted
+func (c *session) expunge(l *lexer) (string, error) {
+	if c.readOnly {
+		return "", errMailboxReadOnly
+	}
+	c.s.mu.Lock()
+	lines, err := c.remove()
+	c.s.mu.Unlock()
+	c.writeLines(lines)
+	return "", err
+}
+
+// remove deletes the emails flagged as deleted, the server lock must be held
+func (c *session) remove() ([]string, error) {
+	lines := []string{}
+	for index := len(c.view) - 1; index >= 0; index-- {
+		ID := c.view[index]
+		if !c.box.deleted[ID] {
+			continue
+		}
+		if err := c.box.remove(ID); err != nil {
+			return lines, err
+		}
+		c.view = slices.Delete(c.view, index, index+1)
+		lines = append(lines, fmt.Sprintf("* %d EXPUNGE", index+1))
+	}
+	return lines, nil
+}
+
+// update lists the inbox and returns the changes since the client last
+// got them, the emails gone are expunged and the new ones are appended,
+// the server lock must be held
+func (c *session) update() ([]string, error) {
+	if err := c.box.refresh(c.s.config.Limit, c.s.config.RefreshInterval); err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for index := len(c.view) - 1; index >= 0; index-- {
+		if _, ok := c.box.item(c.view[index]); !ok {
+			c.view = slices.Delete(c.view, index, index+1)
+			lines = append(lines, fmt.Sprintf("* %d EXPUNGE", index+1))
+		}
+	}
+	count := len(c.view)
+	for _, item := range c.box.items {
+		if !slices.Contains(c.view, item.ID) {
+			c.view = append(c.view, item.ID)
+		}
+	}
+	if len(c.view) != count {
+		lines = append(lines, fmt.Sprintf("* %d EXISTS", len(c.view)))
+	}
+	return lines, nil
+}
+
+// uid runs the FETCH, STORE and SEARCH commands with UIDs
+func (c *session) uid(l *lexer) (string, error) {
+	name, err := l.nextString()
+	if err != nil {
+		return "", badError{err.Error()}
+	}
+	switch strings.ToUpper(name) {
+	case "FETCH":
+		return c.fetchMessages(l, true)
+	case "STORE":
+		return c.storeFlags(l, true)
+	case "SEARCH":
+		return c.searchMessages(l, true)
+	case "COPY":
+		return "", errReadOnly
+	}
+	return "", badErrorf(`unknown UID command "%s"`, name)
+}
+
+func (c *session) fetch(l *lexer) (string, error) {
+	return c.fetchMessages(l, false)
+}
+
+func (c *session) store(l *lexer) (string, error) {
+	return c.storeFlags(l, false)
+}
+
+func (c *session) search(l *lexer) (string, error) {
+	return c.searchMessages(l, false)
+}
+
+// targets returns the positions in the view of the emails in the set,
+// the server lock must be held
+func (c *session) targets(set sequenceSet, uid bool) []int {
+	positions := []int{}
+	if !uid {
+		for index := range c.view {
+			if set.contains(uint32(index+1), uint32(len(c.view))) {
+				positions = append(positions, index)
+			}
+		}
+		return positions
+	}
+	largest := uint32(0)
+	for _, ID := range c.view {
+		largest = max(largest, c.box.uids[ID])
+	}
+	for index, ID := range c.view {
+		if set.contains(c.box.uids[ID], largest) {
+			positions = append(positions, index)
+		}
+	}
+	return positions
+}
+
+// sequence reads a sequence set
+func sequence(l *lexer) (sequenceSet, error) {
+	s, err := l.nextString()
+	if err != nil {
+		return nil, badError{err.Error()}
+	}
+	set, err := parseSequenceSet(s)
+	if err != nil {
+		return nil, badError{err.Error()}
+	}
+	return set, nil
+}
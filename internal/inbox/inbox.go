@@ -2,8 +2,10 @@ package inbox
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"text/template"
@@ -15,9 +17,52 @@ import (
 	"github.com/fatih/color"
 )
 
-type Render interface {
-	Coloured() (string, error)
-	JSON() (string, error)
+// Link is a link found in an email
+type Link = mail.Link
+
+// Attachment is a file attached to an email
+type Attachment = mail.Attachment
+
+// Sender is the sender of an email
+type Sender = mail.Sender
+
+// HTMLMail is an email fetched from its HTML page
+type HTMLMail = mail.HTMLMail
+
+// SourceMail is an email fetched from its source
+type SourceMail = mail.SourceMail
+
+// Entity is a MIME entity of an email source
+type Entity = mail.Entity
+
+// ParseEntity builds the tree of the MIME entities of an email source
+func ParseEntity(raw []byte) *Entity {
+	return mail.ParseEntity(raw, "text/plain")
+}
+
+// Mail is a fetched email
+type Mail interface {
+	Content() string
+	GetLinks() []Link
+	GetAttachments() []Attachment
+}
+
+// HTMLPage is a fetched email providing its original HTML rendering
+type HTMLPage interface {
+	Mail
+	HTML() string
+	Document() (string, error)
+	SetHyperlinks(bool)
+	SetWidth(int)
+}
+
+// Source is a fetched email source
+type Source interface {
+	Mail
+	SetRaw(bool)
+	FilterHeaders(include []string, exclude []string)
+	SetHeadersOnly(bool)
+	Message() []byte
 }
 
 const noDataToDisplayMsg = "[no data to display]"
@@ -30,11 +75,6 @@ type Inbox[M client.MailDoc] struct {
 	client     client.Client[M]
 }
 
-type Sender struct {
-	Mail string `json:"mail,omitempty"`
-	Name string `json:"name,omitempty"`
-}
-
 // Inbox represents a mail sumup in an inbox
 type InboxItem struct {
 	ID      string     `json:"id"`
@@ -46,8 +86,8 @@ type InboxItem struct {
 }
 
 // NewInbox creates a new mail inbox
-func NewInbox[M client.MailDoc](name string, enableDebugMode bool) (*Inbox[M], error) {
-	client, err := client.New[M](enableDebugMode)
+func NewInbox[M client.MailDoc](name string, enableDebugMode bool, opts ...client.Option) (*Inbox[M], error) {
+	client, err := client.New[M](enableDebugMode, opts...)
 	return &Inbox[M]{
 		client:     client,
 		Name:       name,
@@ -57,9 +97,14 @@ func NewInbox[M client.MailDoc](name string, enableDebugMode bool) (*Inbox[M], e
 
 // Fetch retrieves the full email content from the given
 // inbox email offset
-func (i *Inbox[M]) Fetch(offset int) (Render, error) {
-	ID := &i.InboxItems[offset].ID
-	doc, err := i.client.GetMailPage(i.Name, *ID)
+func (i *Inbox[M]) Fetch(offset int) (Mail, error) {
+	return i.FetchByID(i.InboxItems[offset].ID)
+}
+
+// FetchByID retrieves the full email content of the given
+// email ID, the email doesn't need to be in the parsed pages
+func (i *Inbox[M]) FetchByID(ID string) (Mail, error) {
+	doc, err := i.client.GetMailPage(i.Name, ID)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +112,7 @@ func (i *Inbox[M]) Fetch(offset int) (Render, error) {
 	if err != nil {
 		return nil, err
 	}
-	m.SetID(*ID)
+	m.SetID(ID)
 	return m, nil
 }
 
@@ -101,6 +146,16 @@ func (i *Inbox[M]) Delete(position int) error {
 	return nil
 }
 
+// DeleteByID removes the email matching the given ID
+func (i *Inbox[M]) DeleteByID(ID string) error {
+	for position, mail := range i.InboxItems {
+		if mail.ID == ID {
+			return i.Delete(position)
+		}
+	}
+	return fmt.Errorf(`email "%s" not found`, ID)
+}
+
 // Flush empties an inbox
 func (i *Inbox[M]) Flush() error {
 	if len(i.InboxItems) == 0 {
@@ -201,6 +256,36 @@ func (i *Inbox[M]) JSON() (string, error) {
 	return s, nil
 }
 
+// Items returns the inbox emails one by one
+func (i *Inbox[M]) Items() []any {
+	items := make([]any, 0, i.Count())
+	for _, item := range i.GetMails() {
+		items = append(items, item)
+	}
+	return items
+}
+
+// Header returns the columns of the inbox emails
+func (i *Inbox[M]) Header() []string {
+	return []string{"offset", "id", "sender_name", "sender_mail", "subject", "date", "spam"}
+}
+
+// Rows returns a row per inbox email
+func (i *Inbox[M]) Rows() [][]string {
+	rows := [][]string{}
+	for index, item := range i.GetMails() {
+		var name, mail, date string
+		if item.Sender != nil {
+			name, mail = item.Sender.Name, item.Sender.Mail
+		}
+		if item.Date != nil {
+			date = item.Date.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{strconv.Itoa(index + 1), item.ID, name, mail, item.Subject, date, strconv.FormatBool(item.IsSPAM)})
+	}
+	return rows
+}
+
 // ParseInboxPages parses inbox email in given page
 func (i *Inbox[M]) ParseInboxPages(limit int) error {
 	for page := 1; page <= (limit/itemNumber)+1 && limit >= i.Count(); page++ {
@@ -218,6 +303,12 @@ func (i *Inbox[M]) ParseInboxPages(limit int) error {
 	return nil
 }
 
+// Refresh discards the parsed emails and parses the inbox pages again
+func (i *Inbox[M]) Refresh(limit int) error {
+	i.InboxItems = []InboxItem{}
+	return i.ParseInboxPages(limit)
+}
+
 // ParseInboxPage parses inbox email in given page
 func parseInboxPage[M client.MailDoc](doc *goquery.Document, inbox *Inbox[M]) {
 	doc.Find("div.m").Each(func(i int, s *goquery.Selection) {
@@ -244,6 +335,7 @@ func parseInboxPage[M client.MailDoc](doc *goquery.Document, inbox *Inbox[M]) {
 					Mail: userEmail,
 				},
 				Subject: s.Find("div.lms").Text(),
+				Date:    parseDateFromID(ID),
 				IsSPAM:  isSPAM,
 			}
 
@@ -251,3 +343,34 @@ func parseInboxPage[M client.MailDoc](doc *goquery.Document, inbox *Inbox[M]) {
 		}
 	})
 }
+
+// parseDateFromID extracts the reception date from an email ID,
+// the part following the "e_" prefix is a rot13 obfuscated base64
+// string starting with a UTC timestamp formatted as YYMMDDhhmmss,
+// the inbox page only lists the hour of the emails in the Paris
+// time and the day relatively to today so the ID is used instead
+func parseDateFromID(ID string) *time.Time {
+	_, encoded, ok := strings.Cut(ID, "_")
+	if !ok {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Map(rot13, encoded))
+	if err != nil || len(data) < 12 {
+		return nil
+	}
+	date, err := time.Parse("060102150405", string(data[:12]))
+	if err != nil {
+		return nil
+	}
+	return &date
+}
+
+func rot13(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 'a' + (r-'a'+13)%26
+	case r >= 'A' && r <= 'Z':
+		return 'A' + (r-'A'+13)%26
+	}
+	return r
+}
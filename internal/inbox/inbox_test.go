@@ -1,13 +1,18 @@
 package inbox
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"testing"
+	"time"
+	_ "time/tzdata"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/antham/yogo/v4/internal/client"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFetch(t *testing.T) {
@@ -49,9 +54,42 @@ func TestFetch(t *testing.T) {
 
 	m, err := inbox.Fetch(0)
 	assert.NoError(t, err)
-	j, err := m.JSON()
+	j, err := json.Marshal(m)
 	assert.NoError(t, err)
-	assert.Contains(t, j, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+	assert.Contains(t, string(j), "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+}
+
+func TestFetchByID(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert.NoError(t, registerResponders([]responder{
+		{
+			"GET",
+			"https://yopmail.com/en/mail?b=test&id=me_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D",
+			"features/mail.html",
+		},
+		{
+			"GET",
+			"https://yopmail.com",
+			"features/main_page.html",
+		},
+		{
+			"GET",
+			"https://yopmail.com/ver/4.8/webmail.js",
+			"features/webmail.js",
+		},
+	}))
+
+	inbox, err := NewInbox[client.MailHTMLDoc]("test", false)
+	assert.NoError(t, err)
+
+	m, err := inbox.FetchByID("e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, inbox.Count())
+	j, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.Contains(t, string(j), "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
 }
 
 func TestFetchWithDebug(t *testing.T) {
@@ -93,9 +131,9 @@ func TestFetchWithDebug(t *testing.T) {
 
 	m, err := inbox.Fetch(0)
 	assert.NoError(t, err)
-	j, err := m.JSON()
+	j, err := json.Marshal(m)
 	assert.NoError(t, err)
-	assert.Contains(t, j, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+	assert.Contains(t, string(j), "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
 }
 
 func TestCount(t *testing.T) {
@@ -194,24 +232,55 @@ func TestParseInboxPages(t *testing.T) {
 	assert.Equal(t, 29, inbox.Count())
 	m, err := inbox.Fetch(0)
 	assert.NoError(t, err)
-	j, err := m.JSON()
+	j, err := json.Marshal(m)
 	assert.NoError(t, err)
-	assert.Contains(t, j, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+	assert.Contains(t, string(j), "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
 	m, err = inbox.Fetch(28)
 	assert.NoError(t, err)
-	j, err = m.JSON()
+	j, err = json.Marshal(m)
 	assert.NoError(t, err)
-	assert.Contains(t, j, "e_ZwRjAwRmZGtmZQR0ZQNjAwt2BGV5BN==")
+	assert.Contains(t, string(j), "e_ZwRjAwRmZGtmZQR0ZQNjAwt2BGV5BN==")
 	m, err = inbox.Fetch(13)
 	assert.NoError(t, err)
-	j, err = m.JSON()
+	j, err = json.Marshal(m)
 	assert.NoError(t, err)
-	assert.Contains(t, j, "e_ZwRjAwRmZGtmZwR0ZQNjAwt3AmxlZN==")
+	assert.Contains(t, string(j), "e_ZwRjAwRmZGtmZwR0ZQNjAwt3AmxlZN==")
 	m, err = inbox.Fetch(14)
 	assert.NoError(t, err)
-	j, err = m.JSON()
+	j, err = json.Marshal(m)
 	assert.NoError(t, err)
-	assert.Contains(t, j, "e_ZwRjAwRmZGtmZwN3ZQNjAwt3AmZlAD==")
+	assert.Contains(t, string(j), "e_ZwRjAwRmZGtmZwN3ZQNjAwt3AmZlAD==")
+}
+
+func TestRefresh(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert.NoError(t, registerResponders([]responder{
+		{
+			"GET",
+			"https://yopmail.com/en/inbox?ad=0&ctrl=&d=&id=&login=test&p=1&r_c=&scrl=&spam=true&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp",
+			"features/inbox_page_1.html",
+		},
+		{
+			"GET",
+			"https://yopmail.com",
+			"features/main_page.html",
+		},
+		{
+			"GET",
+			"https://yopmail.com/ver/4.8/webmail.js",
+			"features/webmail.js",
+		},
+	}))
+
+	inbox, err := NewInbox[client.MailHTMLDoc]("test", false)
+	assert.NoError(t, err)
+
+	assert.NoError(t, inbox.ParseInboxPages(10))
+	assert.NoError(t, inbox.Refresh(10))
+	assert.Equal(t, 10, inbox.Count())
+	assert.Equal(t, 2, httpmock.GetCallCountInfo()["GET https://yopmail.com/en/inbox?ad=0&ctrl=&d=&id=&login=test&p=1&r_c=&scrl=&spam=true&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp"])
 }
 
 func TestShrink(t *testing.T) {
@@ -265,7 +334,7 @@ func TestShrink(t *testing.T) {
 	assert.Equal(t, 19, inbox.Count())
 	m, err := inbox.Fetch(0)
 	assert.NoError(t, err)
-	_, err = m.JSON()
+	_, err = json.Marshal(m)
 	assert.NoError(t, err)
 	_, err = inbox.Fetch(18)
 	assert.NoError(t, err)
@@ -651,6 +720,114 @@ func TestColoured(t *testing.T) {
 	}
 }
 
+func TestRows(t *testing.T) {
+	date := time.Date(2023, 8, 13, 22, 45, 9, 0, time.UTC)
+	inbox := Inbox[client.MailHTMLDoc]{
+		Name: "test",
+		InboxItems: []InboxItem{
+			{ID: "abcdefg", Sender: &Sender{Name: "name", Mail: "test@yopmail.com"}, Subject: "subject", Date: &date},
+			{ID: "hijklmn", IsSPAM: true},
+		},
+	}
+
+	assert.Equal(t, []any{inbox.InboxItems[0], inbox.InboxItems[1]}, inbox.Items())
+	assert.Equal(t, []string{"offset", "id", "sender_name", "sender_mail", "subject", "date", "spam"}, inbox.Header())
+	assert.Equal(t, [][]string{
+		{"1", "abcdefg", "name", "test@yopmail.com", "subject", "2023-08-13T22:45:09Z", "false"},
+		{"2", "hijklmn", "", "", "", "", "true"},
+	}, inbox.Rows())
+}
+
+func TestDeleteByID(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	assert.NoError(t, registerResponders([]responder{
+		{
+			"GET",
+			"https://yopmail.com/en/inbox?ad=0&ctrl=&d=&id=&login=test&p=1&r_c=&scrl=&spam=true&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp",
+			"features/inbox_page_1.html",
+		},
+		{
+			"GET",
+			"https://yopmail.com",
+			"features/main_page.html",
+		},
+		{
+			"GET",
+			"https://yopmail.com/ver/4.8/webmail.js",
+			"features/webmail.js",
+		},
+		{
+			"GET",
+			"https://yopmail.com/en/inbox?ad=0&ctrl=&d=e_ZwRjAwRmZGtmAwN1ZQNjAwt5ZwZ4AN%3D%3D&id=&login=test&p=1&r_c=&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp",
+			"features/noop.html",
+		},
+	}))
+
+	inbox, err := NewInbox[client.MailHTMLDoc]("test", false)
+	assert.NoError(t, err)
+
+	assert.NoError(t, inbox.ParseInboxPages(2))
+	assert.NoError(t, inbox.DeleteByID("e_ZwRjAwRmZGtmAwN1ZQNjAwt5ZwZ4AN=="))
+	assert.Equal(t, 1, inbox.Count())
+	assert.Equal(t, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==", inbox.GetMails()[0].ID)
+	assert.EqualError(t, inbox.DeleteByID("e_unknown"), `email "e_unknown" not found`)
+}
+
+func TestParseDateFromID(t *testing.T) {
+	type scenario struct {
+		name         string
+		ID           string
+		dateExpected *time.Time
+	}
+
+	date := time.Date(2021, time.June, 13, 18, 36, 35, 0, time.UTC)
+	scenarios := []scenario{
+		{
+			name:         "Regular ID",
+			ID:           "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==",
+			dateExpected: &date,
+		},
+		{
+			name: "ID without prefix",
+			ID:   "ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==",
+		},
+		{
+			name: "ID not encoded",
+			ID:   "e_02d3583b-7b58-40cb-a2b7-c09d79673334",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, scenario.dateExpected, parseDateFromID(scenario.ID))
+		})
+	}
+}
+
+func TestParseDateFromIDTimezone(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	for _, filename := range []string{"features/inbox_page.html", "features/noop.html"} {
+		f, err := os.Open(filename)
+		require.NoError(t, err)
+		doc, err := goquery.NewDocumentFromReader(f)
+		require.NoError(t, f.Close())
+		require.NoError(t, err)
+		doc.Find("div.m").Each(func(i int, s *goquery.Selection) {
+			ID, _ := s.Attr("id")
+			date := parseDateFromID(ID)
+			require.NotNil(t, date)
+			assert.Equal(t, time.UTC, date.Location())
+			// the hour listed by yopmail is in the Paris time
+			assert.Equal(t, s.Find("span.lmh").Text(), date.In(paris).Format("15:04"), ID)
+		})
+	}
+}
+
 type responder struct {
 	method   string
 	URL      string
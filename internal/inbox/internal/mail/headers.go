@@ -0,0 +1,174 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	gomail "net/mail"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// addressHeaders are the headers parsed as address lists
+var addressHeaders = []string{"From", "To", "Cc", "Reply-To"}
+
+// wordDecoder decodes RFC 2047 encoded words, the charsets
+// not supported by default (e.g. Windows-1252) are handled too
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// Address defines a mail address
+type Address struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+}
+
+// Header defines a mail header field
+type Header struct {
+	Key   string
+	Value string
+}
+
+// Headers are the mail header fields in the order they were received
+type Headers []Header
+
+// Values returns all the values of a header in the order they were received
+func (h Headers) Values(key string) []string {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	values := []string{}
+	for _, header := range h {
+		if header.Key == key {
+			values = append(values, header.Value)
+		}
+	}
+	return values
+}
+
+// Filter keeps the headers defined in include, all of them when include is
+// empty, and removes the ones defined in exclude, keys are case insensitive
+func (h Headers) Filter(include []string, exclude []string) Headers {
+	if len(include) == 0 && len(exclude) == 0 {
+		return h
+	}
+	contains := func(keys []string, key string) bool {
+		for _, k := range keys {
+			if strings.EqualFold(strings.TrimSpace(k), key) {
+				return true
+			}
+		}
+		return false
+	}
+	headers := Headers{}
+	for _, header := range h {
+		if len(include) > 0 && !contains(include, header.Key) {
+			continue
+		}
+		if contains(exclude, header.Key) {
+			continue
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// MarshalJSON renders the headers as an object mapping every key to its
+// values, the keys are ordered by their first occurrence
+func (h Headers) MarshalJSON() ([]byte, error) {
+	keys := []string{}
+	values := map[string][]string{}
+	for _, header := range h {
+		if _, ok := values[header.Key]; !ok {
+			keys = append(keys, header.Key)
+		}
+		values[header.Key] = append(values[header.Key], header.Value)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (h Headers) mimeHeader() textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	for _, v := range h {
+		header.Add(v.Key, v.Value)
+	}
+	return header
+}
+
+// readHeaders reads the header block of a message keeping the fields order,
+// folded values are unfolded the same way net/mail does
+func readHeaders(r *textproto.Reader) (Headers, error) {
+	headers := Headers{}
+	for {
+		line, err := r.ReadContinuedLine()
+		if err == io.EOF && len(headers) > 0 {
+			return headers, nil
+		}
+		if err != nil {
+			return headers, err
+		}
+		if line == "" {
+			return headers, nil
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return headers, textproto.ProtocolError("malformed header line: " + line)
+		}
+		headers = append(headers, Header{
+			Key:   textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key)),
+			Value: strings.TrimSpace(value),
+		})
+	}
+}
+
+// decodeHeaders decodes the RFC 2047 encoded header
+// values, values failing to decode are kept as is
+func decodeHeaders(headers Headers) Headers {
+	decoded := make(Headers, len(headers))
+	for i, header := range headers {
+		s, err := wordDecoder.DecodeHeader(header.Value)
+		if err != nil {
+			s = header.Value
+		}
+		decoded[i] = Header{Key: header.Key, Value: s}
+	}
+	return decoded
+}
+
+// parseAddresses parses the address headers, the
+// values that are not valid address lists are skipped
+func parseAddresses(headers Headers) map[string][]Address {
+	parser := gomail.AddressParser{WordDecoder: wordDecoder}
+	addresses := map[string][]Address{}
+	for _, k := range addressHeaders {
+		for _, v := range headers.Values(k) {
+			list, err := parser.ParseList(v)
+			if err != nil {
+				continue
+			}
+			for _, a := range list {
+				addresses[k] = append(addresses[k], Address{Name: a.Name, Address: a.Address})
+			}
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"bufio"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadHeaders(t *testing.T) {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader("Received: from b\r\nSubject: a folded\r\n  subject\r\nreceived: from a\r\nMESSAGE-ID: <1@acme.com>\r\n\r\nbody")))
+	headers, err := readHeaders(r)
+	assert.NoError(t, err)
+	assert.Equal(t, Headers{
+		{Key: "Received", Value: "from b"},
+		{Key: "Subject", Value: "a folded subject"},
+		{Key: "Received", Value: "from a"},
+		{Key: "Message-Id", Value: "<1@acme.com>"},
+	}, headers)
+	line, err := r.ReadLine()
+	assert.NoError(t, err)
+	assert.Equal(t, "body", line)
+
+	headers, err = readHeaders(textproto.NewReader(bufio.NewReader(strings.NewReader("Subject: no body"))))
+	assert.NoError(t, err)
+	assert.Equal(t, Headers{{Key: "Subject", Value: "no body"}}, headers)
+
+	_, err = readHeaders(textproto.NewReader(bufio.NewReader(strings.NewReader("not a header\r\n\r\n"))))
+	assert.EqualError(t, err, "malformed header line: not a header")
+
+	_, err = readHeaders(textproto.NewReader(bufio.NewReader(strings.NewReader(""))))
+	assert.Error(t, err)
+}
+
+func TestHeaders(t *testing.T) {
+	headers := Headers{
+		{Key: "Received", Value: "from b"},
+		{Key: "Subject", Value: "test"},
+		{Key: "Received", Value: "from a"},
+		{Key: "Dkim-Signature", Value: "v=1"},
+	}
+
+	assert.Equal(t, []string{"from b", "from a"}, headers.Values("received"))
+	assert.Equal(t, []string{}, headers.Values("From"))
+
+	assert.Equal(t, headers, headers.Filter(nil, nil))
+	assert.Equal(t, Headers{
+		{Key: "Subject", Value: "test"},
+		{Key: "Dkim-Signature", Value: "v=1"},
+	}, headers.Filter([]string{"DKIM-Signature", "subject"}, nil))
+	assert.Equal(t, Headers{
+		{Key: "Subject", Value: "test"},
+		{Key: "Dkim-Signature", Value: "v=1"},
+	}, headers.Filter(nil, []string{"Received"}))
+	assert.Equal(t, Headers{
+		{Key: "Subject", Value: "test"},
+	}, headers.Filter([]string{"Subject", "Dkim-Signature"}, []string{"dkim-signature"}))
+
+	data, err := headers.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Received":["from b","from a"],"Subject":["test"],"Dkim-Signature":["v=1"]}`, string(data))
+	data, err = Headers{}.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(data))
+}
+
+func TestDecodeHeaders(t *testing.T) {
+	assert.Equal(t, Headers{
+		{Key: "Subject", Value: "Marcación de un punto"},
+		{Key: "X-Charset", Value: "café"},
+		{Key: "X-Plain", Value: "plain"},
+		{Key: "X-Plain", Value: "values"},
+		{Key: "X-Broken", Value: "=?utf-8?X?broken?="},
+	}, decodeHeaders(Headers{
+		{Key: "Subject", Value: "=?utf-8?Q?Marcaci=C3=B3n?= de un punto"},
+		{Key: "X-Charset", Value: "=?windows-1252?Q?caf=E9?="},
+		{Key: "X-Plain", Value: "plain"},
+		{Key: "X-Plain", Value: "values"},
+		{Key: "X-Broken", Value: "=?utf-8?X?broken?="},
+	}))
+}
+
+func TestParseAddresses(t *testing.T) {
+	assert.Equal(t, map[string][]Address{
+		"From":     {{Name: "Ola no-reply", Address: "aplicativos@notificacionesatlas.com"}},
+		"To":       {{Address: "test@yopmail.com"}, {Name: "José", Address: "jose@yopmail.com"}},
+		"Reply-To": {{Name: "Support", Address: "support@acme.com"}},
+	}, parseAddresses(Headers{
+		{Key: "From", Value: "Ola no-reply <aplicativos@notificacionesatlas.com>"},
+		{Key: "To", Value: "test@yopmail.com, =?utf-8?B?Sm9zw6k=?= <jose@yopmail.com>"},
+		{Key: "Cc", Value: "not an address"},
+		{Key: "Reply-To", Value: `"Support" <support@acme.com>`},
+		{Key: "Subject", Value: "test@yopmail.com"},
+	}))
+}
@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	htmltemplate "html/template"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/fatih/color"
 	"github.com/jaytaylor/html2text"
+	"golang.org/x/term"
 )
 
 // Sender defines a mail sender
@@ -19,20 +24,148 @@ type Sender struct {
 	Name string `json:"name,omitempty"`
 }
 
+// String renders a sender the way a mail client would,
+// a nil sender is rendered as an empty string
+func (s *Sender) String() string {
+	switch {
+	case s == nil:
+		return ""
+	case s.Name != "" && s.Mail != "":
+		return fmt.Sprintf("%s <%s>", s.Name, s.Mail)
+	case s.Name != "":
+		return s.Name
+	}
+	return s.Mail
+}
+
+// Link defines a link found in a mail
+type Link struct {
+	URL  string `json:"url"`
+	Text string `json:"text,omitempty"`
+}
+
 // HTMLMail is an HTML mail message
 type HTMLMail struct {
-	ID      string     `json:"id"`
-	Sender  *Sender    `json:"sender,omitempty"`
-	Subject string     `json:"subject,omitempty"`
-	Date    *time.Time `json:"date,omitempty"`
-	Body    string     `json:"body,omitempty"`
-	IsSPAM  bool       `json:"isSPAM"`
+	ID         string     `json:"id"`
+	Sender     *Sender    `json:"sender,omitempty"`
+	Subject    string     `json:"subject,omitempty"`
+	Date       *time.Time `json:"date,omitempty"`
+	Body       string     `json:"body,omitempty"`
+	Links      []Link     `json:"links,omitempty"`
+	IsSPAM     bool       `json:"isSPAM"`
+	html       string
+	hyperlinks bool
+	width      int
 }
 
 func (m *HTMLMail) SetID(ID string) {
 	m.ID = ID
 }
 
+// Content returns the email body converted to text
+func (m *HTMLMail) Content() string {
+	return m.Body
+}
+
+// GetLinks returns the links found in the mail
+func (m *HTMLMail) GetLinks() []Link {
+	return m.Links
+}
+
+// SetHyperlinks toggles the rendering of the links as terminal
+// hyperlinks (OSC 8) instead of numbered footnotes
+func (m *HTMLMail) SetHyperlinks(hyperlinks bool) {
+	m.hyperlinks = hyperlinks
+}
+
+// SetWidth defines the width the body is wrapped
+// to, the width of the terminal is used by default
+func (m *HTMLMail) SetWidth(width int) {
+	m.width = width
+}
+
+func (m *HTMLMail) body() string {
+	if m.html == "" {
+		return m.Body
+	}
+	width := m.width
+	if width <= 0 {
+		var err error
+		if width, _, err = term.GetSize(0); err != nil {
+			width = 80
+		}
+	}
+	body, err := renderHTML(m.html, width, m.hyperlinks)
+	if err != nil || body == "" {
+		return m.Body
+	}
+	return body
+}
+
+// HTML returns the mail body as the HTML fragment sent by yopmail
+func (m *HTMLMail) HTML() string {
+	return m.html
+}
+
+// Document renders a standalone HTML page, the body is preceded by a
+// block with the main mail headers, the scripts and the remote
+// resources of the body are blocked as it is opened from the disk
+func (m *HTMLMail) Document() (string, error) {
+	info := struct {
+		From    string
+		Subject string
+		Date    string
+		Body    htmltemplate.HTML
+	}{
+		From:    noDataToDisplayMsg,
+		Subject: noDataToDisplayMsg,
+		Date:    noDataToDisplayMsg,
+		// the fragment is rendered as received, the content security
+		// policy prevents it from running code or tracking the reader
+		Body: htmltemplate.HTML(m.html),
+	}
+	if from := m.Sender.String(); from != "" {
+		info.From = from
+	}
+	if m.Subject != "" {
+		info.Subject = m.Subject
+	}
+	if m.Date != nil {
+		info.Date = m.Date.Format("2006-01-02 15:04")
+	}
+
+	var buf bytes.Buffer
+	tpl := htmltemplate.Must(htmltemplate.New("t").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="Content-Security-Policy" content="default-src 'none'; img-src data:; style-src 'unsafe-inline'; font-src data:">
+<title>{{ .Subject }}</title>
+<style>
+table.headers { font-family: sans-serif; font-size: 14px; border-bottom: 1px solid #ccc; margin-bottom: 16px; padding-bottom: 8px; }
+table.headers th { text-align: left; padding-right: 16px; }
+</style>
+</head>
+<body>
+<table class="headers">
+<tr><th>From</th><td>{{ .From }}</td></tr>
+<tr><th>Subject</th><td>{{ .Subject }}</td></tr>
+<tr><th>Date</th><td>{{ .Date }}</td></tr>
+</table>
+<div id="mail">{{ .Body }}</div>
+</body>
+</html>
+`))
+	err := tpl.Execute(&buf, info)
+	return buf.String(), err
+}
+
+// GetAttachments returns the files attached to the mail, they
+// are only available from the mail source
+func (m *HTMLMail) GetAttachments() []Attachment {
+	return []Attachment{}
+}
+
 func (m *HTMLMail) Coloured() (string, error) {
 	info := struct {
 		HasSenderName bool
@@ -72,8 +205,8 @@ func (m *HTMLMail) Coloured() (string, error) {
 	} else {
 		info.Date = color.GreenString(noDataToDisplayMsg)
 	}
-	if m.Body != "" {
-		info.Body = color.CyanString(m.Body)
+	if body := m.body(); body != "" {
+		info.Body = color.CyanString(body)
 	} else {
 		info.Body = color.CyanString(noDataToDisplayMsg)
 	}
@@ -100,6 +233,31 @@ Date    : {{.Date}}
 	return buf.String(), err
 }
 
+// Header returns the columns of the mail fields
+func (m *HTMLMail) Header() []string {
+	return []string{"field", "value"}
+}
+
+// Rows returns a row per mail field
+func (m *HTMLMail) Rows() [][]string {
+	var name, mail, date string
+	if m.Sender != nil {
+		name, mail = m.Sender.Name, m.Sender.Mail
+	}
+	if m.Date != nil {
+		date = m.Date.Format(time.RFC3339)
+	}
+	return [][]string{
+		{"id", m.ID},
+		{"sender_name", name},
+		{"sender_mail", mail},
+		{"subject", m.Subject},
+		{"date", date},
+		{"spam", strconv.FormatBool(m.IsSPAM)},
+		{"body", m.Body},
+	}
+}
+
 func (m *HTMLMail) JSON() (string, error) {
 	data, err := json.Marshal(&m)
 	if err != nil {
@@ -130,6 +288,23 @@ func parseDate(s string) *time.Time {
 	return &date
 }
 
+func parseLinks(s *goquery.Selection) []Link {
+	links := []Link{}
+	seen := map[Link]bool{}
+	s.Each(func(i int, s *goquery.Selection) {
+		URL := strings.TrimSpace(s.AttrOr("href", ""))
+		if URL == "" || strings.HasPrefix(URL, "#") {
+			return
+		}
+		link := Link{URL: URL, Text: strings.Join(strings.Fields(s.Text()), " ")}
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	})
+	return links
+}
+
 func parseHTML(content string, err error) string {
 	if err != nil {
 		return ""
@@ -125,10 +125,46 @@ Date    : 2022-10-24 23:20
 			assert.NoError(t, err)
 			assert.Equal(t, scenario.outputExpected, c)
 			assert.JSONEq(t, scenario.jsonOutputExpected, j)
+			assert.Equal(t, scenario.mail.Body, scenario.mail.Content())
 		})
 	}
 }
 
+func TestHTMLMailDocument(t *testing.T) {
+	date, err := time.Parse("2006-01-02 15:04", "2022-10-24 23:20")
+	assert.NoError(t, err)
+
+	mail := &HTMLMail{ID: "test", Sender: &Sender{Name: "test", Mail: "test@protonmail.com"}, Subject: "A <subject>", Date: &date, html: `<p>Hello <a href="https://example.com">world</a></p>`}
+	assert.Equal(t, `<p>Hello <a href="https://example.com">world</a></p>`, mail.HTML())
+	doc, err := mail.Document()
+	assert.NoError(t, err)
+	assert.Contains(t, doc, `<meta http-equiv="Content-Security-Policy" content="default-src 'none'; img-src data:; style-src 'unsafe-inline'; font-src data:">`)
+	assert.Contains(t, doc, "<title>A &lt;subject&gt;</title>")
+	assert.Contains(t, doc, "<tr><th>From</th><td>test &lt;test@protonmail.com&gt;</td></tr>")
+	assert.Contains(t, doc, "<tr><th>Date</th><td>2022-10-24 23:20</td></tr>")
+	assert.Contains(t, doc, `<div id="mail"><p>Hello <a href="https://example.com">world</a></p></div>`)
+
+	doc, err = (&HTMLMail{}).Document()
+	assert.NoError(t, err)
+	assert.Contains(t, doc, "<tr><th>From</th><td>[no data to display]</td></tr>")
+	assert.Contains(t, doc, "<tr><th>Subject</th><td>[no data to display]</td></tr>")
+}
+
+func TestHTMLMailRows(t *testing.T) {
+	date := time.Date(2022, 10, 24, 23, 20, 0, 0, time.UTC)
+	mail := &HTMLMail{ID: "test", Sender: &Sender{Name: "test", Mail: "test@protonmail.com"}, Subject: "A subject", Date: &date, Body: "test"}
+	assert.Equal(t, []string{"field", "value"}, mail.Header())
+	assert.Equal(t, [][]string{
+		{"id", "test"},
+		{"sender_name", "test"},
+		{"sender_mail", "test@protonmail.com"},
+		{"subject", "A subject"},
+		{"date", "2022-10-24T23:20:00Z"},
+		{"spam", "false"},
+		{"body", "test"},
+	}, mail.Rows())
+}
+
 func TestParseFrom(t *testing.T) {
 	type scenario struct {
 		name        string
@@ -216,3 +252,18 @@ func TestParseHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestHTMLMailSetWidth(t *testing.T) {
+	t.Parallel()
+	m := &HTMLMail{Body: "fallback", html: "<p>first second third</p>"}
+	m.SetWidth(12)
+	assert.Equal(t, "first second\nthird", m.body())
+}
+
+func TestSenderString(t *testing.T) {
+	var sender *Sender
+	assert.Equal(t, "", sender.String())
+	assert.Equal(t, "name", (&Sender{Name: "name"}).String())
+	assert.Equal(t, "test@yopmail.com", (&Sender{Mail: "test@yopmail.com"}).String())
+	assert.Equal(t, "name <test@yopmail.com>", (&Sender{Name: "name", Mail: "test@yopmail.com"}).String())
+}
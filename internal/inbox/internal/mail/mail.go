@@ -1,11 +1,14 @@
 package mail
 
 import (
-	"github.com/PuerkitoBio/goquery"
-	"github.com/antham/yogo/v4/internal/client"
+	"bufio"
+	"bytes"
 	"io"
-	gomail "net/mail"
+	"net/textproto"
 	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antham/yogo/v4/internal/client"
 )
 
 const noDataToDisplayMsg = "[no data to display]"
@@ -14,51 +17,83 @@ type Identifier interface {
 	SetID(string)
 }
 
-type Render interface {
-	Coloured() (string, error)
-	JSON() (string, error)
+type Contenter interface {
+	Content() string
+	GetLinks() []Link
+	GetAttachments() []Attachment
 }
 
-type RenderIdentifier interface {
+type Mail interface {
 	Identifier
-	Render
+	Contenter
 }
 
-func Parse[M client.MailDoc](doc M) (RenderIdentifier, error) {
-	var m RenderIdentifier
+func Parse[M client.MailDoc](doc M) (Mail, error) {
+	var m Mail
 	switch any(doc).(type) {
 	case client.MailHTMLDoc:
-		mail := &HTMLMail{}
-		doc.Find("body div.fl .ellipsis").Each(func(i int, s *goquery.Selection) {
-			switch i {
-			case 0:
-				mail.Subject = strings.TrimSpace(s.Text())
-			case 1:
-				mail.Sender = &Sender{}
-				mail.Sender.Name, mail.Sender.Mail = parseFrom(s.Text())
-			case 2:
-				mail.Date = parseDate(strings.Join(strings.Fields(s.Text()), " "))
-			}
-		})
-		mail.Body = parseHTML(doc.Find("div#mail").Html())
+		mail := parseSummary(doc.Find("body div.fl .ellipsis"))
+		fragment, err := doc.Find("div#mail").Html()
+		mail.Body = parseHTML(fragment, err)
+		mail.html = strings.TrimSpace(fragment)
+		mail.Links = parseLinks(doc.Find("div#mail a[href]"))
+		m = mail
+	case client.MailTextDoc:
+		// the body is converted to text by yopmail, there is no HTML to render
+		mail := parseSummary(doc.Find("body div.fl .ellipsis"))
+		mail.Body = strings.TrimSpace(doc.Find("div#mail").Text())
+		mail.Links = parseLinks(doc.Find("div#mail a[href]"))
 		m = mail
 	case client.MailSourceDoc:
-		msg, err := gomail.ReadMessage(
-			strings.NewReader(
-				doc.Find("body div#mail pre").Text(),
+		message := doc.Find("body div#mail pre").Text()
+		r := textproto.NewReader(
+			bufio.NewReader(
+				strings.NewReader(message),
 			),
 		)
+		headers, err := readHeaders(r)
 		if err != nil {
 			return m, err
 		}
-		body, err := io.ReadAll(msg.Body)
+		body, err := io.ReadAll(r.R)
 		if err != nil {
 			return m, err
 		}
+		// a malformed MIME structure must not prevent the source from
+		// being displayed, what was decoded until the failure is kept
+		structure, err := parsePart(headers.mimeHeader(), bytes.NewReader(body))
+		text := structure.text()
+		if err != nil && text == "" {
+			text = string(body)
+		}
 		m = &SourceMail{
-			Headers: msg.Header,
-			Body:    string(body),
+			Headers:     decodeHeaders(headers),
+			RawHeaders:  headers,
+			Addresses:   parseAddresses(headers),
+			Body:        text,
+			RawBody:     string(body),
+			Structure:   &structure,
+			Attachments: structure.attachments(),
+			message:     message,
 		}
 	}
 	return m, nil
 }
+
+// parseSummary reads the subject, the sender and the date
+// displayed above the body of the HTML and the text pages
+func parseSummary(s *goquery.Selection) *HTMLMail {
+	mail := &HTMLMail{}
+	s.Each(func(i int, s *goquery.Selection) {
+		switch i {
+		case 0:
+			mail.Subject = strings.TrimSpace(s.Text())
+		case 1:
+			mail.Sender = &Sender{}
+			mail.Sender.Name, mail.Sender.Mail = parseFrom(s.Text())
+		case 2:
+			mail.Date = parseDate(strings.Join(strings.Fields(s.Text()), " "))
+		}
+	})
+	return mail
+}
@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/antham/yogo/v4/internal/client"
@@ -11,39 +12,69 @@ func TestParse(t *testing.T) {
 	mail, err := Parse[client.MailHTMLDoc](getDoc[client.MailHTMLDoc](t, "html_mail.html"))
 	assert.NoError(t, err)
 
-	content, err := mail.Coloured()
+	content, err := mail.(*HTMLMail).Coloured()
 	assert.NoError(t, err)
 	assert.Equal(t, `---
 From    : Liana <AnnaMartinezpisea@lionspest.com.au>
 Subject : In any case, I am happy that we met
 Date    : 2021-06-13 20:57
 ---
-( https://fectment.page.link/Ymry )
+show photo [1]
 
 What such a gorgeous man is doing here?
 
-*s ho Dent blink scorn league rose ivy superman atkins atkins mugsy freeze thorne katana bane jason edward batarang alfred rumor edward. w ph Maxie vale bartok selina hangman batman young hugo knight freeze batgirl ragman jason batmobile fairchild mister grayson ghul solomon the. ot Elongated czonk diamond bennett batmobile martha hatter snake bruce swamp strange blink creeper abattoir flash sinestro falcone harley bane ragdoll. o* ( https://fectment.page.link/CF1b )
+*s ho Dent blink scorn league rose ivy superman atkins atkins mugsy freeze
+thorne katana bane jason edward batarang alfred rumor edward. w ph Maxie vale
+bartok selina hangman batman young hugo knight freeze batgirl ragman jason
+batmobile fairchild mister grayson ghul solomon the. ot Elongated czonk diamond
+bennett batmobile martha hatter snake bruce swamp strange blink creeper abattoir
+flash sinestro falcone harley bane ragdoll. o* [2]
 
-( https://matering.page.link/bAmq )
+show photo [3]
 
 Will you come to me on the weekend?
 
-*s ho Todd aquaman bullock falcone jester chase croc doom swamp sinestro hangman fairchild nocturna hangman creeper hangman caird aquaman kane barrow. w p Clench chill green canary metallo face robin shrike hatter riddler gleeson justice rumor batarang kane lucius ragman fox grey batmobile. ho Night gleeson oswald cluemaster abattoir ragman gleeson oswald elongated batmobile face quinn abbott clayface moth knight prey knight atkins killer? to* ( https://exteleer.page.link/kjcS )
+*s ho Todd aquaman bullock falcone jester chase croc doom swamp sinestro hangman
+fairchild nocturna hangman creeper hangman caird aquaman kane barrow. w p Clench
+chill green canary metallo face robin shrike hatter riddler gleeson justice
+rumor batarang kane lucius ragman fox grey batmobile. ho Night gleeson oswald
+cluemaster abattoir ragman gleeson oswald elongated batmobile face quinn abbott
+clayface moth knight prey knight atkins killer? to* [4]
+
+Links:
+[1] https://fectment.page.link/Ymry
+[2] https://fectment.page.link/CF1b
+[3] https://matering.page.link/bAmq
+[4] https://exteleer.page.link/kjcS
+
+Images:
+- show photo
+- show photo
 ---
 `, content)
 
+	fragment := mail.(*HTMLMail).HTML()
+	assert.True(t, strings.HasPrefix(fragment, "<div>\n                    <table style=\"table-layout: fixed;"))
+	assert.True(t, strings.HasSuffix(fragment, "</table>\n                </div>"))
+
+	URLs := []string{}
+	for _, link := range mail.GetLinks() {
+		URLs = append(URLs, link.URL)
+	}
+	assert.Equal(t, []string{
+		"https://fectment.page.link/Ymry",
+		"https://fectment.page.link/CF1b",
+		"https://matering.page.link/bAmq",
+		"https://exteleer.page.link/kjcS",
+	}, URLs)
+
 	mail, err = Parse[client.MailSourceDoc](getDoc[client.MailSourceDoc](t, "source_mail.html"))
 	assert.NoError(t, err)
+	mail.(*SourceMail).SetRaw(true)
 
-	content, err = mail.Coloured()
+	content, err = mail.(*SourceMail).Coloured()
 	assert.NoError(t, err)
 	assert.Equal(t, `---
-Content-Transfer-Encoding : quoted-printable
-
-Content-Type              : text/html; charset=utf-8
-
-Date                      : Sun, 13 Aug 2023 22:45:09 +0000
-
 Dkim-Signature[0]         : v=1; a=rsa-sha256; q=dns/txt; c=relaxed/simple; s=q5
                             bw7xixmmvalostlj63tyl4baejvbto; d=notificacionesatla
                             s.com; t=1691966709; h=Sender:Message-ID:Date:Subjec
@@ -67,25 +98,31 @@ Dkim-Signature[1]         : v=1; a=rsa-sha256; q=dns/txt; c=relaxed/simple; s=22
                             ZmvaplA59iYQsGQI38bZNhf8Dsczpugwm/zy/hTX 7q2ZNub78+g
                             qsXoaoyTSPOcdFhwFrlSfbvxZ14bo=
 
-Feedback-Id               : 1.us-east-1.kRR7d+JzqofruPoUpbLTHFnCtNSHgd8N+6f35f6u
-                            eyg=:AmazonSES
-
-From                      : Ola no-reply <aplicativos@notificacionesatlas.com>
+Sender                    : Ola no-reply <aplicativos@notificacionesatlas.com>
 
 Message-Id                : <01000189f1131ee1-caa9ba5a-7352-4f31-a033-df29983a54
                             cc-000000@email.amazonses.com>
 
-Mime-Version              : 1.0
-
-Sender                    : Ola no-reply <aplicativos@notificacionesatlas.com>
+Date                      : Sun, 13 Aug 2023 22:45:09 +0000
 
 Subject                   : =?utf-8?Q?Marcaci=C3=B3n?= de un punto de ronda fuer
                             a de la =?utf-8?Q?posici=C3=B3n?= georreferencia del
                              cliente en INTERCOLOMBIA S.A. E.S.P., zona: RONDA C
                             ASA FEISA.
 
+From                      : Ola no-reply <aplicativos@notificacionesatlas.com>
+
 To                        : test@yopmail.com
 
+Mime-Version              : 1.0
+
+Content-Type              : text/html; charset=utf-8
+
+Content-Transfer-Encoding : quoted-printable
+
+Feedback-Id               : 1.us-east-1.kRR7d+JzqofruPoUpbLTHFnCtNSHgd8N+6f35f6u
+                            eyg=:AmazonSES
+
 X-Ses-Outgoing            : 2023.08.13-54.240.48.111
 
 ---
@@ -116,7 +153,48 @@ marcaci=C3=B3n respecto a la sede (metros)</b>: 330.06859458703<br />=
 ---
 `, content)
 
-	content, err = mail.JSON()
+	content, err = mail.(*SourceMail).JSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"","headers":{"Dkim-Signature":["v=1; a=rsa-sha256; q=dns/txt; c=relaxed/simple; s=q5bw7xixmmvalostlj63tyl4baejvbto; d=notificacionesatlas.com; t=1691966709; h=Sender:Message-ID:Date:Subject:From:To:MIME-Version:Content-Type:Content-Transfer-Encoding; bh=Zl0o2FHSd18g28sSGzovn6Xq/HWn9YPl2DFr+Dd+KE4=; b=DTOkYKD3HyTGHUOTGRGL0V2nTOPes9HlNBXHcSms0XHdr7xL1AXriMYTLwuv1UTM 5iO0ZTFPpMQDfjd7mi/Ca0oNVUAmgaSojcuxWUHu5znCt3e3OSEL8q5u9rN5fI3jFkj ASRgVFTIvJNhH17o44ONqwpIdt2cYd17LMBAfp1f4KK9lPERd0H2jX8SIjc4dHEQxa5 5JDAQN92SlVV6CkhcZYF2mdEhsYuZsPkFVSd6BKlKNPT2Y4tZiEW5lI+UjTvvbdlRWj i/7ATftL+CYE/mz7soGeeJXV+PNKX4Mgbz8jujp2nV/PrJlZSp7IijF3K/piMTV4udN 6yG/+O1V+Q==","v=1; a=rsa-sha256; q=dns/txt; c=relaxed/simple; s=224i4yxa5dv7c2xz3womw6peuasteono; d=amazonses.com; t=1691966709; h=Sender:Message-ID:Date:Subject:From:To:MIME-Version:Content-Type:Content-Transfer-Encoding:Feedback-ID; bh=Zl0o2FHSd18g28sSGzovn6Xq/HWn9YPl2DFr+Dd+KE4=; b=aIwZk+y/naOdqtrYzyFrc8/qkfwgJt6APQ6vP22zqLe5/oLJ23M1KFTbyKCqXlKF t4W1TktUHy2iGXzZB3izHAFHmPAZmvaplA59iYQsGQI38bZNhf8Dsczpugwm/zy/hTX 7q2ZNub78+gqsXoaoyTSPOcdFhwFrlSfbvxZ14bo="],"Sender":["Ola no-reply \u003caplicativos@notificacionesatlas.com\u003e"],"Message-Id":["\u003c01000189f1131ee1-caa9ba5a-7352-4f31-a033-df29983a54cc-000000@email.amazonses.com\u003e"],"Date":["Sun, 13 Aug 2023 22:45:09 +0000"],"Subject":["=?utf-8?Q?Marcaci=C3=B3n?= de un punto de ronda fuera de la =?utf-8?Q?posici=C3=B3n?= georreferencia del cliente en INTERCOLOMBIA S.A. E.S.P., zona: RONDA CASA FEISA."],"From":["Ola no-reply \u003caplicativos@notificacionesatlas.com\u003e"],"To":["test@yopmail.com"],"Mime-Version":["1.0"],"Content-Type":["text/html; charset=utf-8"],"Content-Transfer-Encoding":["quoted-printable"],"Feedback-Id":["1.us-east-1.kRR7d+JzqofruPoUpbLTHFnCtNSHgd8N+6f35f6ueyg=:AmazonSES"],"X-Ses-Outgoing":["2023.08.13-54.240.48.111"]},"body":"\u003cp\u003eHola,\u003cbr /\u003e\n\u003cbr /\u003e\nMarcaci=C3=B3n de un punto de ronda fuera d=\ne la posici=C3=B3n georreferencia del cliente:\u003cbr /\u003e\n\u003cb\u003eFecha y hora d=\ne la ronda\u003c/b\u003e: 2023-08-13 17:15:00\u003cbr /\u003e\n\u003cb\u003eResponsable asignado\u003c/b=\n\u003e: \u003cbr /\u003e\n\u003cb\u003eJornada\u003c/b\u003e: 24 HORAS \u003e DIURNA\u003cbr /\u003e\n\u003cb\u003eCliente\u003c/b\u003e=\n: ISA INTERCOLOMBIA SA ESP\u003cbr /\u003e\n\u003cb\u003eSede o Punto del Cliente\u003c/b\u003e: IN=\nTERCOLOMBIA S.A. E.S.P.\u003cbr /\u003e\n\u003cb\u003eZona interna\u003c/b\u003e: RONDA CASA FEISA\u003c=\nbr /\u003e=20\n\u003cb\u003eCoordenadas del cliente\u003c/b\u003e: 6.1870833;-75.5596067\u003cbr =\n/\u003e=20\n\u003cb\u003eRadio georreferenciado para validar las marcaciones se realice=\nn dentro de dicha geocerca (metros)\u003c/b\u003e: \u003cbr /\u003e=20\n\u003cb\u003eDistancia de la =\nmarcaci=C3=B3n respecto a la sede (metros)\u003c/b\u003e: 330.06859458703\u003cbr /\u003e=\n=20\n\u003c/p\u003e.\"\n"}`, content)
+
+	message := string(mail.(*SourceMail).Message())
+	assert.True(t, strings.HasPrefix(message, "DKIM-Signature: v=1; a=rsa-sha256; q=dns/txt; c=relaxed/simple;\r\n\ts=q5bw7xixmmvalostlj63tyl4baejvbto;"))
+	assert.True(t, strings.HasSuffix(message, "\r\n</p>.\"\r\n"))
+	assert.Equal(t, strings.Count(message, "\n"), strings.Count(message, "\r\n"))
+
+	mail.(*SourceMail).SetRaw(false)
+	assert.Equal(t, &Part{ContentType: "text/html", Charset: "utf-8", Size: 670, Content: mail.Content()}, mail.(*SourceMail).Structure)
+	assert.Contains(t, mail.Content(), "Marcación de un punto de ronda fuera de la posición georreferencia del cliente")
+	assert.Equal(t, []string{"Marcación de un punto de ronda fuera de la posición georreferencia del cliente en INTERCOLOMBIA S.A. E.S.P., zona: RONDA CASA FEISA."}, mail.(*SourceMail).Headers.Values("Subject"))
+	assert.Equal(t, []Address{{Name: "Ola no-reply", Address: "aplicativos@notificacionesatlas.com"}}, mail.(*SourceMail).Addresses["From"])
+	content, err = mail.(*SourceMail).JSON()
+	assert.NoError(t, err)
+	assert.Contains(t, content, `"rawHeaders":{"Dkim-Signature":["v=1; a=rsa-sha256;`)
+	assert.Contains(t, content, `"structure":{"contentType":"text/html","charset":"utf-8","size":670,"content":"\u003cp\u003eHola,`)
+
+	mail, err = Parse[client.MailSourceDoc](getDoc[client.MailSourceDoc](t, "source_mail_multipart.html"))
 	assert.NoError(t, err)
-	assert.Equal(t, `{"id":"","headers":{"Content-Transfer-Encoding":["quoted-printable"],"Content-Type":["text/html; charset=utf-8"],"Date":["Sun, 13 Aug 2023 22:45:09 +0000"],"Dkim-Signature":["v=1; a=rsa-sha256; q=dns/txt; c=relaxed/simple; s=q5bw7xixmmvalostlj63tyl4baejvbto; d=notificacionesatlas.com; t=1691966709; h=Sender:Message-ID:Date:Subject:From:To:MIME-Version:Content-Type:Content-Transfer-Encoding; bh=Zl0o2FHSd18g28sSGzovn6Xq/HWn9YPl2DFr+Dd+KE4=; b=DTOkYKD3HyTGHUOTGRGL0V2nTOPes9HlNBXHcSms0XHdr7xL1AXriMYTLwuv1UTM 5iO0ZTFPpMQDfjd7mi/Ca0oNVUAmgaSojcuxWUHu5znCt3e3OSEL8q5u9rN5fI3jFkj ASRgVFTIvJNhH17o44ONqwpIdt2cYd17LMBAfp1f4KK9lPERd0H2jX8SIjc4dHEQxa5 5JDAQN92SlVV6CkhcZYF2mdEhsYuZsPkFVSd6BKlKNPT2Y4tZiEW5lI+UjTvvbdlRWj i/7ATftL+CYE/mz7soGeeJXV+PNKX4Mgbz8jujp2nV/PrJlZSp7IijF3K/piMTV4udN 6yG/+O1V+Q==","v=1; a=rsa-sha256; q=dns/txt; c=relaxed/simple; s=224i4yxa5dv7c2xz3womw6peuasteono; d=amazonses.com; t=1691966709; h=Sender:Message-ID:Date:Subject:From:To:MIME-Version:Content-Type:Content-Transfer-Encoding:Feedback-ID; bh=Zl0o2FHSd18g28sSGzovn6Xq/HWn9YPl2DFr+Dd+KE4=; b=aIwZk+y/naOdqtrYzyFrc8/qkfwgJt6APQ6vP22zqLe5/oLJ23M1KFTbyKCqXlKF t4W1TktUHy2iGXzZB3izHAFHmPAZmvaplA59iYQsGQI38bZNhf8Dsczpugwm/zy/hTX 7q2ZNub78+gqsXoaoyTSPOcdFhwFrlSfbvxZ14bo="],"Feedback-Id":["1.us-east-1.kRR7d+JzqofruPoUpbLTHFnCtNSHgd8N+6f35f6ueyg=:AmazonSES"],"From":["Ola no-reply \u003caplicativos@notificacionesatlas.com\u003e"],"Message-Id":["\u003c01000189f1131ee1-caa9ba5a-7352-4f31-a033-df29983a54cc-000000@email.amazonses.com\u003e"],"Mime-Version":["1.0"],"Sender":["Ola no-reply \u003caplicativos@notificacionesatlas.com\u003e"],"Subject":["=?utf-8?Q?Marcaci=C3=B3n?= de un punto de ronda fuera de la =?utf-8?Q?posici=C3=B3n?= georreferencia del cliente en INTERCOLOMBIA S.A. E.S.P., zona: RONDA CASA FEISA."],"To":["test@yopmail.com"],"X-Ses-Outgoing":["2023.08.13-54.240.48.111"]},"body":"\u003cp\u003eHola,\u003cbr /\u003e\n\u003cbr /\u003e\nMarcaci=C3=B3n de un punto de ronda fuera d=\ne la posici=C3=B3n georreferencia del cliente:\u003cbr /\u003e\n\u003cb\u003eFecha y hora d=\ne la ronda\u003c/b\u003e: 2023-08-13 17:15:00\u003cbr /\u003e\n\u003cb\u003eResponsable asignado\u003c/b=\n\u003e: \u003cbr /\u003e\n\u003cb\u003eJornada\u003c/b\u003e: 24 HORAS \u003e DIURNA\u003cbr /\u003e\n\u003cb\u003eCliente\u003c/b\u003e=\n: ISA INTERCOLOMBIA SA ESP\u003cbr /\u003e\n\u003cb\u003eSede o Punto del Cliente\u003c/b\u003e: IN=\nTERCOLOMBIA S.A. E.S.P.\u003cbr /\u003e\n\u003cb\u003eZona interna\u003c/b\u003e: RONDA CASA FEISA\u003c=\nbr /\u003e=20\n\u003cb\u003eCoordenadas del cliente\u003c/b\u003e: 6.1870833;-75.5596067\u003cbr =\n/\u003e=20\n\u003cb\u003eRadio georreferenciado para validar las marcaciones se realice=\nn dentro de dicha geocerca (metros)\u003c/b\u003e: \u003cbr /\u003e=20\n\u003cb\u003eDistancia de la =\nmarcaci=C3=B3n respecto a la sede (metros)\u003c/b\u003e: 330.06859458703\u003cbr /\u003e=\n=20\n\u003c/p\u003e.\"\n"}`, content)
+	assert.Equal(t, []Attachment{
+		{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 12, Content: []byte("Hello World!")},
+		{Filename: "résumé.txt", ContentType: "text/plain", Size: 8, Content: []byte("résumé")},
+	}, mail.GetAttachments())
+	assert.Equal(t, "Hello, your invoice of 10 € is attached.", mail.Content())
+	assert.Equal(t, []string{"multipart/alternative", "application/pdf", "text/plain"}, []string{
+		mail.(*SourceMail).Structure.Parts[0].ContentType,
+		mail.(*SourceMail).Structure.Parts[1].ContentType,
+		mail.(*SourceMail).Structure.Parts[2].ContentType,
+	})
+}
+
+func TestParseText(t *testing.T) {
+	mail, err := Parse[client.MailTextDoc](getDoc[client.MailTextDoc](t, "text_mail.html"))
+	assert.NoError(t, err)
+
+	m := mail.(*HTMLMail)
+	assert.Equal(t, "In any case, I am happy that we met", m.Subject)
+	assert.Equal(t, &Sender{Name: "Liana", Mail: "AnnaMartinezpisea@lionspest.com.au"}, m.Sender)
+	assert.NotNil(t, m.Date)
+	assert.Equal(t, "What such a gorgeous man is doing here?\n\nWill you come to me on the weekend? https://example.com/profile", m.Content())
+	assert.Empty(t, m.HTML())
+	assert.Equal(t, []Link{{URL: "https://example.com/profile", Text: "https://example.com/profile"}}, m.GetLinks())
 }
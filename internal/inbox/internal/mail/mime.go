@@ -0,0 +1,270 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Attachment defines a file attached to a mail
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+	Content     []byte `json:"-"`
+}
+
+// Part defines a MIME part of a mail, the content of
+// the text parts is decoded and converted to UTF-8
+type Part struct {
+	ContentType string `json:"contentType"`
+	Charset     string `json:"charset,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	Size        int    `json:"size"`
+	Content     string `json:"content,omitempty"`
+	Parts       []Part `json:"parts,omitempty"`
+	attachment  *Attachment
+}
+
+// Entity is a MIME entity of a message, the message itself or one of
+// its parts, the header and the body are kept as they are
+type Entity struct {
+	Raw       []byte
+	Header    []byte
+	Body      []byte
+	Fields    textproto.MIMEHeader
+	MediaType string
+	Params    map[string]string
+	Children  []*Entity
+	// Embedded is the message carried by a message/rfc822 entity
+	Embedded *Entity
+}
+
+// ParseEntity builds the tree of the entities of a message, defaultType
+// is the media type of an entity without a valid Content-Type header
+func ParseEntity(raw []byte, defaultType string) *Entity {
+	length := headerLength(raw)
+	fields, _ := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw[:length]))).ReadMIMEHeader()
+	e := newEntity(fields, raw[length:], defaultType)
+	e.Raw, e.Header = raw, raw[:length]
+	return e
+}
+
+func newEntity(fields textproto.MIMEHeader, body []byte, defaultType string) *Entity {
+	if fields == nil {
+		fields = textproto.MIMEHeader{}
+	}
+	e := &Entity{Body: body, Fields: fields, MediaType: defaultType, Params: map[string]string{}}
+	if mediaType, params, err := mime.ParseMediaType(fields.Get("Content-Type")); err == nil {
+		e.MediaType, e.Params = mediaType, params
+	}
+	switch {
+	case strings.HasPrefix(e.MediaType, "multipart/") && e.Params["boundary"] != "":
+		childType := "text/plain"
+		if e.MediaType == "multipart/digest" {
+			childType = "message/rfc822"
+		}
+		for _, part := range splitMultipart(body, e.Params["boundary"]) {
+			e.Children = append(e.Children, ParseEntity(part, childType))
+		}
+	case e.MediaType == "message/rfc822":
+		e.Embedded = ParseEntity(body, "text/plain")
+	}
+	return e
+}
+
+// headerLength returns the length of the header with the blank line
+// ending it, an entity without blank line is only made of a header
+func headerLength(raw []byte) int {
+	for offset := 0; offset < len(raw); {
+		end := bytes.IndexByte(raw[offset:], '\n')
+		if end == -1 {
+			break
+		}
+		if len(bytes.TrimRight(raw[offset:offset+end], "\r")) == 0 {
+			return offset + end + 1
+		}
+		offset += end + 1
+	}
+	return len(raw)
+}
+
+// splitMultipart returns the raw parts found between the boundaries,
+// the lines can end with CRLF or LF
+func splitMultipart(body []byte, boundary string) [][]byte {
+	delimiter := []byte("--" + boundary)
+	parts := [][]byte{}
+	start := -1
+	for offset := 0; offset < len(body); {
+		end := bytes.IndexByte(body[offset:], '\n')
+		next := offset + end + 1
+		if end == -1 {
+			end = len(body) - offset
+			next = len(body)
+		}
+		line := bytes.TrimRight(body[offset:offset+end], " \t\r")
+		if bytes.HasPrefix(line, delimiter) {
+			closing := bytes.Equal(line[len(delimiter):], []byte("--"))
+			if bytes.Equal(line, delimiter) || closing {
+				if start != -1 {
+					// The line break before a delimiter belongs to the delimiter
+					part := bytes.TrimSuffix(body[start:max(start, offset)], []byte("\n"))
+					parts = append(parts, bytes.TrimSuffix(part, []byte("\r")))
+				}
+				if closing {
+					return parts
+				}
+				start = next
+			}
+		}
+		offset = next
+	}
+	if start != -1 && start < len(body) {
+		parts = append(parts, body[start:])
+	}
+	return parts
+}
+
+// parsePart builds the tree of a MIME message, when an error occurs
+// the part returned contains everything parsed until then
+func parsePart(header textproto.MIMEHeader, body io.Reader) (Part, error) {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return Part{}, err
+	}
+	return newPart(newEntity(header, content, "text/plain"))
+}
+
+func newPart(e *Entity) (Part, error) {
+	part := Part{ContentType: e.MediaType}
+	if strings.HasPrefix(e.MediaType, "multipart/") && e.Params["boundary"] != "" {
+		for _, entity := range e.Children {
+			child, err := newPart(entity)
+			part.Parts = append(part.Parts, child)
+			if err != nil {
+				return part, err
+			}
+		}
+		return part, nil
+	}
+
+	content, err := decodeTransferEncoding(e.Fields.Get("Content-Transfer-Encoding"), bytes.NewReader(e.Body))
+	if err != nil {
+		return part, err
+	}
+	part.Size = len(content)
+	if attachment, ok := parseAttachment(e.Fields, content); ok {
+		part.Filename = attachment.Filename
+		part.attachment = &attachment
+		return part, nil
+	}
+	if strings.HasPrefix(e.MediaType, "text/") {
+		part.Charset = e.Params["charset"]
+		part.Content, err = decodeCharset(part.Charset, content)
+	}
+	return part, err
+}
+
+func decodeTransferEncoding(encoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// base64 content is split in lines that the decoder doesn't support
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(bytes.Join(bytes.Fields(data), nil))))
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(body))
+	}
+	return io.ReadAll(body)
+}
+
+// decodeCharset converts a content to UTF-8, unknown
+// charsets are kept as is rather than failing
+func decodeCharset(label string, content []byte) (string, error) {
+	switch strings.ToLower(label) {
+	case "", "utf-8", "us-ascii":
+		return string(content), nil
+	}
+	r, err := charset.NewReaderLabel(label, bytes.NewReader(content))
+	if err != nil {
+		return string(content), nil
+	}
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+// parseAttachment returns the attachment stored in a part, the filename
+// is looked up in the Content-Disposition header first and in the
+// Content-Type header then, RFC 2231 parameters are handled by ParseMediaType
+func parseAttachment(header textproto.MIMEHeader, content []byte) (Attachment, bool) {
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	contentType, contentTypeParams, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		contentType = "application/octet-stream"
+	}
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = contentTypeParams["name"]
+	}
+	if disposition != "attachment" && filename == "" {
+		return Attachment{}, false
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	return Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        len(content),
+		Content:     content,
+	}, true
+}
+
+// attachments returns the attachments found in the part tree
+func (p Part) attachments() []Attachment {
+	attachments := []Attachment{}
+	if p.attachment != nil {
+		attachments = append(attachments, *p.attachment)
+	}
+	for _, child := range p.Parts {
+		attachments = append(attachments, child.attachments()...)
+	}
+	return attachments
+}
+
+// text returns the readable content of the part tree, in alternatives
+// the plain text version is preferred to the HTML one
+func (p Part) text() string {
+	if len(p.Parts) == 0 {
+		return p.Content
+	}
+	if p.ContentType == "multipart/alternative" {
+		for _, child := range p.Parts {
+			if child.ContentType == "text/plain" && child.Content != "" {
+				return child.Content
+			}
+		}
+		for _, child := range p.Parts {
+			if s := child.text(); s != "" {
+				return s
+			}
+		}
+		return ""
+	}
+	texts := []string{}
+	for _, child := range p.Parts {
+		if s := child.text(); s != "" {
+			texts = append(texts, s)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
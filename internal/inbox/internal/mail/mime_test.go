@@ -0,0 +1,199 @@
+package mail
+
+import (
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAttachment(t *testing.T) {
+	type scenario struct {
+		name               string
+		header             textproto.MIMEHeader
+		attachmentExpected Attachment
+		okExpected         bool
+	}
+
+	scenarios := []scenario{
+		{
+			name:   "Text part",
+			header: textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}},
+		},
+		{
+			name: "Filename in Content-Disposition",
+			header: textproto.MIMEHeader{
+				"Content-Type":        {"application/pdf"},
+				"Content-Disposition": {`attachment; filename="invoice.pdf"`},
+			},
+			attachmentExpected: Attachment{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 4, Content: []byte("test")},
+			okExpected:         true,
+		},
+		{
+			name:               "Filename in Content-Type",
+			header:             textproto.MIMEHeader{"Content-Type": {`image/png; name="logo.png"`}},
+			attachmentExpected: Attachment{Filename: "logo.png", ContentType: "image/png", Size: 4, Content: []byte("test")},
+			okExpected:         true,
+		},
+		{
+			name: "RFC 2231 encoded filename",
+			header: textproto.MIMEHeader{
+				"Content-Disposition": {`attachment; filename*0*=UTF-8''r%C3%A9sum; filename*1="é.txt"`},
+			},
+			attachmentExpected: Attachment{Filename: "résumé.txt", ContentType: "application/octet-stream", Size: 4, Content: []byte("test")},
+			okExpected:         true,
+		},
+		{
+			name: "RFC 2047 encoded filename",
+			header: textproto.MIMEHeader{
+				"Content-Type":        {"text/plain"},
+				"Content-Disposition": {`attachment; filename="=?UTF-8?B?csOpc3Vtw6kudHh0?="`},
+			},
+			attachmentExpected: Attachment{Filename: "résumé.txt", ContentType: "text/plain", Size: 4, Content: []byte("test")},
+			okExpected:         true,
+		},
+		{
+			name: "Attachment without filename",
+			header: textproto.MIMEHeader{
+				"Content-Type":        {"text/calendar"},
+				"Content-Disposition": {"attachment"},
+			},
+			attachmentExpected: Attachment{ContentType: "text/calendar", Size: 4, Content: []byte("test")},
+			okExpected:         true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			attachment, ok := parseAttachment(scenario.header, []byte("test"))
+			assert.Equal(t, scenario.okExpected, ok)
+			assert.Equal(t, scenario.attachmentExpected, attachment)
+		})
+	}
+}
+
+func TestParsePart(t *testing.T) {
+	type scenario struct {
+		name          string
+		header        textproto.MIMEHeader
+		body          string
+		partExpected  Part
+		textExpected  string
+		errorExpected bool
+	}
+
+	scenarios := []scenario{
+		{
+			name:         "Plain text without headers",
+			body:         "hello",
+			partExpected: Part{ContentType: "text/plain", Size: 5, Content: "hello"},
+			textExpected: "hello",
+		},
+		{
+			name:         "Quoted-printable ISO-8859-1 text",
+			header:       textproto.MIMEHeader{"Content-Type": {"text/plain; charset=ISO-8859-1"}, "Content-Transfer-Encoding": {"quoted-printable"}},
+			body:         "caf=E9 cr=E8me",
+			partExpected: Part{ContentType: "text/plain", Charset: "ISO-8859-1", Size: 10, Content: "café crème"},
+			textExpected: "café crème",
+		},
+		{
+			name:         "Base64 Windows-1252 text",
+			header:       textproto.MIMEHeader{"Content-Type": {"text/plain; charset=windows-1252"}, "Content-Transfer-Encoding": {"base64"}},
+			body:         "gCAx\nMA==\n",
+			partExpected: Part{ContentType: "text/plain", Charset: "windows-1252", Size: 4, Content: "€ 10"},
+			textExpected: "€ 10",
+		},
+		{
+			name:         "Unknown charset",
+			header:       textproto.MIMEHeader{"Content-Type": {"text/plain; charset=x-unknown"}},
+			body:         "hello",
+			partExpected: Part{ContentType: "text/plain", Charset: "x-unknown", Size: 5, Content: "hello"},
+			textExpected: "hello",
+		},
+		{
+			name:   "Alternative prefers plain text",
+			header: textproto.MIMEHeader{"Content-Type": {`multipart/alternative; boundary="b"`}},
+			body:   "--b\nContent-Type: text/html\n\n<p>hello</p>\n--b\nContent-Type: text/plain\n\nhello\n--b--\n",
+			partExpected: Part{ContentType: "multipart/alternative", Parts: []Part{
+				{ContentType: "text/html", Size: 12, Content: "<p>hello</p>"},
+				{ContentType: "text/plain", Size: 5, Content: "hello"},
+			}},
+			textExpected: "hello",
+		},
+		{
+			name:   "Mixed with attachment",
+			header: textproto.MIMEHeader{"Content-Type": {`multipart/mixed; boundary="b"`}},
+			body:   "--b\nContent-Type: text/plain\n\ntext\n--b\nContent-Type: application/pdf; name=a.pdf\nContent-Transfer-Encoding: base64\n\nYWJj\n--b--\n",
+			partExpected: Part{ContentType: "multipart/mixed", Parts: []Part{
+				{ContentType: "text/plain", Size: 4, Content: "text"},
+				{ContentType: "application/pdf", Filename: "a.pdf", Size: 3, attachment: &Attachment{Filename: "a.pdf", ContentType: "application/pdf", Size: 3, Content: []byte("abc")}},
+			}},
+			textExpected: "text",
+		},
+		{
+			name:   "Malformed base64 part",
+			header: textproto.MIMEHeader{"Content-Type": {`multipart/mixed; boundary="b"`}},
+			body:   "--b\nContent-Type: text/plain\n\ntext\n--b\nContent-Type: text/plain\nContent-Transfer-Encoding: base64\n\n!!!\n--b--\n",
+			partExpected: Part{ContentType: "multipart/mixed", Parts: []Part{
+				{ContentType: "text/plain", Size: 4, Content: "text"},
+				{ContentType: "text/plain"},
+			}},
+			textExpected:  "text",
+			errorExpected: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			part, err := parsePart(scenario.header, strings.NewReader(scenario.body))
+			if scenario.errorExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, scenario.partExpected, part)
+			assert.Equal(t, scenario.textExpected, part.text())
+		})
+	}
+}
+
+func TestParseEntity(t *testing.T) {
+	type scenario struct {
+		name string
+		raw  string
+	}
+
+	scenarios := []scenario{
+		{
+			name: "LF line endings",
+			raw:  "Content-Type: multipart/digest; boundary=b\n\npreamble\n--b\n\nSubject: Forwarded\n\nInner body\n--b\nContent-Type: text/plain\n\nsecond\n--b--\nepilogue\n",
+		},
+		{
+			name: "CRLF line endings",
+			raw:  "Content-Type: multipart/digest; boundary=b\r\n\r\npreamble\r\n--b\r\n\r\nSubject: Forwarded\r\n\r\nInner body\r\n--b\r\nContent-Type: text/plain\r\n\r\nsecond\r\n--b--\r\nepilogue\r\n",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			e := ParseEntity([]byte(scenario.raw), "text/plain")
+			assert.Equal(t, "multipart/digest", e.MediaType)
+			if assert.Len(t, e.Children, 2) {
+				assert.Equal(t, "message/rfc822", e.Children[0].MediaType)
+				if assert.NotNil(t, e.Children[0].Embedded) {
+					assert.Equal(t, "Forwarded", e.Children[0].Embedded.Fields.Get("Subject"))
+					assert.Equal(t, "Inner body", string(e.Children[0].Embedded.Body))
+				}
+				assert.Equal(t, "text/plain", e.Children[1].MediaType)
+				assert.Equal(t, "second", string(e.Children[1].Body))
+			}
+		})
+	}
+}
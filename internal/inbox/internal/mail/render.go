@@ -0,0 +1,246 @@
+package mail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jaytaylor/html2text"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hyperlinkRegexp matches the OSC 8 escape sequences, they
+// are not displayed so they must not be counted when wrapping
+var hyperlinkRegexp = regexp.MustCompile("\x1b\\]8;;[^\x1b]*\x1b\\\\")
+
+// tableLineRegexp matches the borders and the rows of the data tables
+var tableLineRegexp = regexp.MustCompile(`^(\+(-+\+)+|\|.*\|)$`)
+
+// htmlRenderer renders an HTML mail for a terminal, links are
+// turned into numbered footnotes or into OSC 8 hyperlinks
+type htmlRenderer struct {
+	width      int
+	hyperlinks bool
+	links      []string
+	images     []string
+}
+
+func renderHTML(fragment string, width int, hyperlinks bool) (string, error) {
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return "", err
+	}
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, node := range nodes {
+		root.AppendChild(node)
+	}
+
+	r := htmlRenderer{width: width, hyperlinks: hyperlinks}
+	r.prepare(root, false, false)
+	text, err := html2text.FromHTMLNode(root, html2text.Options{
+		PrettyTables:        true,
+		PrettyTablesOptions: html2text.NewPrettyTablesOptions(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(r.wrap(text))
+	if len(r.links) > 0 {
+		b.WriteString("\n\nLinks:")
+		for i, link := range r.links {
+			fmt.Fprintf(&b, "\n[%d] %s", i+1, link)
+		}
+	}
+	if len(r.images) > 0 {
+		b.WriteString("\n\nImages:")
+		for _, image := range r.images {
+			fmt.Fprintf(&b, "\n- %s", image)
+		}
+	}
+	return b.String(), nil
+}
+
+// prepare rewrites the tree before its conversion to text, layout
+// tables are unwrapped so only the data tables are drawn
+func (r *htmlRenderer) prepare(node *html.Node, inLink bool, inLayoutTable bool) {
+	switch node.DataAtom {
+	case atom.Img:
+		alt := strings.TrimSpace(getAttribute(node, "alt"))
+		if alt != "" {
+			r.images = append(r.images, alt)
+		}
+		if inLink && alt != "" {
+			node.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: alt}, node)
+		}
+		node.Parent.RemoveChild(node)
+		return
+	case atom.Table:
+		inLayoutTable = !isDataTable(node)
+		if inLayoutTable {
+			toDiv(node)
+		}
+	case atom.Thead, atom.Tbody, atom.Tfoot, atom.Tr, atom.Td, atom.Th, atom.Caption:
+		if inLayoutTable {
+			toDiv(node)
+		}
+	}
+
+	URL := ""
+	if node.DataAtom == atom.A {
+		URL = strings.TrimSpace(getAttribute(node, "href"))
+		removeAttribute(node, "href")
+		if URL != "" && !strings.HasPrefix(URL, "#") {
+			inLink = true
+		} else {
+			URL = ""
+		}
+	}
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		r.prepare(child, inLink, inLayoutTable)
+		child = next
+	}
+	if URL != "" {
+		r.link(node, URL)
+	}
+}
+
+func (r *htmlRenderer) link(node *html.Node, URL string) {
+	URL = escapeControls(URL)
+	if !r.hyperlinks {
+		index := len(r.links) + 1
+		for i, link := range r.links {
+			if link == URL {
+				index = i + 1
+			}
+		}
+		if index > len(r.links) {
+			r.links = append(r.links, URL)
+		}
+		node.AppendChild(&html.Node{Type: html.TextNode, Data: fmt.Sprintf("[%d]", index)})
+		return
+	}
+
+	texts := []*html.Node{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode && strings.TrimSpace(n.Data) != "" {
+			texts = append(texts, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	if len(texts) == 0 {
+		text := &html.Node{Type: html.TextNode, Data: URL}
+		node.AppendChild(text)
+		texts = append(texts, text)
+	}
+	// the sequences are glued to the text as html2text
+	// inserts a space between two consecutive text nodes
+	first, last := texts[0], texts[len(texts)-1]
+	first.Data = "\x1b]8;;" + URL + "\x1b\\" + strings.TrimLeft(first.Data, " \t\r\n")
+	last.Data = strings.TrimRight(last.Data, " \t\r\n") + "\x1b]8;;\x1b\\"
+}
+
+// wrap breaks the lines longer than the terminal width on
+// spaces, the lines of the tables are kept untouched
+func (r *htmlRenderer) wrap(text string) string {
+	if r.width <= 0 {
+		return text
+	}
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if visibleLength(line) <= r.width || tableLineRegexp.MatchString(line) {
+			lines = append(lines, line)
+			continue
+		}
+		prefix := line[:len(line)-len(strings.TrimLeft(line, "> "))]
+		current := prefix
+		for _, word := range strings.Fields(line[len(prefix):]) {
+			if current != prefix && visibleLength(current)+1+visibleLength(word) > r.width {
+				lines = append(lines, current)
+				current = prefix
+			}
+			if current != prefix {
+				current += " "
+			}
+			current += word
+		}
+		lines = append(lines, current)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeControls percent-encodes the control characters of an URL,
+// an escape sequence would otherwise be sent to the terminal
+func escapeControls(URL string) string {
+	var b strings.Builder
+	for _, c := range URL {
+		if !unicode.IsControl(c) {
+			b.WriteRune(c)
+			continue
+		}
+		for _, octet := range []byte(string(c)) {
+			fmt.Fprintf(&b, "%%%02X", octet)
+		}
+	}
+	return b.String()
+}
+
+func visibleLength(s string) int {
+	return utf8.RuneCountInString(hyperlinkRegexp.ReplaceAllString(s, ""))
+}
+
+// isDataTable reports if a table holds data rather than being
+// used for the layout, such a table has headers and no nested table
+func isDataTable(table *html.Node) bool {
+	hasHeader := false
+	var walk func(*html.Node) bool
+	walk = func(n *html.Node) bool {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Table:
+				return false
+			case atom.Th:
+				hasHeader = true
+			}
+			if !walk(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return walk(table) && hasHeader
+}
+
+func toDiv(node *html.Node) {
+	node.DataAtom = atom.Div
+	node.Data = "div"
+}
+
+func getAttribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func removeAttribute(node *html.Node, key string) {
+	attrs := node.Attr[:0]
+	for _, attr := range node.Attr {
+		if attr.Key != key {
+			attrs = append(attrs, attr)
+		}
+	}
+	node.Attr = attrs
+}
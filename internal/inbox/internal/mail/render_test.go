@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHTML(t *testing.T) {
+	type scenario struct {
+		name       string
+		html       string
+		width      int
+		hyperlinks bool
+		expected   string
+	}
+
+	scenarios := []scenario{
+		{
+			name:  "Links as footnotes and images by alt text",
+			html:  `<p>Hello <a href="https://a.com">click here</a> and <a href="https://a.com">again</a>, <a href="#top">top</a></p><a href="https://b.com"><img alt="Logo" src="x.png"></a><img src="pixel.gif"><img alt="Banner">`,
+			width: 80,
+			expected: `Hello click here [1] and again [1] , top
+
+Logo [2]
+
+Links:
+[1] https://a.com
+[2] https://b.com
+
+Images:
+- Logo
+- Banner`,
+		},
+		{
+			name:       "Links as hyperlinks",
+			html:       `<p>Hello <a href="https://a.com"> click here </a>!</p><a href="https://b.com"></a>`,
+			width:      80,
+			hyperlinks: true,
+			expected:   "Hello \x1b]8;;https://a.com\x1b\\click here\x1b]8;;\x1b\\ !\n\n\x1b]8;;https://b.com\x1b\\https://b.com\x1b]8;;\x1b\\",
+		},
+		{
+			name:       "Control characters in links",
+			html:       "<a href=\"https://a.com/\x1b]8;;evil\x07\u009b\">click</a>",
+			width:      80,
+			hyperlinks: true,
+			expected:   "\x1b]8;;https://a.com/%1B]8;;evil%07%C2%9B\x1b\\click\x1b]8;;\x1b\\",
+		},
+		{
+			name:  "Data table",
+			html:  `<table><tr><th>Item</th><th>Price</th></tr><tr><td>Book</td><td>10 €</td></tr></table>`,
+			width: 10,
+			expected: `+------+-------+
+| ITEM | PRICE |
++------+-------+
+| Book | 10 €  |
++------+-------+`,
+		},
+		{
+			name:  "Layout table",
+			html:  `<table><tr><td><table><tr><td>Welcome</td></tr></table></td><td>Second cell</td></tr></table>`,
+			width: 80,
+			expected: `Welcome
+Second cell`,
+		},
+		{
+			name:  "Wrap long lines",
+			html:  `<blockquote>one two three four five six seven</blockquote><p>alpha beta gamma delta epsilon</p>`,
+			width: 12,
+			expected: `> 
+> one two
+> three four
+> five six
+> seven
+
+alpha beta
+gamma delta
+epsilon`,
+		},
+		{
+			name:  "Wrap lines starting like a table",
+			html:  `<p>+33 1 23 45 67 89 is the number</p><p>| not a row at all</p>`,
+			width: 12,
+			expected: `+33 1 23 45
+67 89 is the
+number
+
+| not a row
+at all`,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			text, err := renderHTML(scenario.html, scenario.width, scenario.hyperlinks)
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expected, text)
+		})
+	}
+}
+
+func TestVisibleLength(t *testing.T) {
+	assert.Equal(t, 4, visibleLength("café"))
+	assert.Equal(t, 10, visibleLength("\x1b]8;;https://a.com\x1b\\click here\x1b]8;;\x1b\\"))
+}
@@ -16,67 +16,200 @@ import (
 
 // SourceMail is an HTML  mail message
 type SourceMail struct {
-	ID      string              `json:"id"`
-	Headers map[string][]string `json:"headers"`
-	Body    string              `json:"body"`
+	ID          string               `json:"id"`
+	Headers     Headers              `json:"headers"`
+	RawHeaders  Headers              `json:"rawHeaders,omitempty"`
+	Addresses   map[string][]Address `json:"addresses,omitempty"`
+	Body        string               `json:"body"`
+	RawBody     string               `json:"-"`
+	Structure   *Part                `json:"structure,omitempty"`
+	Attachments []Attachment         `json:"attachments,omitempty"`
+	raw         bool
+	include     []string
+	exclude     []string
+	headersOnly bool
+	message     string
 }
 
 func (m *SourceMail) SetID(ID string) {
 	m.ID = ID
 }
 
+// SetRaw toggles the rendering of the headers and the body as they were
+// received, without decoding the encoded words, transfer encoding and charset
+func (m *SourceMail) SetRaw(raw bool) {
+	m.raw = raw
+}
+
+// FilterHeaders restricts the rendered headers to the ones defined in
+// include, all of them when include is empty, minus the ones in exclude
+func (m *SourceMail) FilterHeaders(include []string, exclude []string) {
+	m.include = include
+	m.exclude = exclude
+}
+
+// SetHeadersOnly toggles the rendering of the headers without the body
+func (m *SourceMail) SetHeadersOnly(headersOnly bool) {
+	m.headersOnly = headersOnly
+}
+
+func (m *SourceMail) headers() Headers {
+	if m.raw {
+		return m.RawHeaders.Filter(m.include, m.exclude)
+	}
+	return m.Headers.Filter(m.include, m.exclude)
+}
+
+func (m *SourceMail) addresses() map[string][]Address {
+	if len(m.include) == 0 && len(m.exclude) == 0 {
+		return m.Addresses
+	}
+	addresses := map[string][]Address{}
+	for k, v := range m.Addresses {
+		if len(Headers{{Key: k}}.Filter(m.include, m.exclude)) > 0 {
+			addresses[k] = v
+		}
+	}
+	return addresses
+}
+
+func (m *SourceMail) body() string {
+	if m.raw {
+		return m.RawBody
+	}
+	return m.Body
+}
+
+// Content returns the email body
+func (m *SourceMail) Content() string {
+	return m.Body
+}
+
+// GetLinks returns the links found in the mail, the source
+// body is not parsed as HTML so no links are provided
+func (m *SourceMail) GetLinks() []Link {
+	return []Link{}
+}
+
+// Message returns the message as received, the line
+// endings are normalized to CRLF as required by RFC 5322
+func (m *SourceMail) Message() []byte {
+	s := strings.ReplaceAll(m.message, "\r\n", "\n")
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return []byte(strings.ReplaceAll(s, "\n", "\r\n"))
+}
+
+// GetAttachments returns the files attached to the mail
+func (m *SourceMail) GetAttachments() []Attachment {
+	return m.Attachments
+}
+
 func (m *SourceMail) Coloured() (string, error) {
+	type header struct {
+		Key   string
+		Value string
+	}
 	var padding int
 	info := struct {
-		Headers map[string]string
-		Body    string
-	}{}
+		Headers     []header
+		Body        string
+		HeadersOnly bool
+	}{HeadersOnly: m.headersOnly}
 	width, _, err := term.GetSize(0)
 	if err != nil {
 		width = 80
 	}
-	info.Headers = map[string]string{}
-	for k, vs := range m.Headers {
-		for i, v := range vs {
-			key := k
-			if len(vs) > 1 {
-				key = fmt.Sprintf("%s[%d]", k, i)
-			}
-			if len(key) > padding {
-				padding = len(key)
-			}
-			info.Headers[key] = v
+	headers := m.headers()
+	counts := map[string]int{}
+	for _, h := range headers {
+		counts[h.Key]++
+	}
+	indexes := map[string]int{}
+	for _, h := range headers {
+		key := h.Key
+		if counts[h.Key] > 1 {
+			key = fmt.Sprintf("%s[%d]", h.Key, indexes[h.Key])
+			indexes[h.Key]++
+		}
+		if len(key) > padding {
+			padding = len(key)
 		}
+		info.Headers = append(info.Headers, header{key, h.Value})
 	}
-	info.Body = color.CyanString(m.Body)
-	for k, v := range info.Headers {
+	info.Body = color.CyanString(m.body())
+	for i, h := range info.Headers {
 		acc := []string{}
-		for i, s := range splitString(v, width-3-padding) {
-			if i == 0 {
+		for j, s := range splitString(h.Value, width-3-padding) {
+			if j == 0 {
 				acc = append(acc, s)
 				continue
 			}
 			acc = append(acc, fmt.Sprintf("%s%s", strings.Repeat(" ", 3+padding), s))
 		}
-		info.Headers[k] = color.MagentaString(strings.Join(acc, "\n"))
+		info.Headers[i].Value = color.MagentaString(strings.Join(acc, "\n"))
 	}
 
 	var buf bytes.Buffer
 	tpl := template.Must(template.New("t").Parse(`---
-{{ range $key, $value := .Headers -}}
-{{ printf "%-` + strconv.Itoa(padding) + `s" $key }} : {{ $value }}
+{{ range .Headers -}}
+{{ printf "%-` + strconv.Itoa(padding) + `s" .Key }} : {{ .Value }}
 
 {{ end -}}
 ---
+{{ if not .HeadersOnly -}}
 {{.Body}}
 ---
+{{ end -}}
 `))
 	err = tpl.Execute(&buf, info)
 	return buf.String(), err
 }
 
+// Header returns the columns of the mail headers
+func (m *SourceMail) Header() []string {
+	return []string{"header", "value"}
+}
+
+// Rows returns a row per mail header in the order they were received
+func (m *SourceMail) Rows() [][]string {
+	rows := [][]string{}
+	for _, header := range m.headers() {
+		rows = append(rows, []string{header.Key, header.Value})
+	}
+	return rows
+}
+
 func (m *SourceMail) JSON() (string, error) {
-	data, err := json.Marshal(&m)
+	var v any
+	switch {
+	case m.raw && m.headersOnly:
+		v = struct {
+			ID      string  `json:"id"`
+			Headers Headers `json:"headers"`
+		}{m.ID, m.headers()}
+	case m.raw:
+		v = struct {
+			ID      string  `json:"id"`
+			Headers Headers `json:"headers"`
+			Body    string  `json:"body"`
+		}{m.ID, m.headers(), m.RawBody}
+	case m.headersOnly:
+		v = struct {
+			ID         string               `json:"id"`
+			Headers    Headers              `json:"headers"`
+			RawHeaders Headers              `json:"rawHeaders,omitempty"`
+			Addresses  map[string][]Address `json:"addresses,omitempty"`
+		}{m.ID, m.headers(), m.RawHeaders.Filter(m.include, m.exclude), m.addresses()}
+	default:
+		mail := *m
+		mail.Headers = m.headers()
+		mail.RawHeaders = m.RawHeaders.Filter(m.include, m.exclude)
+		mail.Addresses = m.addresses()
+		v = &mail
+	}
+	data, err := json.Marshal(v)
 	if err != nil {
 		return "", errors.New("something wrong occurred")
 	}
@@ -10,6 +10,9 @@ func TestSourceMail(t *testing.T) {
 	type scenario struct {
 		name               string
 		mail               *SourceMail
+		include            []string
+		exclude            []string
+		headersOnly        bool
 		outputExpected     string
 		jsonOutputExpected string
 	}
@@ -19,16 +22,16 @@ func TestSourceMail(t *testing.T) {
 			name: "Display a regular email",
 			mail: &SourceMail{
 				ID: "e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt==",
-				Headers: map[string][]string{
-					"Content-Transfer-Encoding": {"quoted-printable"},
-					"Content-Type":              {"text/html; charset=utf-8"},
-					"Date":                      {"Sun, 13 Aug 2023 23:12:14 +0000"},
-					"Message-Id":                {"\u003c01000189f12beb37-090862b4-87cf-4a57-9071-9a39ade2308c-000000@email.amazonses.com\u003e"},
-					"Mime-Version":              {"1.0"},
-					"Sender":                    {"Ola no-reply \u003caplicativos@notificacionesatlas.com\u003e"},
-					"Subject":                   {"=?utf-8?Q?Marcaci=C3=B3n?= de un punto de ronda fuera de la =?utf-8?Q?posici=C3=B3n?= georreferencia del cliente en PONTIFICIA UNIVERSIDAD JAVERIANA, zona:Casa Farallones."},
-					"To":                        {"test@yopmail.com"},
-					"X-Ses-Outgoing":            {"2023.08.13-54.240.48.112"},
+				Headers: Headers{
+					{Key: "Content-Transfer-Encoding", Value: "quoted-printable"},
+					{Key: "Content-Type", Value: "text/html; charset=utf-8"},
+					{Key: "Date", Value: "Sun, 13 Aug 2023 23:12:14 +0000"},
+					{Key: "Message-Id", Value: "\u003c01000189f12beb37-090862b4-87cf-4a57-9071-9a39ade2308c-000000@email.amazonses.com\u003e"},
+					{Key: "Mime-Version", Value: "1.0"},
+					{Key: "Sender", Value: "Ola no-reply \u003caplicativos@notificacionesatlas.com\u003e"},
+					{Key: "Subject", Value: "=?utf-8?Q?Marcaci=C3=B3n?= de un punto de ronda fuera de la =?utf-8?Q?posici=C3=B3n?= georreferencia del cliente en PONTIFICIA UNIVERSIDAD JAVERIANA, zona:Casa Farallones."},
+					{Key: "To", Value: "test@yopmail.com"},
+					{Key: "X-Ses-Outgoing", Value: "2023.08.13-54.240.48.112"},
 				},
 				Body: "TEST\nTEST\nTEST\n",
 			},
@@ -78,12 +81,65 @@ TEST
 "id":"e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt=="
 }`,
 		},
+		{
+			name: "Display headers in wire order",
+			mail: &SourceMail{
+				ID: "e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt==",
+				Headers: Headers{
+					{Key: "Received", Value: "from b"},
+					{Key: "Subject", Value: "test"},
+					{Key: "Received", Value: "from a"},
+					{Key: "From", Value: "test@yopmail.com"},
+				},
+				Body: "TEST\n",
+			},
+			outputExpected: `---
+Received[0] : from b
+
+Subject     : test
+
+Received[1] : from a
+
+From        : test@yopmail.com
+
+---
+TEST
+
+---
+`,
+			jsonOutputExpected: `{"body":"TEST\n","headers":{"Received":["from b","from a"],"Subject":["test"],"From":["test@yopmail.com"]},"id":"e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt=="}`,
+		},
+		{
+			name: "Display filtered headers only",
+			mail: &SourceMail{
+				ID: "e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt==",
+				Headers: Headers{
+					{Key: "Received", Value: "from b"},
+					{Key: "Subject", Value: "test"},
+					{Key: "Received", Value: "from a"},
+					{Key: "From", Value: "test@yopmail.com"},
+				},
+				Addresses: map[string][]Address{"From": {{Address: "test@yopmail.com"}}},
+				Body:      "TEST\n",
+			},
+			include:     []string{"subject", "received"},
+			exclude:     []string{"Received"},
+			headersOnly: true,
+			outputExpected: `---
+Subject : test
+
+---
+`,
+			jsonOutputExpected: `{"headers":{"Subject":["test"]},"id":"e_ZwZjBQRmZwZkZwR4ZQNjAQZ4ZQRlZt=="}`,
+		},
 	}
 
 	for _, scenario := range scenarios {
 		scenario := scenario
 		t.Run(scenario.name, func(t *testing.T) {
 			t.Parallel()
+			scenario.mail.FilterHeaders(scenario.include, scenario.exclude)
+			scenario.mail.SetHeadersOnly(scenario.headersOnly)
 			j, err := scenario.mail.JSON()
 			assert.NoError(t, err)
 			c, err := scenario.mail.Coloured()
@@ -92,6 +148,21 @@ TEST
 			assert.NoError(t, err)
 			assert.Equal(t, scenario.outputExpected, c)
 			assert.JSONEq(t, scenario.jsonOutputExpected, j)
+			assert.Equal(t, scenario.mail.Body, scenario.mail.Content())
 		})
 	}
 }
+
+func TestSourceMailRows(t *testing.T) {
+	mail := &SourceMail{
+		Headers:    Headers{{Key: "Subject", Value: "test"}, {Key: "From", Value: "test@yopmail.com"}},
+		RawHeaders: Headers{{Key: "Subject", Value: "=?utf-8?Q?test?="}},
+	}
+	assert.Equal(t, []string{"header", "value"}, mail.Header())
+	assert.Equal(t, [][]string{{"Subject", "test"}, {"From", "test@yopmail.com"}}, mail.Rows())
+	mail.FilterHeaders([]string{"from"}, nil)
+	assert.Equal(t, [][]string{{"From", "test@yopmail.com"}}, mail.Rows())
+	mail.FilterHeaders(nil, nil)
+	mail.SetRaw(true)
+	assert.Equal(t, [][]string{{"Subject", "=?utf-8?Q?test?="}}, mail.Rows())
+}
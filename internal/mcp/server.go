@@ -0,0 +1,273 @@
+// Package mcp exposes the inboxes as the tools of a Model Context
+// Protocol server, the JSON-RPC messages are exchanged over stdio
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+)
+
+// protocolVersions are the supported versions of the protocol, the latest comes last
+var protocolVersions = []string{"2024-11-05", "2025-03-26", "2025-06-18"}
+
+// maxMessageSize bounds the messages sent by the clients
+const maxMessageSize = 1024 * 1024
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Config defines the tools provided by the server
+type Config struct {
+	// Limit is the number of emails listed by default
+	Limit int
+	// PollInterval is the delay between two checks of an inbox while waiting for an email
+	PollInterval time.Duration
+	// MaxWait caps the time spent waiting for an email
+	MaxWait time.Duration
+	// AllowDelete enables the tools deleting emails
+	AllowDelete bool
+	// Name normalizes the inbox names given to the tools
+	Name func(string) string
+	// Version is the version of the server reported to the clients
+	Version string
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// Server answers the requests of a client, the requests are handled
+// concurrently as waiting for an email can take a while
+type Server struct {
+	session *backend.Session
+	config  Config
+	tools   []tool
+	// mu guards the writer and the running requests
+	mu      sync.Mutex
+	w       io.Writer
+	running map[string]context.CancelFunc
+}
+
+// New creates a server building its inboxes with build
+func New(build backend.Builder, config Config) *Server {
+	if config.Name == nil {
+		config.Name = func(name string) string { return name }
+	}
+	s := &Server{session: backend.NewSession(build), config: config, running: map[string]context.CancelFunc{}}
+	s.tools = s.newTools()
+	return s
+}
+
+// Serve reads the messages from r until it's closed or the
+// context is cancelled, the responses are written to w and the
+// requests still running are cancelled when it returns
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	// the running requests are cancelled before being waited for
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			select {
+			case lines <- slices.Clone(scanner.Bytes()):
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			return err
+		case line := <-lines:
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			var req request
+			if err := json.Unmarshal(line, &req); err != nil {
+				s.write(response{ID: json.RawMessage("null"), Error: &rpcError{codeParseError, fmt.Sprintf("invalid message : %s", err)}})
+				continue
+			}
+			if req.JSONRPC != "2.0" || req.Method == "" {
+				if req.ID != nil {
+					s.write(response{ID: req.ID, Error: &rpcError{codeInvalidRequest, "invalid JSON-RPC 2.0 request"}})
+				}
+				continue
+			}
+			if req.ID == nil {
+				s.notify(req)
+				continue
+			}
+			reqCtx := s.start(ctx, req.ID)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer s.finish(req.ID)
+				result, err := s.handle(reqCtx, req)
+				resp := response{ID: req.ID, Result: result}
+				var rpcErr *rpcError
+				if errors.As(err, &rpcErr) {
+					resp.Result, resp.Error = nil, rpcErr
+				}
+				s.write(resp)
+			}()
+		}
+	}
+}
+
+// notify handles a notification, a cancellation stops a running request
+func (s *Server) notify(req request) {
+	if req.Method != "notifications/cancelled" {
+		return
+	}
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.running[string(params.RequestID)]; ok {
+		cancel()
+	}
+}
+
+func (s *Server) start(ctx context.Context, ID json.RawMessage) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	s.running[string(ID)] = cancel
+	return ctx
+}
+
+func (s *Server) finish(ID json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.running[string(ID)]; ok {
+		cancel()
+		delete(s.running, string(ID))
+	}
+}
+
+func (s *Server) write(resp response) {
+	resp.JSONRPC = "2.0"
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{codeInvalidRequest, err.Error()}})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.w.Write(append(data, '\n'))
+}
+
+// handle runs a request, a failure of a tool is not a protocol
+// error so it is returned as a result
+func (s *Server) handle(ctx context.Context, req request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf("invalid parameters : %s", err)}
+		}
+		version := protocolVersions[len(protocolVersions)-1]
+		if slices.Contains(protocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "yogo", "version": s.config.Version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		tools := []map[string]any{}
+		for _, t := range s.tools {
+			tools = append(tools, map[string]any{
+				"name":        t.name,
+				"description": t.description,
+				"inputSchema": t.schema,
+			})
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf("invalid parameters : %s", err)}
+		}
+		index := slices.IndexFunc(s.tools, func(t tool) bool { return t.name == params.Name })
+		if index == -1 {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf(`unknown tool "%s"`, params.Name)}
+		}
+		if len(params.Arguments) == 0 {
+			params.Arguments = json.RawMessage("{}")
+		}
+		text, err := s.tools[index].call(ctx, params.Arguments)
+		if err != nil {
+			return toolResult(err.Error(), true), nil
+		}
+		return toolResult(text, false), nil
+	}
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf(`method "%s" not found`, req.Method)}
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/backend/backendtest"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(in *backendtest.Inbox, allowDelete bool) *Server {
+	sources := &backendtest.Inbox{Mails: map[string]inbox.Mail{"a": backendtest.Source{Raw: "Subject: Welcome\r\n\r\nWelcome"}}}
+	return New(func(name string, source bool) (backend.Inbox, error) {
+		if name != "test" {
+			return nil, fmt.Errorf(`inbox "%s" doesn't exist`, name)
+		}
+		if source {
+			return sources, nil
+		}
+		return in, nil
+	}, Config{
+		Limit:        15,
+		PollInterval: time.Millisecond,
+		MaxWait:      time.Second,
+		AllowDelete:  allowDelete,
+		Name:         strings.ToLower,
+		Version:      "1.0.0",
+	})
+}
+
+func newTestInbox() *backendtest.Inbox {
+	return &backendtest.Inbox{
+		Lists: [][]inbox.InboxItem{{{ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme", Mail: "hello@acme.com"}}}},
+		Mails: map[string]inbox.Mail{
+			"a": backendtest.Mail{
+				Text:  "Welcome",
+				Links: []inbox.Link{{URL: "https://acme.com/verify?token=1", Text: "Verify"}, {URL: "https://acme.com/unsubscribe"}},
+				Doc:   `{"subject":"Welcome"}`,
+			},
+		},
+	}
+}
+
+// run sends the messages to the server and returns the responses by ID,
+// the input is closed once the requests are answered as closing it
+// cancels the requests still running
+func run(t *testing.T, s *Server, messages ...string) map[string]json.RawMessage {
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(context.Background(), serverReader, serverWriter)
+	}()
+	go func() {
+		for _, message := range messages {
+			if _, err := fmt.Fprintln(clientWriter, message); err != nil {
+				return
+			}
+		}
+	}()
+
+	r := bufio.NewReader(clientReader)
+	responses := map[string]json.RawMessage{}
+	for _, message := range messages {
+		if !answered(message) {
+			continue
+		}
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		var resp struct {
+			ID json.RawMessage `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &resp))
+		responses[string(resp.ID)] = json.RawMessage(strings.TrimSpace(line))
+	}
+	require.NoError(t, clientWriter.Close())
+	require.NoError(t, <-done)
+	return responses
+}
+
+// answered reports if the server answers a message, the
+// notifications and the empty lines are not answered
+func answered(message string) bool {
+	if strings.TrimSpace(message) == "" {
+		return false
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(message), &m); err != nil {
+		return true
+	}
+	_, ok := m["id"]
+	return ok
+}
+
+func call(ID int, tool string, arguments string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"%s","arguments":%s}}`, ID, tool, arguments)
+}
+
+func textResult(ID int, text string, isError bool) string {
+	data, _ := json.Marshal(text)
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"content":[{"type":"text","text":%s}],"isError":%t}}`, ID, data, isError)
+}
+
+func TestServerProtocol(t *testing.T) {
+	responses := run(t, newTestServer(newTestInbox(), false),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"delete_mail","arguments":{}}}`,
+		`{"jsonrpc":"1.0","id":6,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"initialize","params":[]}`,
+		`not json`,
+		``,
+	)
+	assert.Len(t, responses, 8)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{}},"serverInfo":{"name":"yogo","version":"1.0.0"}}}`, string(responses["1"]))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"two","result":{"protocolVersion":"2025-06-18","capabilities":{"tools":{}},"serverInfo":{"name":"yogo","version":"1.0.0"}}}`, string(responses[`"two"`]))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":3,"result":{}}`, string(responses["3"]))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"method \"resources/list\" not found"}}`, string(responses["4"]))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":5,"error":{"code":-32602,"message":"unknown tool \"delete_mail\""}}`, string(responses["5"]))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":6,"error":{"code":-32600,"message":"invalid JSON-RPC 2.0 request"}}`, string(responses["6"]))
+	assert.Contains(t, string(responses["7"]), `"code":-32602`)
+	assert.Contains(t, string(responses["null"]), `"code":-32700`)
+}
+
+func TestServerToolsList(t *testing.T) {
+	names := func(allowDelete bool) []string {
+		responses := run(t, newTestServer(newTestInbox(), allowDelete), `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+		var resp struct {
+			Result struct {
+				Tools []struct {
+					Name        string         `json:"name"`
+					Description string         `json:"description"`
+					InputSchema map[string]any `json:"inputSchema"`
+				} `json:"tools"`
+			} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(responses["1"], &resp))
+		names := []string{}
+		for _, tool := range resp.Result.Tools {
+			assert.NotEmpty(t, tool.Description)
+			assert.Equal(t, "object", tool.InputSchema["type"])
+			assert.Contains(t, tool.InputSchema["required"], "inbox")
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"list_inbox", "read_mail", "read_source", "wait_for_mail", "extract_links"}, names(false))
+	assert.Equal(t, []string{"list_inbox", "read_mail", "read_source", "wait_for_mail", "extract_links", "delete_mail"}, names(true))
+}
+
+func TestServerTools(t *testing.T) {
+	type scenario struct {
+		name          string
+		tool          string
+		arguments     string
+		allowDelete   bool
+		refreshError  error
+		expected      string
+		isError       bool
+		deletedIDs    []string
+		limitExpected int
+	}
+	scenarios := []scenario{
+		{
+			name:          "List an inbox",
+			tool:          "list_inbox",
+			arguments:     `{"inbox":"TEST","limit":5}`,
+			expected:      `{"name":"test","mails":[{"id":"a","sender":{"mail":"hello@acme.com","name":"Acme"},"subject":"Welcome","isSPAM":false}]}`,
+			limitExpected: 5,
+		},
+		{
+			name:      "List an inbox without name",
+			tool:      "list_inbox",
+			arguments: `{}`,
+			expected:  `argument "inbox" is required`,
+			isError:   true,
+		},
+		{
+			name:         "List an inbox failing",
+			tool:         "list_inbox",
+			arguments:    `{"inbox":"test"}`,
+			refreshError: errors.New("captcha"),
+			expected:     "captcha",
+			isError:      true,
+		},
+		{
+			name:      "Read an email",
+			tool:      "read_mail",
+			arguments: `{"inbox":"test","id":"a"}`,
+			expected:  `{"subject":"Welcome"}`,
+		},
+		{
+			name:      "Read an unknown email",
+			tool:      "read_mail",
+			arguments: `{"inbox":"test","id":"b"}`,
+			expected:  `failure when fetching email "b"`,
+			isError:   true,
+		},
+		{
+			name:      "Read an email without ID",
+			tool:      "read_mail",
+			arguments: `{"inbox":"test"}`,
+			expected:  `argument "id" is required`,
+			isError:   true,
+		},
+		{
+			name:      "Read a source",
+			tool:      "read_source",
+			arguments: `{"inbox":"test","id":"a"}`,
+			expected:  "Subject: Welcome\r\n\r\nWelcome",
+		},
+		{
+			name:      "Extract the links",
+			tool:      "extract_links",
+			arguments: `{"inbox":"test","id":"a","pattern":"verify"}`,
+			expected:  `[{"url":"https://acme.com/verify?token=1","text":"Verify"}]`,
+		},
+		{
+			name:      "Extract the links with an invalid pattern",
+			tool:      "extract_links",
+			arguments: `{"inbox":"test","id":"a","pattern":"("}`,
+			expected:  "pattern \"(\" is invalid : error parsing regexp: missing closing ): `(`",
+			isError:   true,
+		},
+		{
+			name:      "Wait for an existing email",
+			tool:      "wait_for_mail",
+			arguments: `{"inbox":"test","subject":"(?i)welcome","include_existing":true}`,
+			expected:  `{"subject":"Welcome"}`,
+		},
+		{
+			name:      "Wait for an email that never comes",
+			tool:      "wait_for_mail",
+			arguments: `{"inbox":"test","from":"acme","timeout":"10ms"}`,
+			expected:  `no email matched in inbox "test" within 10ms`,
+			isError:   true,
+		},
+		{
+			name:      "Wait with an invalid timeout",
+			tool:      "wait_for_mail",
+			arguments: `{"inbox":"test","timeout":"soon"}`,
+			expected:  `timeout "soon" must be a positive duration`,
+			isError:   true,
+		},
+		{
+			name:        "Delete an email",
+			tool:        "delete_mail",
+			arguments:   `{"inbox":"test","id":"a"}`,
+			allowDelete: true,
+			expected:    `Email "a" deleted`,
+			deletedIDs:  []string{"a"},
+		},
+		{
+			name:        "Delete an email not listed",
+			tool:        "delete_mail",
+			arguments:   `{"inbox":"test","id":"b"}`,
+			allowDelete: true,
+			expected:    `email "b" not found in the last 15 emails of inbox "test"`,
+			isError:     true,
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			in := newTestInbox()
+			in.RefreshError = scenario.refreshError
+			responses := run(t, newTestServer(in, scenario.allowDelete), call(1, scenario.tool, scenario.arguments))
+			assert.JSONEq(t, textResult(1, scenario.expected, scenario.isError), string(responses["1"]))
+			assert.Equal(t, scenario.deletedIDs, in.DeletedIDs)
+			if scenario.limitExpected != 0 {
+				assert.Equal(t, scenario.limitExpected, in.RefreshLimit)
+			}
+		})
+	}
+}
+
+func TestServerWaitForNewMail(t *testing.T) {
+	in := newTestInbox()
+	in.Lists = append([][]inbox.InboxItem{{}}, in.Lists...)
+	responses := run(t, newTestServer(in, false), call(1, "wait_for_mail", `{"inbox":"test","from":"Acme <hello@"}`))
+	assert.JSONEq(t, textResult(1, `{"subject":"Welcome"}`, false), string(responses["1"]))
+	assert.Equal(t, 2, in.RefreshCount)
+}
+
+func TestServerCancel(t *testing.T) {
+	in := newTestInbox()
+	in.Lists = [][]inbox.InboxItem{{}}
+	s := newTestServer(in, false)
+	s.config.MaxWait = time.Hour
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(context.Background(), serverReader, serverWriter)
+	}()
+
+	_, err := fmt.Fprintln(clientWriter, call(1, "wait_for_mail", `{"inbox":"test"}`))
+	require.NoError(t, err)
+	_, err = fmt.Fprintln(clientWriter, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	require.NoError(t, err)
+	r := bufio.NewReader(clientReader)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":{}}`, line)
+
+	_, err = fmt.Fprintln(clientWriter, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`)
+	require.NoError(t, err)
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	assert.JSONEq(t, textResult(1, "context canceled", true), line)
+
+	require.NoError(t, clientWriter.Close())
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,344 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/antham/yogo/v4/internal/render"
+)
+
+// tool is a tool provided to the clients, call returns the text given to the model
+type tool struct {
+	name        string
+	description string
+	schema      map[string]any
+	call        func(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// schema describes the arguments of a tool, inbox is always required
+func schema(properties map[string]any, required ...string) map[string]any {
+	properties["inbox"] = map[string]any{
+		"type":        "string",
+		"description": "Name of the inbox, e.g. helloworld or helloworld@yopmail.com",
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   append([]string{"inbox"}, required...),
+	}
+}
+
+var idProperty = map[string]any{
+	"type":        "string",
+	"description": "ID of the email as returned by list_inbox",
+}
+
+func (s *Server) newTools() []tool {
+	tools := []tool{
+		{
+			name:        "list_inbox",
+			description: "List the last emails of a yopmail inbox, the most recent first, with their ID, sender, subject and date",
+			schema: schema(map[string]any{
+				"limit": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": fmt.Sprintf("Number of emails listed, %d by default", s.config.Limit),
+				},
+			}),
+			call: s.listInbox,
+		},
+		{
+			name:        "read_mail",
+			description: "Read an email, the result holds its sender, subject, date, text content, links and attachments as JSON",
+			schema:      schema(map[string]any{"id": idProperty}, "id"),
+			call: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+				return s.readMail(arguments, false)
+			},
+		},
+		{
+			name:        "read_source",
+			description: "Read the raw source of an email with all its headers, e.g. to check authentication results",
+			schema:      schema(map[string]any{"id": idProperty}, "id"),
+			call: func(ctx context.Context, arguments json.RawMessage) (string, error) {
+				return s.readMail(arguments, true)
+			},
+		},
+		{
+			name:        "wait_for_mail",
+			description: "Wait for a new email whose sender and subject match the given regexps and read it, the emails already in the inbox are ignored unless include_existing is set",
+			schema: schema(map[string]any{
+				"from":             map[string]any{"type": "string", "description": "Regexp matched against the sender, e.g. (?i)acme"},
+				"subject":          map[string]any{"type": "string", "description": "Regexp matched against the subject, e.g. (?i)verify"},
+				"timeout":          map[string]any{"type": "string", "description": fmt.Sprintf("Maximum time to wait, e.g. 30s, capped to %s", s.config.MaxWait)},
+				"include_existing": map[string]any{"type": "boolean", "description": "Match the emails already in the inbox too"},
+			}),
+			call: s.waitForMail,
+		},
+		{
+			name:        "extract_links",
+			description: "Extract the links of an email, e.g. to find a confirmation link, optionally filtered by a regexp matched against the URLs",
+			schema: schema(map[string]any{
+				"id":      idProperty,
+				"pattern": map[string]any{"type": "string", "description": "Regexp matched against the URLs"},
+			}, "id"),
+			call: s.extractLinks,
+		},
+	}
+	if s.config.AllowDelete {
+		tools = append(tools, tool{
+			name:        "delete_mail",
+			description: "Delete an email, it must be one of the last emails of the inbox",
+			schema:      schema(map[string]any{"id": idProperty}, "id"),
+			call:        s.deleteMail,
+		})
+	}
+	return tools
+}
+
+type mailArguments struct {
+	Inbox string `json:"inbox"`
+	ID    string `json:"id"`
+}
+
+func decodeArguments(data json.RawMessage, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid arguments : %s", err)
+	}
+	return nil
+}
+
+func (s *Server) mailArguments(data json.RawMessage) (string, string, error) {
+	var arguments mailArguments
+	if err := decodeArguments(data, &arguments); err != nil {
+		return "", "", err
+	}
+	name := s.config.Name(arguments.Inbox)
+	if name == "" {
+		return "", "", errors.New(`argument "inbox" is required`)
+	}
+	if arguments.ID == "" {
+		return "", "", errors.New(`argument "id" is required`)
+	}
+	return name, arguments.ID, nil
+}
+
+func (s *Server) listInbox(ctx context.Context, data json.RawMessage) (string, error) {
+	var arguments struct {
+		Inbox string `json:"inbox"`
+		Limit int    `json:"limit"`
+	}
+	if err := decodeArguments(data, &arguments); err != nil {
+		return "", err
+	}
+	name := s.config.Name(arguments.Inbox)
+	if name == "" {
+		return "", errors.New(`argument "inbox" is required`)
+	}
+	limit := s.config.Limit
+	if arguments.Limit != 0 {
+		limit = arguments.Limit
+	}
+	if limit < 1 {
+		return "", fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+	}
+	var items []inbox.InboxItem
+	err := s.session.Do(name, false, func(in backend.Inbox) error {
+		if err := in.Refresh(limit); err != nil {
+			return err
+		}
+		items = in.GetMails()
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return marshal(struct {
+		Name  string            `json:"name"`
+		Mails []inbox.InboxItem `json:"mails"`
+	}{name, items})
+}
+
+func (s *Server) readMail(data json.RawMessage, source bool) (string, error) {
+	name, ID, err := s.mailArguments(data)
+	if err != nil {
+		return "", err
+	}
+	mail, err := s.fetch(name, ID, source)
+	if err != nil {
+		return "", err
+	}
+	if source {
+		return mail.Content(), nil
+	}
+	return mailJSON(mail)
+}
+
+func (s *Server) extractLinks(ctx context.Context, data json.RawMessage) (string, error) {
+	name, ID, err := s.mailArguments(data)
+	if err != nil {
+		return "", err
+	}
+	var arguments struct {
+		Pattern string `json:"pattern"`
+	}
+	if err := decodeArguments(data, &arguments); err != nil {
+		return "", err
+	}
+	var re *regexp.Regexp
+	if arguments.Pattern != "" {
+		if re, err = regexp.Compile(arguments.Pattern); err != nil {
+			return "", fmt.Errorf(`pattern "%s" is invalid : %s`, arguments.Pattern, err)
+		}
+	}
+	mail, err := s.fetch(name, ID, false)
+	if err != nil {
+		return "", err
+	}
+	links := []inbox.Link{}
+	for _, link := range mail.GetLinks() {
+		if re == nil || re.MatchString(link.URL) {
+			links = append(links, link)
+		}
+	}
+	return marshal(links)
+}
+
+func (s *Server) deleteMail(ctx context.Context, data json.RawMessage) (string, error) {
+	name, ID, err := s.mailArguments(data)
+	if err != nil {
+		return "", err
+	}
+	err = s.session.Do(name, false, func(in backend.Inbox) error {
+		// The email must be listed to be deleted
+		if err := in.Refresh(s.config.Limit); err != nil {
+			return err
+		}
+		for _, item := range in.GetMails() {
+			if item.ID == ID {
+				return in.DeleteByID(ID)
+			}
+		}
+		return fmt.Errorf(`email "%s" not found in the last %d emails of inbox "%s"`, ID, s.config.Limit, name)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`Email "%s" deleted`, ID), nil
+}
+
+// waitForMail polls the inbox until an email matching the filters arrives
+func (s *Server) waitForMail(ctx context.Context, data json.RawMessage) (string, error) {
+	var arguments struct {
+		Inbox           string `json:"inbox"`
+		From            string `json:"from"`
+		Subject         string `json:"subject"`
+		Timeout         string `json:"timeout"`
+		IncludeExisting bool   `json:"include_existing"`
+	}
+	if err := decodeArguments(data, &arguments); err != nil {
+		return "", err
+	}
+	name := s.config.Name(arguments.Inbox)
+	if name == "" {
+		return "", errors.New(`argument "inbox" is required`)
+	}
+	filters := map[string]*regexp.Regexp{}
+	for key, v := range map[string]string{"from": arguments.From, "subject": arguments.Subject} {
+		if v == "" {
+			continue
+		}
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return "", fmt.Errorf(`%s regexp "%s" is invalid : %s`, key, v, err)
+		}
+		filters[key] = re
+	}
+	timeout := s.config.MaxWait
+	if arguments.Timeout != "" {
+		d, err := time.ParseDuration(arguments.Timeout)
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf(`timeout "%s" must be a positive duration`, arguments.Timeout)
+		}
+		timeout = min(d, s.config.MaxWait)
+	}
+	match := func(item inbox.InboxItem) bool {
+		if re := filters["from"]; re != nil && !re.MatchString(item.Sender.String()) {
+			return false
+		}
+		if re := filters["subject"]; re != nil && !re.MatchString(item.Subject) {
+			return false
+		}
+		return true
+	}
+
+	seen := map[string]bool{}
+	deadline := time.After(timeout)
+	for first := true; ; first = false {
+		var found string
+		err := s.session.Do(name, false, func(in backend.Inbox) error {
+			if err := in.Refresh(s.config.Limit); err != nil {
+				return err
+			}
+			items := in.GetMails()
+			for index := len(items) - 1; index >= 0; index-- {
+				item := items[index]
+				if seen[item.ID] {
+					continue
+				}
+				seen[item.ID] = true
+				if found == "" && (!first || arguments.IncludeExisting) && match(item) {
+					found = item.ID
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		if found != "" {
+			mail, err := s.fetch(name, found, false)
+			if err != nil {
+				return "", err
+			}
+			return mailJSON(mail)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-deadline:
+			return "", fmt.Errorf("no email matched in inbox \"%s\" within %s", name, timeout)
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+func (s *Server) fetch(name string, ID string, source bool) (inbox.Mail, error) {
+	var mail inbox.Mail
+	err := s.session.Do(name, source, func(in backend.Inbox) error {
+		var err error
+		mail, err = in.FetchByID(ID)
+		return err
+	})
+	return mail, err
+}
+
+func mailJSON(mail inbox.Mail) (string, error) {
+	if j, ok := mail.(render.JSONer); ok {
+		return j.JSON()
+	}
+	return marshal(mail)
+}
+
+func marshal(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,186 @@
+// Package pop3 exposes the inboxes to the legacy mail clients
+// through a POP3 server, the user name selects the inbox
+package pop3
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+// Config defines how the inboxes are served
+type Config struct {
+	// Limit is the number of emails available in a maildrop
+	Limit int
+	// Name normalizes the user names
+	Name func(string) string
+}
+
+// maxSourcesSize bounds the memory used by the sources,
+// the sources fetched first are evicted first
+const maxSourcesSize = 32 << 20
+
+// sourceKey identifies the source of an email of an inbox
+type sourceKey struct {
+	name string
+	ID   string
+}
+
+// Server serves the sources of the emails of the inboxes as the maildrop
+// of a user, the sources are fetched on demand and the last ones are kept
+// in memory, the sizes are kept as long as the emails are listed
+type Server struct {
+	build  backend.Builder
+	config Config
+	// mu guards the inboxes, the sources and the sizes, and serializes the calls to the inboxes
+	mu             sync.Mutex
+	inboxes        map[string]backend.Inbox
+	sources        map[sourceKey][]byte
+	fetched        []sourceKey
+	sourcesSize    int
+	maxSourcesSize int
+	sizes          map[sourceKey]int
+	conns          backend.Conns
+}
+
+// New creates a server building its inboxes with build
+func New(build backend.Builder, config Config) *Server {
+	if config.Limit == 0 {
+		config.Limit = 50
+	}
+	if config.Name == nil {
+		config.Name = func(name string) string { return name }
+	}
+	return &Server{
+		build:          build,
+		config:         config,
+		inboxes:        map[string]backend.Inbox{},
+		sources:        map[sourceKey][]byte{},
+		maxSourcesSize: maxSourcesSize,
+		sizes:          map[sourceKey]int{},
+	}
+}
+
+// Serve accepts the connections until the server is closed
+func (s *Server) Serve(l net.Listener) error {
+	return s.conns.Serve(l, func(conn net.Conn) {
+		newSession(s, conn).serve()
+	})
+}
+
+// Close stops the listeners and closes the connections
+func (s *Server) Close() error {
+	return s.conns.Close()
+}
+
+// list returns the emails of an inbox, the oldest email comes first
+func (s *Server) list(name string) ([]inbox.InboxItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in, ok := s.inboxes[name]
+	if !ok {
+		var err error
+		if in, err = s.build(name, true); err != nil {
+			return nil, err
+		}
+		s.inboxes[name] = in
+	}
+	if err := in.Refresh(s.config.Limit); err != nil {
+		return nil, err
+	}
+	items := []inbox.InboxItem{}
+	present := map[string]bool{}
+	mails := in.GetMails()
+	for index := len(mails) - 1; index >= 0; index-- {
+		items = append(items, mails[index])
+		present[mails[index].ID] = true
+	}
+	for key := range s.sizes {
+		if key.name == name && !present[key.ID] {
+			s.forget(key)
+		}
+	}
+	return items, nil
+}
+
+// source returns the source of an email with CRLF line endings, it is
+// fetched again once evicted
+func (s *Server) source(name string, ID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := sourceKey{name: name, ID: ID}
+	if data, ok := s.sources[key]; ok {
+		return data, nil
+	}
+	mail, err := s.inboxes[name].FetchByID(ID)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := mail.(interface{ Message() []byte })
+	if !ok {
+		return nil, fmt.Errorf(`source of email "%s" is not available`, ID)
+	}
+	data := bytes.ReplaceAll(source.Message(), []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		data = append(data, "\r\n"...)
+	}
+	s.sizes[key] = len(data)
+	s.sources[key] = data
+	s.fetched = append(s.fetched, key)
+	s.sourcesSize += len(data)
+	for s.sourcesSize > s.maxSourcesSize && len(s.fetched) > 1 {
+		s.evict(s.fetched[0])
+	}
+	return data, nil
+}
+
+// size returns the exact size of the source of an email,
+// the source is fetched when its size is not known yet
+func (s *Server) size(name string, ID string) (int, error) {
+	s.mu.Lock()
+	size, ok := s.sizes[sourceKey{name: name, ID: ID}]
+	s.mu.Unlock()
+	if ok {
+		return size, nil
+	}
+	data, err := s.source(name, ID)
+	return len(data), err
+}
+
+// evict removes a source from memory, its size is kept
+func (s *Server) evict(key sourceKey) {
+	data, ok := s.sources[key]
+	if !ok {
+		return
+	}
+	delete(s.sources, key)
+	s.sourcesSize -= len(data)
+	for index, k := range s.fetched {
+		if k == key {
+			s.fetched = append(s.fetched[:index], s.fetched[index+1:]...)
+			break
+		}
+	}
+}
+
+// forget removes the source and the size of an email
+func (s *Server) forget(key sourceKey) {
+	s.evict(key)
+	delete(s.sizes, key)
+}
+
+// remove deletes an email from yopmail
+func (s *Server) remove(name string, ID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.inboxes[name].DeleteByID(ID); err != nil {
+		return err
+	}
+	s.forget(sourceKey{name: name, ID: ID})
+	return nil
+}
@@ -0,0 +1,166 @@
+package pop3
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/backend/backendtest"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// client sends commands to the server and reads the responses
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) readLine() string {
+	line, err := c.r.ReadString('\n')
+	require.NoError(c.t, err)
+	return strings.TrimSuffix(line, "\r\n")
+}
+
+// send runs a command, the lines of a multiline response
+// are returned until the termination line
+func (c *client) send(command string, multiline bool) []string {
+	_, err := fmt.Fprintf(c.conn, "%s\r\n", command)
+	require.NoError(c.t, err)
+	lines := []string{c.readLine()}
+	if !multiline || !strings.HasPrefix(lines[0], "+OK") {
+		return lines
+	}
+	for {
+		line := c.readLine()
+		lines = append(lines, line)
+		if line == "." {
+			return lines
+		}
+	}
+}
+
+func newTestInbox() *backendtest.Inbox {
+	return &backendtest.Inbox{
+		Lists: [][]inbox.InboxItem{{{ID: "news"}, {ID: "welcome"}}},
+		Mails: map[string]inbox.Mail{
+			"welcome": backendtest.Source{Raw: "Subject: Welcome\n\nWelcome\n.signature\n"},
+			"news":    backendtest.Source{Raw: "Subject: News\r\n\r\nLine 1\r\nLine 2\r\nLine 3"},
+		},
+	}
+}
+
+// startServer serves the inbox and returns a connected client
+func startServer(t *testing.T, in *backendtest.Inbox) *client {
+	s := New(func(name string, source bool) (backend.Inbox, error) {
+		if name != "test" {
+			return nil, fmt.Errorf(`inbox "%s" doesn't exist`, name)
+		}
+		return in, nil
+	}, Config{Name: strings.ToLower})
+	conn := backendtest.Dial(t, s)
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+	assert.Equal(t, "+OK Yogo POP3 server ready", c.readLine())
+	return c
+}
+
+func TestServerSession(t *testing.T) {
+	in := newTestInbox()
+	c := startServer(t, in)
+
+	assert.Equal(t, []string{"+OK Capability list follows", "USER", "UIDL", "TOP", "."}, c.send("CAPA", true))
+	assert.Equal(t, []string{"-ERR STAT is not allowed before authentication"}, c.send("STAT", false))
+	assert.Equal(t, []string{"-ERR USER must come first"}, c.send("PASS secret", false))
+	assert.Equal(t, []string{"+OK send the password, any password is accepted"}, c.send("USER unknown", false))
+	assert.Equal(t, []string{`-ERR inbox "unknown" doesn't exist`}, c.send("PASS secret", false))
+	c.send("USER TEST", false)
+	assert.Equal(t, []string{"+OK maildrop has 2 messages"}, c.send("PASS secret", false))
+
+	assert.Equal(t, []string{"+OK 2 82"}, c.send("STAT", false))
+	assert.Equal(t, []string{"+OK scan listing follows", "1 41", "2 41", "."}, c.send("LIST", true))
+	assert.Equal(t, []string{"+OK 2 41"}, c.send("LIST 2", false))
+	assert.Equal(t, []string{"+OK scan listing follows", "1 welcome", "2 news", "."}, c.send("UIDL", true))
+	assert.Equal(t, []string{"+OK 41 octets", "Subject: News", "", "Line 1", "Line 2", "Line 3", "."}, c.send("RETR 2", true))
+	assert.Equal(t, []string{"+OK 41 octets", "Subject: Welcome", "", "Welcome", "..signature", "."}, c.send("RETR 1", true))
+	assert.Equal(t, []string{"+OK top of message follows", "Subject: News", "", "Line 1", "."}, c.send("TOP 2 1", true))
+	assert.Equal(t, []string{`-ERR no such message "3"`}, c.send("RETR 3", false))
+	assert.Equal(t, []string{"-ERR unknown command APOP"}, c.send("APOP test digest", false))
+
+	assert.Equal(t, []string{"+OK message 1 deleted"}, c.send("DELE 1", false))
+	assert.Equal(t, []string{"-ERR message 1 already deleted"}, c.send("DELE 1", false))
+	assert.Equal(t, []string{"+OK scan listing follows", "2 news", "."}, c.send("UIDL", true))
+	assert.Equal(t, []string{"+OK maildrop has 2 messages"}, c.send("RSET", false))
+	c.send("DELE 2", false)
+	assert.Empty(t, in.DeletedIDs)
+	assert.Equal(t, []string{"+OK Yogo POP3 server signing off"}, c.send("QUIT", false))
+	assert.Equal(t, []string{"news"}, in.DeletedIDs)
+
+	// The sources are only fetched once
+	assert.Equal(t, []string{"welcome", "news"}, in.FetchedIDs)
+}
+
+func TestServerFailures(t *testing.T) {
+	in := newTestInbox()
+	in.Lists[0] = append(in.Lists[0], inbox.InboxItem{ID: "missing"})
+	c := startServer(t, in)
+
+	c.send("USER test", false)
+	c.send("PASS secret", false)
+	assert.Equal(t, []string{`-ERR failure when fetching email "missing"`}, c.send("RETR 1", false))
+	assert.Equal(t, []string{"+OK 2 82"}, c.send("STAT", false))
+	assert.Equal(t, []string{"+OK scan listing follows", "2 41", "3 41", "."}, c.send("LIST", true))
+	assert.Equal(t, []string{"-ERR message 1 is not available"}, c.send("LIST 1", false))
+	assert.Equal(t, []string{"-ERR message 1 is not available"}, c.send("TOP 1 0", false))
+	assert.Equal(t, []string{"-ERR a message number is required"}, c.send("RETR", false))
+
+	in.DeleteError = errors.New("delete failed")
+	c.send("DELE 2", false)
+	assert.Equal(t, []string{"-ERR 1 messages not removed"}, c.send("QUIT", false))
+}
+
+func TestServerRefreshFailure(t *testing.T) {
+	in := newTestInbox()
+	in.RefreshError = errors.New("captcha")
+	c := startServer(t, in)
+
+	c.send("USER test", false)
+	assert.Equal(t, []string{"-ERR captcha"}, c.send("PASS secret", false))
+	assert.Equal(t, []string{"+OK Yogo POP3 server signing off"}, c.send("QUIT", false))
+}
+
+func TestServerSources(t *testing.T) {
+	in := newTestInbox()
+	s := New(func(name string, source bool) (backend.Inbox, error) {
+		return in, nil
+	}, Config{})
+	s.maxSourcesSize = 50
+
+	_, err := s.list("test")
+	require.NoError(t, err)
+	_, err = s.source("test", "welcome")
+	require.NoError(t, err)
+	_, err = s.source("test", "news")
+	require.NoError(t, err)
+	assert.Len(t, s.sources, 1)
+	assert.Equal(t, 41, s.sourcesSize)
+
+	// the size of an evicted source is kept
+	size, err := s.size("test", "welcome")
+	require.NoError(t, err)
+	assert.Equal(t, 41, size)
+	assert.Equal(t, []string{"welcome", "news"}, in.FetchedIDs)
+
+	// the sources and the sizes of the emails not listed anymore are dropped
+	in.Lists = [][]inbox.InboxItem{{}}
+	_, err = s.list("test")
+	require.NoError(t, err)
+	assert.Empty(t, s.sources)
+	assert.Empty(t, s.sizes)
+	assert.Zero(t, s.sourcesSize)
+}
@@ -0,0 +1,351 @@
+package pop3
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+const (
+	// autoLogout is the inactivity delay after which a client is disconnected
+	autoLogout = 10 * time.Minute
+	// writeTimeout bounds the time spent sending a response
+	writeTimeout = time.Minute
+	// maxLineSize bounds the commands sent by the clients
+	maxLineSize = 512
+)
+
+// message is an email of the maildrop
+type message struct {
+	item    inbox.InboxItem
+	deleted bool
+	// unavailable is set when the source can't be fetched to
+	// list its size, the message leaves the maildrop
+	unavailable bool
+}
+
+// session is the connection of a client
+type session struct {
+	s    *Server
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	// user is set by USER and name once the maildrop is opened
+	user     string
+	name     string
+	messages []*message
+	done     bool
+}
+
+func newSession(s *Server, conn net.Conn) *session {
+	return &session{s: s, conn: conn, r: bufio.NewReaderSize(conn, maxLineSize), w: bufio.NewWriter(conn)}
+}
+
+func (c *session) serve() {
+	c.ok("Yogo POP3 server ready")
+	for !c.done {
+		if err := c.w.Flush(); err != nil {
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(autoLogout))
+		line, err := c.r.ReadSlice('\n')
+		if err != nil {
+			switch {
+			case errors.Is(err, bufio.ErrBufferFull):
+				c.fail("line too long")
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				c.fail("autologout, idle for too long")
+			}
+			_ = c.w.Flush()
+			return
+		}
+		_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 {
+			c.fail("empty command")
+			continue
+		}
+		c.run(strings.ToUpper(fields[0]), fields[1:])
+	}
+	_ = c.w.Flush()
+}
+
+func (c *session) run(command string, args []string) {
+	if command == "QUIT" {
+		c.quit()
+		return
+	}
+	if command == "CAPA" {
+		c.ok("Capability list follows")
+		c.writeMultiline([]byte("USER\r\nUIDL\r\nTOP\r\n"))
+		return
+	}
+	if c.name == "" {
+		switch command {
+		case "USER":
+			c.userCommand(args)
+		case "PASS":
+			c.pass()
+		default:
+			c.fail(fmt.Sprintf("%s is not allowed before authentication", command))
+		}
+		return
+	}
+	switch command {
+	case "STAT":
+		c.stat()
+	case "LIST":
+		c.list(args)
+	case "UIDL":
+		c.uidl(args)
+	case "RETR":
+		c.retr(args)
+	case "TOP":
+		c.top(args)
+	case "DELE":
+		c.dele(args)
+	case "RSET":
+		for _, m := range c.messages {
+			m.deleted = false
+		}
+		c.ok(fmt.Sprintf("maildrop has %d messages", len(c.messages)))
+	case "NOOP":
+		c.ok("")
+	default:
+		c.fail(fmt.Sprintf("unknown command %s", command))
+	}
+}
+
+func (c *session) ok(text string) {
+	c.writeLine(strings.TrimSpace("+OK " + text))
+}
+
+func (c *session) fail(text string) {
+	c.writeLine("-ERR " + text)
+}
+
+func (c *session) writeLine(line string) {
+	_, _ = c.w.WriteString(line + "\r\n")
+}
+
+// writeMultiline sends data ending with a line break, the lines
+// starting with the termination octet are byte-stuffed
+func (c *session) writeMultiline(data []byte) {
+	for _, line := range bytes.SplitAfter(data, []byte("\r\n")) {
+		if bytes.HasPrefix(line, []byte(".")) {
+			_ = c.w.WriteByte('.')
+		}
+		_, _ = c.w.Write(line)
+	}
+	c.writeLine(".")
+}
+
+func (c *session) userCommand(args []string) {
+	if len(args) != 1 {
+		c.fail("USER requires a name")
+		return
+	}
+	c.user = args[0]
+	c.ok("send the password, any password is accepted")
+}
+
+// pass opens the maildrop of the inbox named after the user,
+// the password is ignored
+func (c *session) pass() {
+	if c.user == "" {
+		c.fail("USER must come first")
+		return
+	}
+	name := c.s.config.Name(c.user)
+	c.user = ""
+	if name == "" {
+		c.fail("invalid user")
+		return
+	}
+	items, err := c.s.list(name)
+	if err != nil {
+		c.fail(err.Error())
+		return
+	}
+	c.name = name
+	for _, item := range items {
+		c.messages = append(c.messages, &message{item: item})
+	}
+	c.ok(fmt.Sprintf("maildrop has %d messages", len(c.messages)))
+}
+
+// stat counts the messages not deleted, the sources
+// are fetched once to compute the size of the maildrop
+func (c *session) stat() {
+	count, size := 0, 0
+	for _, m := range c.messages {
+		if m.deleted || m.unavailable {
+			continue
+		}
+		s, ok := c.size(m)
+		if !ok {
+			continue
+		}
+		count++
+		size += s
+	}
+	c.ok(fmt.Sprintf("%d %d", count, size))
+}
+
+func (c *session) list(args []string) {
+	c.scan(args, func(number int, m *message) (string, bool) {
+		size, ok := c.size(m)
+		return fmt.Sprintf("%d %d", number, size), ok
+	})
+}
+
+// uidl lists the yopmail IDs of the emails, they never change
+func (c *session) uidl(args []string) {
+	c.scan(args, func(number int, m *message) (string, bool) {
+		return fmt.Sprintf("%d %s", number, m.item.ID), true
+	})
+}
+
+// size returns the exact size of a message, a message whose source
+// can't be fetched leaves the maildrop as its size can't be listed
+func (c *session) size(m *message) (int, bool) {
+	size, err := c.s.size(c.name, m.item.ID)
+	if err != nil {
+		m.unavailable = true
+		return 0, false
+	}
+	return size, true
+}
+
+// scan answers a listing command, for a single message when
+// a number is given and for all the messages otherwise
+func (c *session) scan(args []string, line func(int, *message) (string, bool)) {
+	if len(args) > 0 {
+		number, m, err := c.message(args)
+		if err != nil {
+			c.fail(err.Error())
+			return
+		}
+		l, ok := line(number, m)
+		if !ok {
+			c.fail(fmt.Sprintf("message %d is not available", number))
+			return
+		}
+		c.ok(l)
+		return
+	}
+	lines := []string{}
+	for index, m := range c.messages {
+		if m.deleted || m.unavailable {
+			continue
+		}
+		if l, ok := line(index+1, m); ok {
+			lines = append(lines, l+"\r\n")
+		}
+	}
+	c.ok("scan listing follows")
+	c.writeMultiline([]byte(strings.Join(lines, "")))
+}
+
+func (c *session) retr(args []string) {
+	_, m, err := c.message(args)
+	if err != nil {
+		c.fail(err.Error())
+		return
+	}
+	data, err := c.s.source(c.name, m.item.ID)
+	if err != nil {
+		c.fail(err.Error())
+		return
+	}
+	c.ok(fmt.Sprintf("%d octets", len(data)))
+	c.writeMultiline(data)
+}
+
+// top sends the header and the first lines of the body of a message
+func (c *session) top(args []string) {
+	if len(args) != 2 {
+		c.fail("TOP requires a message number and a number of lines")
+		return
+	}
+	lines, err := strconv.Atoi(args[1])
+	if err != nil || lines < 0 {
+		c.fail(fmt.Sprintf(`invalid number of lines "%s"`, args[1]))
+		return
+	}
+	_, m, err := c.message(args[:1])
+	if err != nil {
+		c.fail(err.Error())
+		return
+	}
+	data, err := c.s.source(c.name, m.item.ID)
+	if err != nil {
+		c.fail(err.Error())
+		return
+	}
+	header, body, found := bytes.Cut(data, []byte("\r\n\r\n"))
+	top := append(bytes.Clone(header), "\r\n"...)
+	if found {
+		top = append(top, "\r\n"...)
+		bodyLines := bytes.SplitAfter(body, []byte("\r\n"))
+		top = append(top, bytes.Join(bodyLines[:min(lines, len(bodyLines))], nil)...)
+	}
+	c.ok("top of message follows")
+	c.writeMultiline(top)
+}
+
+// dele marks a message, it is deleted from yopmail when the session ends with QUIT
+func (c *session) dele(args []string) {
+	number, m, err := c.message(args)
+	if err != nil {
+		c.fail(err.Error())
+		return
+	}
+	m.deleted = true
+	c.ok(fmt.Sprintf("message %d deleted", number))
+}
+
+// quit deletes the marked messages when the maildrop is opened
+func (c *session) quit() {
+	c.done = true
+	failures := 0
+	for _, m := range c.messages {
+		if m.deleted {
+			if err := c.s.remove(c.name, m.item.ID); err != nil {
+				failures++
+			}
+		}
+	}
+	if failures > 0 {
+		c.fail(fmt.Sprintf("%d messages not removed", failures))
+		return
+	}
+	c.ok("Yogo POP3 server signing off")
+}
+
+// message returns the message designated by the first argument
+func (c *session) message(args []string) (int, *message, error) {
+	if len(args) != 1 {
+		return 0, nil, errors.New("a message number is required")
+	}
+	number, err := strconv.Atoi(args[0])
+	if err != nil || number < 1 || number > len(c.messages) {
+		return 0, nil, fmt.Errorf(`no such message "%s"`, args[0])
+	}
+	m := c.messages[number-1]
+	if m.deleted {
+		return 0, nil, fmt.Errorf("message %d already deleted", number)
+	}
+	if m.unavailable {
+		return 0, nil, fmt.Errorf("message %d is not available", number)
+	}
+	return number, m, nil
+}
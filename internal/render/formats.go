@@ -0,0 +1,150 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+func renderText(v any) (string, error) {
+	c, ok := v.(Colourer)
+	if !ok {
+		return "", unsupported("text")
+	}
+	return c.Coloured()
+}
+
+func renderJSON(v any) (string, error) {
+	if j, ok := v.(JSONer); ok {
+		return j.JSON()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.New("something wrong occurred")
+	}
+	return string(data), nil
+}
+
+func renderNDJSON(v any) (string, error) {
+	l, ok := v.(Lister)
+	if !ok {
+		return renderJSON(v)
+	}
+	lines := []string{}
+	for _, item := range l.Items() {
+		line, err := renderJSON(item)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// renderYAML converts the JSON representation, the fields are named
+// and ordered as in the JSON output, JSON being a subset of YAML
+func renderYAML(v any) (string, error) {
+	data, err := renderJSON(v)
+	if err != nil {
+		return "", err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &node); err != nil {
+		return "", err
+	}
+	resetStyle(&node)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// resetStyle drops the flow and quoting styles inherited from JSON
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		resetStyle(n)
+	}
+}
+
+func renderCSV(v any) (string, error) {
+	t, ok := v.(Tabler)
+	if !ok {
+		return "", unsupported("csv")
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(t.Header()); err != nil {
+		return "", err
+	}
+	if err := w.WriteAll(t.Rows()); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func renderTable(v any) (string, error) {
+	t, ok := v.(Tabler)
+	if !ok {
+		return "", unsupported("table")
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	header := []string{}
+	for _, column := range t.Header() {
+		header = append(header, strings.ToUpper(column))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range t.Rows() {
+		cells := []string{}
+		// a line break would break the alignment of the columns
+		for _, cell := range row {
+			cells = append(cells, strings.Join(strings.Fields(cell), " "))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// newTemplateRenderer renders a Go template, a value made of
+// items is rendered one item per line (e.g. an inbox listing)
+func newTemplateRenderer(text string) (Renderer, error) {
+	if text == "" {
+		return nil, errors.New(`output "template" requires a template, e.g. template={{.Subject}}`)
+	}
+	tpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template is invalid : %w", err)
+	}
+	return RendererFunc(func(v any) (string, error) {
+		items := []any{v}
+		if l, ok := v.(Lister); ok {
+			items = l.Items()
+		}
+		lines := []string{}
+		for _, item := range items {
+			var buf bytes.Buffer
+			if err := tpl.Execute(&buf, item); err != nil {
+				return "", err
+			}
+			lines = append(lines, strings.TrimSuffix(buf.String(), "\n"))
+		}
+		return strings.Join(lines, "\n"), nil
+	}), nil
+}
@@ -0,0 +1,116 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultOutput is the output used when none is provided
+const DefaultOutput = "text"
+
+// Renderer renders a value in an output format
+type Renderer interface {
+	Render(v any) (string, error)
+}
+
+// RendererFunc is a function used as a Renderer
+type RendererFunc func(v any) (string, error)
+
+// Render renders the value
+func (f RendererFunc) Render(v any) (string, error) {
+	return f(v)
+}
+
+// Factory creates a renderer, arg is the part of
+// the output following the "=" sign (e.g. template=<arg>)
+type Factory func(arg string) (Renderer, error)
+
+// Colourer is a value rendered for a terminal
+type Colourer interface {
+	Coloured() (string, error)
+}
+
+// JSONer is a value providing its own JSON representation
+type JSONer interface {
+	JSON() (string, error)
+}
+
+// Lister is a value made of items, the streaming
+// formats and the templates render them one by one
+type Lister interface {
+	Items() []any
+}
+
+// Tabler is a value rendered as rows of columns
+type Tabler interface {
+	Header() []string
+	Rows() [][]string
+}
+
+var registry = map[string]Factory{}
+
+// Register makes a renderer available under a name
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// Names returns the names of the registered renderers
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the renderer matching an output, an output
+// is a renderer name optionally followed by "=" and an argument
+func New(output string) (Renderer, error) {
+	if output == "" {
+		output = DefaultOutput
+	}
+	name, arg, _ := strings.Cut(output, "=")
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf(`output "%s" is not supported, use one of %s`, name, strings.Join(Names(), ", "))
+	}
+	return factory(arg)
+}
+
+func init() {
+	Register("text", noArgument("text", renderText))
+	Register("json", noArgument("json", renderJSON))
+	Register("ndjson", noArgument("ndjson", renderNDJSON))
+	Register("yaml", noArgument("yaml", renderYAML))
+	Register("csv", noArgument("csv", renderCSV))
+	Register("table", noArgument("table", renderTable))
+	Register("template", func(arg string) (Renderer, error) {
+		return newTemplateRenderer(arg)
+	})
+	Register("template-file", func(arg string) (Renderer, error) {
+		if arg == "" {
+			return nil, fmt.Errorf(`output "template-file" requires a path, e.g. template-file=<path>`)
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		return newTemplateRenderer(string(data))
+	})
+}
+
+func noArgument(name string, f RendererFunc) Factory {
+	return func(arg string) (Renderer, error) {
+		if arg != "" {
+			return nil, fmt.Errorf(`output "%s" doesn't take any argument`, name)
+		}
+		return f, nil
+	}
+}
+
+func unsupported(name string) error {
+	return fmt.Errorf(`output "%s" is not supported for this command`, name)
+}
@@ -0,0 +1,212 @@
+package render
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	ID      string `json:"id"`
+	Subject string `json:"subject"`
+	Version string `json:"version,omitempty"`
+}
+
+type listing struct {
+	Name  string `json:"name"`
+	Mails []item `json:"mails"`
+}
+
+func (l listing) Coloured() (string, error) {
+	return "coloured", nil
+}
+
+func (l listing) Items() []any {
+	items := []any{}
+	for _, i := range l.Mails {
+		items = append(items, i)
+	}
+	return items
+}
+
+func (l listing) Header() []string {
+	return []string{"id", "subject"}
+}
+
+func (l listing) Rows() [][]string {
+	rows := [][]string{}
+	for _, i := range l.Mails {
+		rows = append(rows, []string{i.ID, i.Subject})
+	}
+	return rows
+}
+
+type document struct{}
+
+func (d document) JSON() (string, error) {
+	return `{"z":"last","a":["1.0",true]}`, nil
+}
+
+func TestRender(t *testing.T) {
+	type scenario struct {
+		name        string
+		output      string
+		value       any
+		expected    string
+		errExpected error
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "template")
+	assert.NoError(t, os.WriteFile(path, []byte("{{.ID}}\n"), 0o644))
+	l := listing{Name: "test", Mails: []item{{ID: "1", Subject: "Hello, world"}, {ID: "2", Subject: "Multi\nline", Version: "1.0"}}}
+
+	scenarios := []scenario{
+		{
+			name:     "Default output",
+			value:    l,
+			expected: "coloured",
+		},
+		{
+			name:        "Unknown output",
+			output:      "xml",
+			errExpected: errors.New(`output "xml" is not supported, use one of csv, json, ndjson, table, template, template-file, text, yaml`),
+		},
+		{
+			name:        "Unexpected argument",
+			output:      "json=test",
+			errExpected: errors.New(`output "json" doesn't take any argument`),
+		},
+		{
+			name:        "Text is not supported",
+			output:      "text",
+			value:       item{},
+			errExpected: errors.New(`output "text" is not supported for this command`),
+		},
+		{
+			name:     "JSON",
+			output:   "json",
+			value:    l,
+			expected: `{"name":"test","mails":[{"id":"1","subject":"Hello, world"},{"id":"2","subject":"Multi\nline","version":"1.0"}]}`,
+		},
+		{
+			name:     "JSON provided by the value",
+			output:   "json",
+			value:    document{},
+			expected: `{"z":"last","a":["1.0",true]}`,
+		},
+		{
+			name:   "NDJSON",
+			output: "ndjson",
+			value:  l,
+			expected: `{"id":"1","subject":"Hello, world"}
+{"id":"2","subject":"Multi\nline","version":"1.0"}`,
+		},
+		{
+			name:     "NDJSON of a single value",
+			output:   "ndjson",
+			value:    document{},
+			expected: `{"z":"last","a":["1.0",true]}`,
+		},
+		{
+			name:   "YAML",
+			output: "yaml",
+			value:  l,
+			expected: `name: test
+mails:
+  - id: "1"
+    subject: Hello, world
+  - id: "2"
+    subject: |-
+      Multi
+      line
+    version: "1.0"`,
+		},
+		{
+			name:   "YAML keeps the JSON order",
+			output: "yaml",
+			value:  document{},
+			expected: `z: last
+a:
+  - "1.0"
+  - true`,
+		},
+		{
+			name:   "CSV",
+			output: "csv",
+			value:  l,
+			expected: `id,subject
+1,"Hello, world"
+2,"Multi
+line"`,
+		},
+		{
+			name:        "CSV is not supported",
+			output:      "csv",
+			value:       document{},
+			errExpected: errors.New(`output "csv" is not supported for this command`),
+		},
+		{
+			name:   "Table",
+			output: "table",
+			value:  l,
+			expected: `ID  SUBJECT
+1   Hello, world
+2   Multi line`,
+		},
+		{
+			name:     "Template",
+			output:   "template={{.ID}}: {{.Subject | printf \"%q\"}}",
+			value:    l,
+			expected: "1: \"Hello, world\"\n2: \"Multi\\nline\"",
+		},
+		{
+			name:     "Template of a single value",
+			output:   "template={{.ID}}",
+			value:    item{ID: "3"},
+			expected: "3",
+		},
+		{
+			name:        "Missing template",
+			output:      "template",
+			errExpected: errors.New(`output "template" requires a template, e.g. template={{.Subject}}`),
+		},
+		{
+			name:        "Invalid template",
+			output:      "template={{.ID",
+			errExpected: errors.New(`template is invalid : template: output:1: unclosed action`),
+		},
+		{
+			name:     "Template file",
+			output:   "template-file=" + path,
+			value:    l,
+			expected: "1\n2",
+		},
+		{
+			name:        "Missing template file",
+			output:      "template-file=" + filepath.Join(dir, "missing"),
+			errExpected: errors.New("open " + filepath.Join(dir, "missing") + ": no such file or directory"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			renderer, err := New(scenario.output)
+			if err == nil {
+				var s string
+				s, err = renderer.Render(scenario.value)
+				assert.Equal(t, scenario.expected, s)
+			}
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+// Package server exposes the inboxes through an HTTP REST API
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/antham/yogo/v4/internal/render"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Config defines the limits of the server
+type Config struct {
+	// Limit is the number of emails listed by default
+	Limit int
+	// PollInterval is the delay between two refreshes of a waiting request
+	PollInterval time.Duration
+	// MaxWait caps the duration of a waiting request
+	MaxWait time.Duration
+	// Name normalizes the inbox names found in the URLs
+	Name func(string) string
+}
+
+// Server serves the inboxes through the routes described in openapi.json
+type Server struct {
+	session *backend.Session
+	config  Config
+	mux     *http.ServeMux
+}
+
+// New creates a server building its inboxes with build
+func New(build backend.Builder, config Config) *Server {
+	if config.Name == nil {
+		config.Name = func(name string) string { return name }
+	}
+	s := &Server{session: backend.NewSession(build), config: config, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /openapi.json", s.openAPI)
+	s.mux.HandleFunc("GET /inboxes/{name}/mails", s.list)
+	s.mux.HandleFunc("DELETE /inboxes/{name}/mails", s.flush)
+	s.mux.HandleFunc("GET /inboxes/{name}/mails/{id}", s.get)
+	s.mux.HandleFunc("DELETE /inboxes/{name}/mails/{id}", s.delete)
+	s.mux.HandleFunc("GET /inboxes/{name}/wait", s.wait)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// badRequestError is returned by the handlers when a parameter is invalid
+type badRequestError struct {
+	msg string
+}
+
+func (e badRequestError) Error() string {
+	return e.msg
+}
+
+// notFoundError is returned by the handlers when an email doesn't exist
+type notFoundError struct {
+	msg string
+}
+
+func (e notFoundError) Error() string {
+	return e.msg
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPI)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	name := s.config.Name(r.PathValue("name"))
+	limit, err := s.limit(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var items []inbox.InboxItem
+	err = s.session.Do(name, false, func(in backend.Inbox) error {
+		if err := in.Refresh(limit); err != nil {
+			return err
+		}
+		items = in.GetMails()
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Name  string            `json:"name"`
+		Mails []inbox.InboxItem `json:"mails"`
+	}{name, items})
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	name := s.config.Name(r.PathValue("name"))
+	kind, err := mailKind(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeMail(w, name, r.PathValue("id"), kind)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	name := s.config.Name(r.PathValue("name"))
+	ID := r.PathValue("id")
+	limit, err := s.limit(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = s.session.Do(name, false, func(in backend.Inbox) error {
+		// The email must be listed to be deleted
+		if err := in.Refresh(limit); err != nil {
+			return err
+		}
+		for _, item := range in.GetMails() {
+			if item.ID == ID {
+				return in.DeleteByID(ID)
+			}
+		}
+		return notFoundError{fmt.Sprintf(`email "%s" not found in the last %d emails of inbox "%s"`, ID, limit, name)}
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) flush(w http.ResponseWriter, r *http.Request) {
+	name := s.config.Name(r.PathValue("name"))
+	err := s.session.Do(name, false, func(in backend.Inbox) error {
+		if err := in.Refresh(1); err != nil {
+			return err
+		}
+		return in.Flush()
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// wait holds the request until an email matching the filters
+// arrives, the emails already in the inbox are ignored unless
+// include-existing is enabled
+func (s *Server) wait(w http.ResponseWriter, r *http.Request) {
+	name := s.config.Name(r.PathValue("name"))
+	kind, err := mailKind(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	filters := map[string]*regexp.Regexp{}
+	for _, key := range []string{"from", "subject"} {
+		if v := r.URL.Query().Get(key); v != "" {
+			if filters[key], err = regexp.Compile(v); err != nil {
+				writeError(w, badRequestError{fmt.Sprintf(`%s regexp "%s" is invalid : %s`, key, v, err)})
+				return
+			}
+		}
+	}
+	timeout := s.config.MaxWait
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, badRequestError{fmt.Sprintf(`timeout "%s" must be a positive duration`, v)})
+			return
+		}
+		timeout = min(d, s.config.MaxWait)
+	}
+	includeExisting := r.URL.Query().Get("include-existing") == "true"
+	match := func(item inbox.InboxItem) bool {
+		if re := filters["from"]; re != nil && !re.MatchString(item.Sender.String()) {
+			return false
+		}
+		if re := filters["subject"]; re != nil && !re.MatchString(item.Subject) {
+			return false
+		}
+		return true
+	}
+
+	seen := map[string]bool{}
+	deadline := time.After(timeout)
+	for first := true; ; first = false {
+		var found string
+		err := s.session.Do(name, false, func(in backend.Inbox) error {
+			if err := in.Refresh(s.config.Limit); err != nil {
+				return err
+			}
+			items := in.GetMails()
+			for index := len(items) - 1; index >= 0; index-- {
+				item := items[index]
+				if seen[item.ID] {
+					continue
+				}
+				seen[item.ID] = true
+				if found == "" && (!first || includeExisting) && match(item) {
+					found = item.ID
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if found != "" {
+			s.writeMail(w, name, found, kind)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+// writeMail outputs an email as JSON, the text kind
+// outputs the content of the email as plain text
+func (s *Server) writeMail(w http.ResponseWriter, name string, ID string, kind string) {
+	var mail inbox.Mail
+	err := s.session.Do(name, kind == "source", func(in backend.Inbox) error {
+		var err error
+		mail, err = in.FetchByID(ID)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if kind == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(mail.Content()))
+		return
+	}
+	if j, ok := mail.(render.JSONer); ok {
+		data, err := j.JSON()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(data))
+		return
+	}
+	writeJSON(w, http.StatusOK, mail)
+}
+
+func (s *Server) limit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return s.config.Limit, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 {
+		return 0, badRequestError{fmt.Sprintf(`limit "%s" must be a number greater than 0`, v)}
+	}
+	return limit, nil
+}
+
+func mailKind(r *http.Request) (string, error) {
+	kind := r.URL.Query().Get("kind")
+	switch kind {
+	case "":
+		return "html", nil
+	case "html", "source", "text":
+		return kind, nil
+	}
+	return "", badRequestError{fmt.Sprintf(`kind "%s" must be one of html, source or text`, kind)}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps an error to a status code, the errors
+// coming from yopmail are reported as a bad gateway
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	var badRequest badRequestError
+	var notFound notFoundError
+	switch {
+	case errors.As(err, &badRequest):
+		status = http.StatusBadRequest
+	case errors.As(err, &notFound):
+		status = http.StatusNotFound
+	case errors.Is(err, client.ErrCaptcha), errors.Is(err, client.ErrOffline):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
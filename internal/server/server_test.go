@@ -0,0 +1,292 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/backend/backendtest"
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(in *backendtest.Inbox, sources *backendtest.Inbox) (*httptest.Server, *[]string) {
+	built := []string{}
+	s := New(func(name string, source bool) (backend.Inbox, error) {
+		built = append(built, fmt.Sprintf("%s:%t", name, source))
+		if name == "broken" {
+			return nil, errors.New("inbox builder error")
+		}
+		if source {
+			return sources, nil
+		}
+		return in, nil
+	}, Config{Limit: 15, PollInterval: 10 * time.Millisecond, MaxWait: time.Second, Name: strings.ToLower})
+	return httptest.NewServer(s), &built
+}
+
+func request(t *testing.T, method string, URL string) (int, string, string) {
+	req, err := http.NewRequest(method, URL, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, resp.Header.Get("Content-Type"), string(body)
+}
+
+func TestServer(t *testing.T) {
+	items := []inbox.InboxItem{
+		{ID: "b", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme", Mail: "hello@acme.com"}},
+		{ID: "a", Subject: "Newsletter"},
+	}
+	type scenario struct {
+		name         string
+		method       string
+		path         string
+		in           *backendtest.Inbox
+		status       int
+		contentType  string
+		body         string
+		built        []string
+		refreshLimit int
+		deletedIDs   []string
+		flushed      bool
+	}
+	scenarios := []scenario{
+		{
+			name:         "List the emails",
+			method:       http.MethodGet,
+			path:         "/inboxes/Test/mails?limit=2",
+			status:       http.StatusOK,
+			contentType:  "application/json",
+			body:         `{"name":"test","mails":[{"id":"b","sender":{"mail":"hello@acme.com","name":"Acme"},"subject":"Welcome","isSPAM":false},{"id":"a","subject":"Newsletter","isSPAM":false}]}` + "\n",
+			built:        []string{"test:false"},
+			refreshLimit: 2,
+		},
+		{
+			name:        "Invalid limit",
+			method:      http.MethodGet,
+			path:        "/inboxes/test/mails?limit=0",
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+			body:        `{"error":"limit \"0\" must be a number greater than 0"}` + "\n",
+			built:       []string{},
+		},
+		{
+			name:        "Inbox can't be built",
+			method:      http.MethodGet,
+			path:        "/inboxes/broken/mails",
+			status:      http.StatusBadGateway,
+			contentType: "application/json",
+			body:        `{"error":"inbox builder error"}` + "\n",
+			built:       []string{"broken:false"},
+		},
+		{
+			name:         "CAPTCHA",
+			method:       http.MethodGet,
+			path:         "/inboxes/test/mails",
+			in:           &backendtest.Inbox{RefreshError: client.ErrCaptcha},
+			status:       http.StatusServiceUnavailable,
+			contentType:  "application/json",
+			body:         `{"error":"failure when trying to access content: a CAPTCHA is probably activated, look to the web interface"}` + "\n",
+			built:        []string{"test:false"},
+			refreshLimit: 15,
+		},
+		{
+			name:        "Read an email",
+			method:      http.MethodGet,
+			path:        "/inboxes/test/mails/b",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"id":"b","body":"hello"}`,
+			built:       []string{"test:false"},
+		},
+		{
+			name:        "Read the source of an email",
+			method:      http.MethodGet,
+			path:        "/inboxes/test/mails/b?kind=source",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"id":"b","headers":{}}`,
+			built:       []string{"test:true"},
+		},
+		{
+			name:        "Read the text of an email",
+			method:      http.MethodGet,
+			path:        "/inboxes/test/mails/b?kind=text",
+			status:      http.StatusOK,
+			contentType: "text/plain; charset=utf-8",
+			body:        "hello",
+			built:       []string{"test:false"},
+		},
+		{
+			name:        "Invalid kind",
+			method:      http.MethodGet,
+			path:        "/inboxes/test/mails/b?kind=pdf",
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+			body:        `{"error":"kind \"pdf\" must be one of html, source or text"}` + "\n",
+			built:       []string{},
+		},
+		{
+			name:        "Email can't be fetched",
+			method:      http.MethodGet,
+			path:        "/inboxes/test/mails/z",
+			status:      http.StatusBadGateway,
+			contentType: "application/json",
+			body:        `{"error":"failure when fetching email \"z\""}` + "\n",
+			built:       []string{"test:false"},
+		},
+		{
+			name:         "Delete an email",
+			method:       http.MethodDelete,
+			path:         "/inboxes/test/mails/a",
+			status:       http.StatusNoContent,
+			built:        []string{"test:false"},
+			refreshLimit: 15,
+			deletedIDs:   []string{"a"},
+		},
+		{
+			name:         "Delete an unknown email",
+			method:       http.MethodDelete,
+			path:         "/inboxes/test/mails/z?limit=5",
+			status:       http.StatusNotFound,
+			contentType:  "application/json",
+			body:         `{"error":"email \"z\" not found in the last 5 emails of inbox \"test\""}` + "\n",
+			built:        []string{"test:false"},
+			refreshLimit: 5,
+		},
+		{
+			name:         "Flush the inbox",
+			method:       http.MethodDelete,
+			path:         "/inboxes/test/mails",
+			status:       http.StatusNoContent,
+			built:        []string{"test:false"},
+			refreshLimit: 1,
+			flushed:      true,
+		},
+		{
+			name:        "Unknown route",
+			method:      http.MethodPost,
+			path:        "/inboxes/test/mails",
+			status:      http.StatusMethodNotAllowed,
+			contentType: "text/plain; charset=utf-8",
+			body:        "Method Not Allowed\n",
+			built:       []string{},
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			in := scenario.in
+			if in == nil {
+				in = &backendtest.Inbox{
+					Lists: [][]inbox.InboxItem{items},
+					Mails: map[string]inbox.Mail{"b": backendtest.Mail{Text: "hello", Doc: `{"id":"b","body":"hello"}`}},
+				}
+			}
+			sources := &backendtest.Inbox{Mails: map[string]inbox.Mail{"b": backendtest.Mail{Doc: `{"id":"b","headers":{}}`}}}
+			server, built := newTestServer(in, sources)
+			defer server.Close()
+
+			status, contentType, body := request(t, scenario.method, server.URL+scenario.path)
+			assert.Equal(t, scenario.status, status)
+			assert.Equal(t, scenario.contentType, contentType)
+			assert.Equal(t, scenario.body, body)
+			assert.Equal(t, scenario.built, *built)
+			assert.Equal(t, scenario.refreshLimit, in.RefreshLimit)
+			assert.Equal(t, scenario.deletedIDs, in.DeletedIDs)
+			assert.Equal(t, scenario.flushed, in.Flushed)
+		})
+	}
+}
+
+func TestServerWait(t *testing.T) {
+	existing := inbox.InboxItem{ID: "a", Subject: "Verify your account"}
+	other := inbox.InboxItem{ID: "b", Subject: "Newsletter", Sender: &inbox.Sender{Name: "Acme"}}
+	expected := inbox.InboxItem{ID: "c", Subject: "Verify your account", Sender: &inbox.Sender{Name: "Acme"}}
+	type scenario struct {
+		name   string
+		query  string
+		status int
+		body   string
+	}
+	scenarios := []scenario{
+		{
+			name:   "New email received",
+			query:  "?subject=Verify&from=Acme",
+			status: http.StatusOK,
+			body:   `{"id":"c"}`,
+		},
+		{
+			name:   "Existing email included",
+			query:  "?subject=Verify&include-existing=true",
+			status: http.StatusOK,
+			body:   `{"id":"a"}`,
+		},
+		{
+			name:   "Text of the email",
+			query:  "?subject=Verify&kind=text",
+			status: http.StatusOK,
+			body:   "code: 123456",
+		},
+		{
+			name:   "Timeout reached",
+			query:  "?subject=Unknown&timeout=100ms",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "Invalid regexp",
+			query:  "?subject=[",
+			status: http.StatusBadRequest,
+			body:   `{"error":"subject regexp \"[\" is invalid : error parsing regexp: missing closing ]: ` + "`[`" + `"}` + "\n",
+		},
+		{
+			name:   "Invalid timeout",
+			query:  "?timeout=soon",
+			status: http.StatusBadRequest,
+			body:   `{"error":"timeout \"soon\" must be a positive duration"}` + "\n",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			in := &backendtest.Inbox{
+				Lists: [][]inbox.InboxItem{{existing}, {other, existing}, {expected, other, existing}},
+				Mails: map[string]inbox.Mail{
+					"a": backendtest.Mail{Doc: `{"id":"a"}`},
+					"c": backendtest.Mail{Doc: `{"id":"c"}`, Text: "code: 123456"},
+				},
+			}
+			server, _ := newTestServer(in, &backendtest.Inbox{})
+			defer server.Close()
+
+			status, _, body := request(t, http.MethodGet, server.URL+"/inboxes/test/wait"+scenario.query)
+			assert.Equal(t, scenario.status, status)
+			assert.Equal(t, scenario.body, body)
+		})
+	}
+}
+
+func TestServerOpenAPI(t *testing.T) {
+	server, _ := newTestServer(&backendtest.Inbox{}, &backendtest.Inbox{})
+	defer server.Close()
+
+	status, contentType, body := request(t, http.MethodGet, server.URL+"/openapi.json")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, string(openAPI), body)
+	assert.Contains(t, body, `"/inboxes/{name}/wait"`)
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+// historySize is the number of emails remembered per inbox
+// to resume the streams from the last event received
+const historySize = 500
+
+// StreamConfig defines how the inboxes are polled
+type StreamConfig struct {
+	// Limit is the number of emails listed at each poll
+	Limit int
+	// PollInterval is the delay between two polls of an inbox
+	PollInterval time.Duration
+	// KeepAlive is the delay between two comments sent to keep the connections open
+	KeepAlive time.Duration
+	// Name normalizes the inbox names found in the URLs
+	Name func(string) string
+}
+
+// event is a server-sent event
+type event struct {
+	id   string
+	name string
+	data []byte
+}
+
+// Stream sends the new emails of the inboxes as server-sent events,
+// an inbox is polled once whatever the number of clients following it
+type Stream struct {
+	session *backend.Session
+	config  StreamConfig
+	// mu guards the feeds
+	mu    sync.Mutex
+	feeds map[string]*feed
+	mux   *http.ServeMux
+}
+
+// feed polls an inbox while clients are following it
+type feed struct {
+	name        string
+	ready       chan struct{}
+	cancel      context.CancelFunc
+	history     []inbox.InboxItem
+	subscribers map[chan event]struct{}
+}
+
+// NewStream creates a stream building its inboxes with build
+func NewStream(build backend.Builder, config StreamConfig) *Stream {
+	if config.Name == nil {
+		config.Name = func(name string) string { return name }
+	}
+	if config.KeepAlive == 0 {
+		config.KeepAlive = 30 * time.Second
+	}
+	s := &Stream{session: backend.NewSession(build), config: config, feeds: map[string]*feed{}, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /inboxes/{name}/events", s.events)
+	return s
+}
+
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// events follows an inbox, when the client provides the ID of the last
+// event it received the emails arrived since are sent first
+func (s *Stream) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	name := s.config.Name(r.PathValue("name"))
+	f, ch := s.subscribe(name)
+	defer s.unsubscribe(f, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	select {
+	case <-f.ready:
+	case <-r.Context().Done():
+		return
+	}
+	// An email replayed can also be waiting in the channel
+	replayed := map[string]bool{}
+	for _, item := range s.replay(f, r.Header.Get("Last-Event-ID")) {
+		if err := writeEvent(w, newMailEvent(item)); err != nil {
+			return
+		}
+		replayed[item.ID] = true
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(s.config.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if replayed[e.id] {
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// subscribe registers a client, the inbox
+// starts being polled with the first client
+func (s *Stream) subscribe(name string) (*feed, chan event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.feeds[name]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &feed{name: name, ready: make(chan struct{}), cancel: cancel, subscribers: map[chan event]struct{}{}}
+		s.feeds[name] = f
+		go s.poll(ctx, f)
+	}
+	// A client too slow to consume its events is disconnected
+	ch := make(chan event, 64)
+	f.subscribers[ch] = struct{}{}
+	return f, ch
+}
+
+// unsubscribe removes a client, the inbox stops
+// being polled when nobody follows it anymore
+func (s *Stream) unsubscribe(f *feed, ch chan event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := f.subscribers[ch]; !ok {
+		return
+	}
+	delete(f.subscribers, ch)
+	s.release(f)
+}
+
+// release stops polling an inbox nobody follows, the stream lock must be held
+func (s *Stream) release(f *feed) {
+	if len(f.subscribers) == 0 && s.feeds[f.name] == f {
+		f.cancel()
+		delete(s.feeds, f.name)
+	}
+}
+
+// replay returns the emails received after the one with the given
+// ID, nothing is returned when this email is not known
+func (s *Stream) replay(f *feed, lastEventID string) []inbox.InboxItem {
+	if lastEventID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := slices.IndexFunc(f.history, func(item inbox.InboxItem) bool {
+		return item.ID == lastEventID
+	})
+	if index == -1 {
+		return nil
+	}
+	return slices.Clone(f.history[index+1:])
+}
+
+// poll lists the inbox until the context is cancelled, the first
+// successful listing is only recorded as the emails received before the
+// clients connected are not sent unless a client resumes from one of them
+func (s *Stream) poll(ctx context.Context, f *feed) {
+	first := true
+	for {
+		items, err := s.list(f.name)
+		s.mu.Lock()
+		switch {
+		case err != nil:
+			data, _ := json.Marshal(struct {
+				Error string `json:"error"`
+			}{err.Error()})
+			s.broadcast(f, event{name: "error", data: data})
+		default:
+			for index := len(items) - 1; index >= 0; index-- {
+				item := items[index]
+				if slices.ContainsFunc(f.history, func(known inbox.InboxItem) bool { return known.ID == item.ID }) {
+					continue
+				}
+				f.history = append(f.history, item)
+				if !first {
+					s.broadcast(f, newMailEvent(item))
+				}
+			}
+			if len(f.history) > historySize {
+				f.history = slices.Clone(f.history[len(f.history)-historySize:])
+			}
+			first = false
+		}
+		// the clients wait for the first listing, a failed one
+		// included so they get its error
+		select {
+		case <-f.ready:
+		default:
+			close(f.ready)
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+// broadcast sends an event to all the clients of a feed, the
+// stream lock must be held
+func (s *Stream) broadcast(f *feed, e event) {
+	for ch := range f.subscribers {
+		select {
+		case ch <- e:
+		default:
+			close(ch)
+			delete(f.subscribers, ch)
+		}
+	}
+	s.release(f)
+}
+
+func (s *Stream) list(name string) ([]inbox.InboxItem, error) {
+	var items []inbox.InboxItem
+	err := s.session.Do(name, false, func(in backend.Inbox) error {
+		if err := in.Refresh(s.config.Limit); err != nil {
+			return err
+		}
+		items = in.GetMails()
+		return nil
+	})
+	return items, err
+}
+
+func newMailEvent(item inbox.InboxItem) event {
+	data, _ := json.Marshal(item)
+	return event{id: item.ID, name: "mail", data: data}
+}
+
+func writeEvent(w http.ResponseWriter, e event) error {
+	if e.id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", e.id); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data)
+	return err
+}
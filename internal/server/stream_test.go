@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/backend"
+	"github.com/antham/yogo/v4/internal/backend/backendtest"
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sse struct {
+	id   string
+	name string
+	data string
+}
+
+// readEvent returns the next event of a stream, the comments are skipped
+func readEvent(t *testing.T, r *bufio.Reader) sse {
+	var e sse
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && e.name != "":
+			return e
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func follow(t *testing.T, ctx context.Context, URL string, lastEventID string) (*http.Response, *bufio.Reader) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp, bufio.NewReader(resp.Body)
+}
+
+func newTestStream(in *backendtest.Inbox) (*Stream, *httptest.Server) {
+	s := NewStream(func(name string, source bool) (backend.Inbox, error) {
+		if name == "broken" {
+			return nil, errors.New("inbox builder error")
+		}
+		return in, nil
+	}, StreamConfig{Limit: 15, PollInterval: 20 * time.Millisecond, KeepAlive: 10 * time.Millisecond, Name: strings.ToLower})
+	return s, httptest.NewServer(s)
+}
+
+func TestStream(t *testing.T) {
+	a := inbox.InboxItem{ID: "a", Subject: "First"}
+	b := inbox.InboxItem{ID: "b", Subject: "Second", Sender: &inbox.Sender{Name: "Acme"}}
+	c := inbox.InboxItem{ID: "c", Subject: "Third"}
+	type scenario struct {
+		name         string
+		path         string
+		lastEventID  string
+		refreshItems [][]inbox.InboxItem
+		refreshError error
+		events       []sse
+	}
+	scenarios := []scenario{
+		{
+			name:         "New emails",
+			path:         "/inboxes/Test/events",
+			refreshItems: [][]inbox.InboxItem{{a}, {a}, {c, b, a}},
+			events: []sse{
+				{id: "b", name: "mail", data: `{"id":"b","sender":{"name":"Acme"},"subject":"Second","isSPAM":false}`},
+				{id: "c", name: "mail", data: `{"id":"c","subject":"Third","isSPAM":false}`},
+			},
+		},
+		{
+			name:         "Resume after the last event",
+			path:         "/inboxes/test/events",
+			lastEventID:  "a",
+			refreshItems: [][]inbox.InboxItem{{c, b, a}},
+			events: []sse{
+				{id: "b", name: "mail", data: `{"id":"b","sender":{"name":"Acme"},"subject":"Second","isSPAM":false}`},
+				{id: "c", name: "mail", data: `{"id":"c","subject":"Third","isSPAM":false}`},
+			},
+		},
+		{
+			name:         "Resume from an unknown event",
+			path:         "/inboxes/test/events",
+			lastEventID:  "z",
+			refreshItems: [][]inbox.InboxItem{{b, a}, {c, b, a}},
+			events: []sse{
+				{id: "c", name: "mail", data: `{"id":"c","subject":"Third","isSPAM":false}`},
+			},
+		},
+		{
+			name:         "Polling error",
+			path:         "/inboxes/test/events",
+			refreshError: errors.New("refresh error"),
+			events: []sse{
+				{name: "error", data: `{"error":"refresh error"}`},
+			},
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			_, server := newTestStream(&backendtest.Inbox{Lists: scenario.refreshItems, RefreshError: scenario.refreshError})
+			defer server.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			resp, r := follow(t, ctx, server.URL+scenario.path, scenario.lastEventID)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+			for _, expected := range scenario.events {
+				assert.Equal(t, expected, readEvent(t, r))
+			}
+		})
+	}
+}
+
+func TestStreamSharedPoller(t *testing.T) {
+	in := &backendtest.Inbox{Lists: [][]inbox.InboxItem{{}, {{ID: "a", Subject: "First"}}}}
+	s, server := newTestStream(in)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	resp1, r1 := follow(t, ctx, server.URL+"/inboxes/test/events", "")
+	defer resp1.Body.Close()
+	resp2, r2 := follow(t, ctx, server.URL+"/inboxes/TEST/events", "")
+	defer resp2.Body.Close()
+	s.mu.Lock()
+	assert.Len(t, s.feeds, 1)
+	assert.Len(t, s.feeds["test"].subscribers, 2)
+	s.mu.Unlock()
+
+	assert.Equal(t, "a", readEvent(t, r1).id)
+	assert.Equal(t, "a", readEvent(t, r2).id)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.feeds) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamFirstListingFailure(t *testing.T) {
+	in := &backendtest.Inbox{
+		Lists:        [][]inbox.InboxItem{{{ID: "a", Subject: "First"}}, {{ID: "b", Subject: "Second"}, {ID: "a", Subject: "First"}}},
+		RefreshError: errors.New("refresh error"),
+	}
+	_, server := newTestStream(in)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, r := follow(t, ctx, server.URL+"/inboxes/test/events", "")
+	defer resp.Body.Close()
+	assert.Equal(t, sse{name: "error", data: `{"error":"refresh error"}`}, readEvent(t, r))
+	in.Lock()
+	in.RefreshError = nil
+	in.Unlock()
+
+	// the emails of the first successful listing are not sent
+	e := readEvent(t, r)
+	for e.name == "error" {
+		e = readEvent(t, r)
+	}
+	assert.Equal(t, "b", e.id)
+}
@@ -0,0 +1,420 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/fatih/color"
+)
+
+const noDataToDisplayMsg = "[no data to display]"
+
+const help = "j/k move  enter open  space/b scroll  s source  d delete  F flush  r refresh  / search  q quit"
+
+// Inbox is the inbox browsed
+type Inbox interface {
+	Fetcher
+	Refresh(int) error
+	GetMails() []inbox.InboxItem
+	DeleteByID(string) error
+	Flush() error
+}
+
+// Fetcher retrieves an email from its ID
+type Fetcher interface {
+	FetchByID(string) (inbox.Mail, error)
+}
+
+type mode int
+
+const (
+	modeList mode = iota
+	modeSearch
+	modeConfirm
+)
+
+// App is a full screen interface to browse an inbox, the
+// emails are listed at the top and previewed at the bottom
+type App struct {
+	name     string
+	in       Inbox
+	sources  Fetcher
+	limit    int
+	terminal Terminal
+	mode     mode
+	items    []inbox.InboxItem
+	selected int
+	top      int
+	scroll   int
+	query    string
+	source   bool
+	status   string
+	question string
+	action   func() (string, error)
+	mails    map[string]inbox.Mail
+	sourceOf map[string]inbox.Mail
+}
+
+// New creates an interface browsing the inbox, the
+// sources of the emails are retrieved from sources
+func New(name string, in Inbox, sources Fetcher, limit int, terminal Terminal) *App {
+	return &App{
+		name:     name,
+		in:       in,
+		sources:  sources,
+		limit:    limit,
+		terminal: terminal,
+		mails:    map[string]inbox.Mail{},
+		sourceOf: map[string]inbox.Mail{},
+	}
+}
+
+// Run lists the inbox and handles the keys until the user quits
+func (a *App) Run() error {
+	if err := a.in.Refresh(a.limit); err != nil {
+		return err
+	}
+	a.filter()
+	for {
+		if err := a.terminal.Draw(a.render()); err != nil {
+			return err
+		}
+		key, err := a.terminal.ReadKey()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if quit := a.handle(key); quit {
+			return nil
+		}
+	}
+}
+
+func (a *App) handle(key Key) bool {
+	if key.Code == KeyCtrlC {
+		return true
+	}
+	a.status = ""
+	switch a.mode {
+	case modeSearch:
+		a.handleSearch(key)
+	case modeConfirm:
+		a.handleConfirm(key)
+	default:
+		return a.handleList(key)
+	}
+	return false
+}
+
+func (a *App) handleSearch(key Key) {
+	switch key.Code {
+	case KeyRune:
+		a.query += string(key.Rune)
+	case KeyBackspace:
+		if q := []rune(a.query); len(q) > 0 {
+			a.query = string(q[:len(q)-1])
+		}
+	case KeyEnter:
+		a.mode = modeList
+	case KeyEscape:
+		a.query = ""
+		a.mode = modeList
+	}
+	a.filter()
+}
+
+func (a *App) handleConfirm(key Key) {
+	a.mode = modeList
+	if key.Code != KeyRune || (key.Rune != 'y' && key.Rune != 'Y') {
+		a.status = "Cancelled"
+		return
+	}
+	status, err := a.action()
+	a.setStatus(status, err)
+}
+
+func (a *App) handleList(key Key) bool {
+	_, height := a.terminal.Size()
+	page := a.previewHeight(height)
+	switch {
+	case key.Code == KeyRune && key.Rune == 'q':
+		return true
+	case key.Code == KeyUp, key.Code == KeyRune && key.Rune == 'k':
+		a.move(a.selected - 1)
+	case key.Code == KeyDown, key.Code == KeyRune && key.Rune == 'j':
+		a.move(a.selected + 1)
+	case key.Code == KeyHome, key.Code == KeyRune && key.Rune == 'g':
+		a.move(0)
+	case key.Code == KeyEnd, key.Code == KeyRune && key.Rune == 'G':
+		a.move(len(a.items) - 1)
+	case key.Code == KeyPageDown, key.Code == KeyRune && key.Rune == ' ':
+		a.scroll += page
+	case key.Code == KeyPageUp, key.Code == KeyRune && key.Rune == 'b':
+		a.scroll = max(a.scroll-page, 0)
+	case key.Code == KeyEnter:
+		a.setStatus("", a.load())
+	case key.Code == KeyRune && key.Rune == 's':
+		a.source = !a.source
+		a.scroll = 0
+		a.setStatus("", a.load())
+	case key.Code == KeyRune && key.Rune == 'd':
+		item, ok := a.current()
+		if !ok {
+			break
+		}
+		a.ask(fmt.Sprintf(`Delete the email "%s"? [y/N]`, subject(item)), func() (string, error) {
+			if err := a.in.DeleteByID(item.ID); err != nil {
+				return "", err
+			}
+			delete(a.mails, item.ID)
+			delete(a.sourceOf, item.ID)
+			a.filter()
+			return "Email deleted", nil
+		})
+	case key.Code == KeyRune && key.Rune == 'F':
+		a.ask(fmt.Sprintf(`Flush the inbox "%s"? [y/N]`, a.name), func() (string, error) {
+			if err := a.in.Flush(); err != nil {
+				return "", err
+			}
+			a.mails = map[string]inbox.Mail{}
+			a.sourceOf = map[string]inbox.Mail{}
+			a.filter()
+			return "Inbox flushed", nil
+		})
+	case key.Code == KeyRune && key.Rune == 'r':
+		if err := a.in.Refresh(a.limit); err != nil {
+			a.setStatus("", err)
+			break
+		}
+		a.filter()
+		a.status = fmt.Sprintf("%d email(s) found", len(a.in.GetMails()))
+	case key.Code == KeyRune && key.Rune == '/':
+		a.mode = modeSearch
+	case key.Code == KeyEscape:
+		a.query = ""
+		a.filter()
+	}
+	return false
+}
+
+func (a *App) ask(question string, action func() (string, error)) {
+	a.mode = modeConfirm
+	a.question = question
+	a.action = action
+}
+
+func (a *App) setStatus(status string, err error) {
+	a.status = status
+	if err != nil {
+		a.status = color.RedString("Error: %s", err)
+	}
+}
+
+// filter keeps the emails matching the query, the
+// selection stays on the same email when possible
+func (a *App) filter() {
+	ID := ""
+	if item, ok := a.current(); ok {
+		ID = item.ID
+	}
+	query := strings.ToLower(a.query)
+	a.items = []inbox.InboxItem{}
+	for _, item := range a.in.GetMails() {
+		if query == "" || strings.Contains(strings.ToLower(subject(item)+" "+sender(item)), query) {
+			a.items = append(a.items, item)
+		}
+	}
+	selected := min(a.selected, len(a.items)-1)
+	for i, item := range a.items {
+		if item.ID == ID {
+			selected = i
+		}
+	}
+	a.move(selected)
+}
+
+func (a *App) move(selected int) {
+	selected = max(min(selected, len(a.items)-1), 0)
+	if selected != a.selected {
+		a.scroll = 0
+	}
+	a.selected = selected
+}
+
+func (a *App) current() (inbox.InboxItem, bool) {
+	if a.selected < 0 || a.selected >= len(a.items) {
+		return inbox.InboxItem{}, false
+	}
+	return a.items[a.selected], true
+}
+
+// load fetches the selected email, the emails already
+// fetched are kept until they are deleted
+func (a *App) load() error {
+	item, ok := a.current()
+	if !ok {
+		return nil
+	}
+	cache, fetcher := a.mails, Fetcher(a.in)
+	if a.source {
+		cache, fetcher = a.sourceOf, a.sources
+	}
+	if _, ok := cache[item.ID]; ok {
+		return nil
+	}
+	m, err := fetcher.FetchByID(item.ID)
+	if err != nil {
+		return err
+	}
+	cache[item.ID] = m
+	return nil
+}
+
+func (a *App) listHeight(height int) int {
+	return max((height-3)/3, 1)
+}
+
+func (a *App) previewHeight(height int) int {
+	return max(height-3-a.listHeight(height), 1)
+}
+
+func (a *App) render() []string {
+	width, height := a.terminal.Size()
+	if width < 20 || height < 6 {
+		return []string{fit("Terminal too small", width)}
+	}
+
+	title := fmt.Sprintf(" %s - %d email(s)", a.name, len(a.in.GetMails()))
+	if a.query != "" {
+		title += fmt.Sprintf(` - "%s" matches %d email(s)`, a.query, len(a.items))
+	}
+	lines := []string{fit(color.New(color.ReverseVideo).Sprint(pad(title, width)), width)}
+	lines = append(lines, a.renderList(width, a.listHeight(height))...)
+
+	label := " Email "
+	if a.source {
+		label = " Source "
+	}
+	lines = append(lines, fit("──"+label+strings.Repeat("─", width), width))
+	lines = append(lines, a.renderPreview(width, a.previewHeight(height))...)
+
+	var status string
+	switch {
+	case a.mode == modeSearch:
+		status = "/" + a.query
+	case a.mode == modeConfirm:
+		status = color.YellowString(a.question)
+	case a.status != "":
+		status = a.status
+	default:
+		status = color.CyanString(help)
+	}
+	return append(lines, fit(status, width))
+}
+
+func (a *App) renderList(width int, height int) []string {
+	lines := []string{}
+	if len(a.items) == 0 {
+		lines = append(lines, " No email")
+		if a.query != "" {
+			lines[0] = " No email matches the search"
+		}
+	}
+	// the list is scrolled to keep the selection visible
+	a.top = min(a.top, a.selected)
+	if a.selected >= a.top+height {
+		a.top = a.selected - height + 1
+	}
+	for i := a.top; i < len(a.items) && i < a.top+height; i++ {
+		item := a.items[i]
+		line := fmt.Sprintf("  %s", color.YellowString(sender(item)))
+		if item.IsSPAM {
+			line += " " + color.RedString("[SPAM]")
+		}
+		line += " : " + subject(item)
+		if item.Date != nil {
+			line += " " + color.CyanString(item.Date.Format("2006-01-02 15:04"))
+		}
+		if i == a.selected {
+			// the colours are dropped to keep the selection readable
+			line = color.New(color.ReverseVideo).Sprint(pad(">"+escapeRegexp.ReplaceAllString(line, "")[1:], width))
+		}
+		lines = append(lines, fit(line, width))
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func (a *App) renderPreview(width int, height int) []string {
+	text := a.preview(width)
+	content := strings.Split(text, "\n")
+	a.scroll = max(min(a.scroll, len(content)-height), 0)
+	lines := []string{}
+	for i := a.scroll; i < len(content) && i < a.scroll+height; i++ {
+		lines = append(lines, fit(content[i], width))
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func (a *App) preview(width int) string {
+	item, ok := a.current()
+	if !ok {
+		return ""
+	}
+	m, ok := a.mails[item.ID]
+	if a.source {
+		m, ok = a.sourceOf[item.ID]
+	}
+	if !ok {
+		return " Press enter to display the email"
+	}
+	if page, ok := m.(inbox.HTMLPage); ok {
+		page.SetWidth(width)
+	}
+	if c, ok := m.(interface{ Coloured() (string, error) }); ok {
+		text, err := c.Coloured()
+		if err == nil {
+			return text
+		}
+	}
+	return m.Content()
+}
+
+func pad(s string, width int) string {
+	if l := visibleLength(s); l < width {
+		return s + strings.Repeat(" ", width-l)
+	}
+	return s
+}
+
+func sender(item inbox.InboxItem) string {
+	if item.Sender == nil {
+		return noDataToDisplayMsg
+	}
+	switch {
+	case item.Sender.Name != "" && item.Sender.Mail != "":
+		return item.Sender.Name + " <" + item.Sender.Mail + ">"
+	case item.Sender.Name != "":
+		return item.Sender.Name
+	case item.Sender.Mail != "":
+		return item.Sender.Mail
+	}
+	return noDataToDisplayMsg
+}
+
+func subject(item inbox.InboxItem) string {
+	if item.Subject == "" {
+		return noDataToDisplayMsg
+	}
+	return item.Subject
+}
@@ -0,0 +1,351 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/inbox"
+	"github.com/stretchr/testify/assert"
+)
+
+type mailMock struct {
+	content string
+}
+
+func (m *mailMock) Content() string {
+	return m.content
+}
+
+func (m *mailMock) GetLinks() []inbox.Link {
+	return nil
+}
+
+func (m *mailMock) GetAttachments() []inbox.Attachment {
+	return nil
+}
+
+type inboxMock struct {
+	items        []inbox.InboxItem
+	refreshCount int
+	refreshError error
+	fetchError   error
+	deleteError  error
+	fetched      []string
+	kind         string
+}
+
+func (i *inboxMock) Refresh(int) error {
+	i.refreshCount++
+	return i.refreshError
+}
+
+func (i *inboxMock) GetMails() []inbox.InboxItem {
+	return i.items
+}
+
+func (i *inboxMock) FetchByID(ID string) (inbox.Mail, error) {
+	i.fetched = append(i.fetched, ID)
+	if i.fetchError != nil {
+		return nil, i.fetchError
+	}
+	content := fmt.Sprintf("%s of %s", i.kind, ID)
+	for line := 2; line <= 10; line++ {
+		content += fmt.Sprintf("\nline %d", line)
+	}
+	return &mailMock{content: content}, nil
+}
+
+func (i *inboxMock) DeleteByID(ID string) error {
+	if i.deleteError != nil {
+		return i.deleteError
+	}
+	for index, item := range i.items {
+		if item.ID == ID {
+			i.items = append(i.items[:index], i.items[index+1:]...)
+		}
+	}
+	return nil
+}
+
+func (i *inboxMock) Flush() error {
+	i.items = []inbox.InboxItem{}
+	return nil
+}
+
+func newInboxMock() *inboxMock {
+	date := time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)
+	return &inboxMock{
+		kind: "email",
+		items: []inbox.InboxItem{
+			{ID: "a", Subject: "Welcome", Sender: &inbox.Sender{Name: "Acme"}, Date: &date},
+			{ID: "b", Subject: "Reset your password", Sender: &inbox.Sender{Mail: "no-reply@acme.com"}},
+			{ID: "c", Subject: "Invoice", Sender: &inbox.Sender{Name: "Shop"}, IsSPAM: true},
+		},
+	}
+}
+
+var (
+	down  = Key{Code: KeyDown}
+	up    = Key{Code: KeyUp}
+	enter = Key{Code: KeyEnter}
+)
+
+func keys(groups ...[]Key) []Key {
+	all := []Key{}
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	return all
+}
+
+func TestApp(t *testing.T) {
+	type scenario struct {
+		name        string
+		keys        []Key
+		setup       func(*inboxMock)
+		screen      string
+		errExpected error
+		test        func(*testing.T, *inboxMock, *inboxMock)
+	}
+
+	scenarios := []scenario{
+		{
+			name: "Inbox listed",
+			screen: ` test - 3 email(s)
+> Acme : Welcome 2024-03-02 10:30
+  no-reply@acme.com : Reset your password
+  Shop [SPAM] : Invoice
+── Email ───────────────────────────────────────────────────────────
+ Press enter to display the email
+
+
+
+
+
+j/k move  enter open  space/b scroll  s source  d delete  F flush  r`,
+		},
+		{
+			name: "Email previewed and scrolled",
+			keys: keys([]Key{down, down, up, enter}, Type(" ")),
+			screen: ` test - 3 email(s)
+  Acme : Welcome 2024-03-02 10:30
+> no-reply@acme.com : Reset your password
+  Shop [SPAM] : Invoice
+── Email ───────────────────────────────────────────────────────────
+line 5
+line 6
+line 7
+line 8
+line 9
+line 10
+j/k move  enter open  space/b scroll  s source  d delete  F flush  r`,
+			test: func(t *testing.T, in *inboxMock, sources *inboxMock) {
+				assert.Equal(t, []string{"b"}, in.fetched)
+			},
+		},
+		{
+			name: "Source toggled",
+			keys: keys(Type("js")),
+			screen: ` test - 3 email(s)
+  Acme : Welcome 2024-03-02 10:30
+> no-reply@acme.com : Reset your password
+  Shop [SPAM] : Invoice
+── Source ──────────────────────────────────────────────────────────
+source of b
+line 2
+line 3
+line 4
+line 5
+line 6
+j/k move  enter open  space/b scroll  s source  d delete  F flush  r`,
+			test: func(t *testing.T, in *inboxMock, sources *inboxMock) {
+				assert.Empty(t, in.fetched)
+				assert.Equal(t, []string{"b"}, sources.fetched)
+			},
+		},
+		{
+			name: "Fetched emails are kept",
+			keys: []Key{enter, down, up, enter},
+			test: func(t *testing.T, in *inboxMock, sources *inboxMock) {
+				assert.Equal(t, []string{"a"}, in.fetched)
+			},
+		},
+		{
+			name: "Search",
+			keys: keys(Type("/ACME"), []Key{{Code: KeyBackspace}, enter}),
+			screen: ` test - 3 email(s) - "ACM" matches 2 email(s)
+> Acme : Welcome 2024-03-02 10:30
+  no-reply@acme.com : Reset your password
+
+── Email ───────────────────────────────────────────────────────────
+ Press enter to display the email
+
+
+
+
+
+j/k move  enter open  space/b scroll  s source  d delete  F flush  r`,
+		},
+		{
+			name: "Search without result",
+			keys: Type("/zzz"),
+			screen: ` test - 3 email(s) - "zzz" matches 0 email(s)
+ No email matches the search
+
+
+── Email ───────────────────────────────────────────────────────────
+
+
+
+
+
+
+/zzz`,
+		},
+		{
+			name: "Search cleared",
+			keys: keys(Type("/zzz"), []Key{{Code: KeyEscape}}),
+			screen: ` test - 3 email(s)
+> Acme : Welcome 2024-03-02 10:30
+  no-reply@acme.com : Reset your password
+  Shop [SPAM] : Invoice
+── Email ───────────────────────────────────────────────────────────
+ Press enter to display the email
+
+
+
+
+
+j/k move  enter open  space/b scroll  s source  d delete  F flush  r`,
+		},
+		{
+			name: "Delete asks for a confirmation",
+			keys: keys(Type("jd")),
+			screen: ` test - 3 email(s)
+  Acme : Welcome 2024-03-02 10:30
+> no-reply@acme.com : Reset your password
+  Shop [SPAM] : Invoice
+── Email ───────────────────────────────────────────────────────────
+ Press enter to display the email
+
+
+
+
+
+Delete the email "Reset your password"? [y/N]`,
+		},
+		{
+			name: "Delete confirmed",
+			keys: keys(Type("jdy")),
+			screen: ` test - 2 email(s)
+  Acme : Welcome 2024-03-02 10:30
+> Shop [SPAM] : Invoice
+
+── Email ───────────────────────────────────────────────────────────
+ Press enter to display the email
+
+
+
+
+
+Email deleted`,
+		},
+		{
+			name: "Delete cancelled",
+			keys: keys(Type("jdn")),
+			test: func(t *testing.T, in *inboxMock, sources *inboxMock) {
+				assert.Len(t, in.items, 3)
+			},
+		},
+		{
+			name: "Delete failure",
+			keys: keys(Type("dy")),
+			setup: func(in *inboxMock) {
+				in.deleteError = errors.New("delete failure")
+			},
+			test: func(t *testing.T, in *inboxMock, sources *inboxMock) {
+				assert.Len(t, in.items, 3)
+			},
+		},
+		{
+			name: "Flush confirmed",
+			keys: keys(Type("Fy")),
+			screen: ` test - 0 email(s)
+ No email
+
+
+── Email ───────────────────────────────────────────────────────────
+
+
+
+
+
+
+Inbox flushed`,
+		},
+		{
+			name: "Refresh",
+			keys: Type("r"),
+			test: func(t *testing.T, in *inboxMock, sources *inboxMock) {
+				assert.Equal(t, 2, in.refreshCount)
+			},
+		},
+		{
+			name: "Quit",
+			keys: keys(Type("qj")),
+			test: func(t *testing.T, in *inboxMock, sources *inboxMock) {
+				assert.Empty(t, in.fetched)
+			},
+		},
+		{
+			name: "Refresh failure at start",
+			setup: func(in *inboxMock) {
+				in.refreshError = errors.New("refresh failure")
+			},
+			errExpected: errors.New("refresh failure"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			in := newInboxMock()
+			sources := &inboxMock{kind: "source"}
+			if scenario.setup != nil {
+				scenario.setup(in)
+			}
+			terminal := NewVirtualTerminal(68, 12, scenario.keys...)
+			err := New("test", in, sources, 15, terminal).Run()
+			if scenario.errExpected != nil {
+				assert.EqualError(t, err, scenario.errExpected.Error())
+				return
+			}
+			assert.NoError(t, err)
+			if scenario.screen != "" {
+				assert.Equal(t, scenario.screen, terminal.Screen())
+			}
+			if scenario.test != nil {
+				scenario.test(t, in, sources)
+			}
+		})
+	}
+}
+
+func TestAppErrorDisplayed(t *testing.T) {
+	t.Parallel()
+	in := newInboxMock()
+	in.fetchError = errors.New("fetch failure")
+	terminal := NewVirtualTerminal(68, 12, enter)
+	assert.NoError(t, New("test", in, in, 15, terminal).Run())
+	assert.Contains(t, terminal.Screen(), "Error: fetch failure")
+}
+
+func TestAppSmallTerminal(t *testing.T) {
+	t.Parallel()
+	terminal := NewVirtualTerminal(10, 4)
+	assert.NoError(t, New("test", newInboxMock(), nil, 15, terminal).Run())
+	assert.Equal(t, "Terminal t", terminal.Screen())
+}
@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// escapeRegexp matches the SGR sequences and the OSC 8 hyperlinks,
+// they are not displayed so they don't take any room on the screen
+var escapeRegexp = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]|\x1b\\]8;;[^\x1b]*\x1b\\\\")
+
+// KeyCode identifies a key
+type KeyCode int
+
+const (
+	KeyUnknown KeyCode = iota
+	KeyRune
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+	KeyCtrlC
+)
+
+// Key is a key pressed by the user, Rune is defined for KeyRune
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Terminal is the screen the interface is drawn on
+type Terminal interface {
+	Size() (width int, height int)
+	ReadKey() (Key, error)
+	Draw(lines []string) error
+}
+
+// Term is the terminal of the user, switched to the raw
+// mode and to the alternate screen until it is closed
+type Term struct {
+	in     *os.File
+	reader *bufio.Reader
+	out    io.Writer
+	state  *term.State
+}
+
+// OpenTerminal prepares a terminal to draw the interface
+func OpenTerminal(in *os.File, out io.Writer) (*Term, error) {
+	if !term.IsTerminal(int(in.Fd())) {
+		return nil, errors.New("an interactive terminal is required")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(out, "\x1b[?1049h\x1b[?25l"); err != nil {
+		_ = term.Restore(int(in.Fd()), state)
+		return nil, err
+	}
+	return &Term{in: in, reader: bufio.NewReader(in), out: out, state: state}, nil
+}
+
+// Close restores the terminal as it was before being opened
+func (t *Term) Close() error {
+	if _, err := io.WriteString(t.out, "\x1b[?25h\x1b[?1049l"); err != nil {
+		return err
+	}
+	return term.Restore(int(t.in.Fd()), t.state)
+}
+
+// Size returns the size of the terminal, 80x24 when it can't be read
+func (t *Term) Size() (int, int) {
+	width, height, err := term.GetSize(int(t.in.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+	return width, height
+}
+
+// ReadKey waits for a key
+func (t *Term) ReadKey() (Key, error) {
+	return decodeKey(t.reader)
+}
+
+// Draw replaces the content of the screen
+func (t *Term) Draw(lines []string) error {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString("\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
+
+// decodeKey reads a key, a lone escape byte is the escape
+// key whereas it starts a sequence when followed by other bytes
+func decodeKey(r *bufio.Reader) (Key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	switch c {
+	case '\r', '\n':
+		return Key{Code: KeyEnter}, nil
+	case 0x7f, 0x08:
+		return Key{Code: KeyBackspace}, nil
+	case 0x03:
+		return Key{Code: KeyCtrlC}, nil
+	case 0x1b:
+		if r.Buffered() == 0 {
+			return Key{Code: KeyEscape}, nil
+		}
+		return decodeSequence(r)
+	}
+	if c < 0x20 {
+		return Key{Code: KeyUnknown}, nil
+	}
+	return Key{Code: KeyRune, Rune: c}, nil
+}
+
+func decodeSequence(r *bufio.Reader) (Key, error) {
+	introducer, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	if introducer != '[' && introducer != 'O' {
+		return Key{Code: KeyUnknown}, nil
+	}
+	sequence := ""
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		sequence += string(b)
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+	}
+	switch sequence {
+	case "A":
+		return Key{Code: KeyUp}, nil
+	case "B":
+		return Key{Code: KeyDown}, nil
+	case "H", "1~", "7~":
+		return Key{Code: KeyHome}, nil
+	case "F", "4~", "8~":
+		return Key{Code: KeyEnd}, nil
+	case "5~":
+		return Key{Code: KeyPageUp}, nil
+	case "6~":
+		return Key{Code: KeyPageDown}, nil
+	}
+	return Key{Code: KeyUnknown}, nil
+}
+
+// fit cuts a line to the width of the screen, the escape
+// sequences are kept and the attributes reset at the end
+func fit(line string, width int) string {
+	line = strings.ReplaceAll(strings.ReplaceAll(line, "\r", ""), "\t", "    ")
+	var b strings.Builder
+	visible := 0
+	escaped := false
+	for i := 0; i < len(line); {
+		if loc := escapeRegexp.FindStringIndex(line[i:]); loc != nil && loc[0] == 0 {
+			b.WriteString(line[i : i+loc[1]])
+			i += loc[1]
+			escaped = true
+			continue
+		}
+		if visible == width {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		b.WriteRune(r)
+		visible++
+		i += size
+	}
+	if escaped {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+func visibleLength(line string) int {
+	return utf8.RuneCountInString(escapeRegexp.ReplaceAllString(line, ""))
+}
@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeKey(t *testing.T) {
+	type scenario struct {
+		name     string
+		input    string
+		expected []Key
+	}
+
+	scenarios := []scenario{
+		{
+			name:     "Runes",
+			input:    "aé/",
+			expected: []Key{{Code: KeyRune, Rune: 'a'}, {Code: KeyRune, Rune: 'é'}, {Code: KeyRune, Rune: '/'}},
+		},
+		{
+			name:     "Control keys",
+			input:    "\r\x7f\x03\x01",
+			expected: []Key{{Code: KeyEnter}, {Code: KeyBackspace}, {Code: KeyCtrlC}, {Code: KeyUnknown}},
+		},
+		{
+			name:     "Escape sequences",
+			input:    "\x1b[A\x1b[B\x1bOH\x1b[4~\x1b[5~\x1b[6~\x1b[1;5C",
+			expected: []Key{{Code: KeyUp}, {Code: KeyDown}, {Code: KeyHome}, {Code: KeyEnd}, {Code: KeyPageUp}, {Code: KeyPageDown}, {Code: KeyUnknown}},
+		},
+		{
+			name:     "Lone escape",
+			input:    "\x1b",
+			expected: []Key{{Code: KeyEscape}},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			r := bufio.NewReader(strings.NewReader(scenario.input))
+			keys := []Key{}
+			for {
+				key, err := decodeKey(r)
+				if err != nil {
+					break
+				}
+				keys = append(keys, key)
+			}
+			assert.Equal(t, scenario.expected, keys)
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	type scenario struct {
+		name     string
+		line     string
+		width    int
+		expected string
+	}
+
+	scenarios := []scenario{
+		{
+			name:     "Short line",
+			line:     "hello",
+			width:    10,
+			expected: "hello",
+		},
+		{
+			name:     "Long line",
+			line:     "hello world",
+			width:    5,
+			expected: "hello",
+		},
+		{
+			name:     "Escape sequences are not counted",
+			line:     "\x1b[33mhé\x1b[0m world",
+			width:    4,
+			expected: "\x1b[33mhé\x1b[0m w\x1b[0m",
+		},
+		{
+			name:     "Tabulations and carriage returns",
+			line:     "a\tb\r",
+			width:    10,
+			expected: "a    b",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, scenario.expected, fit(scenario.line, scenario.width))
+		})
+	}
+}
+
+func TestVirtualTerminal(t *testing.T) {
+	t.Parallel()
+	v := NewVirtualTerminal(10, 2, Type("ab")...)
+	key, err := v.ReadKey()
+	assert.NoError(t, err)
+	assert.Equal(t, Key{Code: KeyRune, Rune: 'a'}, key)
+	_, err = v.ReadKey()
+	assert.NoError(t, err)
+	_, err = v.ReadKey()
+	assert.EqualError(t, err, "EOF")
+
+	assert.NoError(t, v.Draw([]string{"\x1b[7mfirst  \x1b[0m", "second"}))
+	assert.Equal(t, "first\nsecond", v.Screen())
+	assert.EqualError(t, v.Draw([]string{"a", "b", "c"}), "3 lines drawn on a screen of 2 lines")
+	assert.EqualError(t, v.Draw([]string{"a very long line"}), `line 1 is wider than the screen : "a very long line"`)
+	assert.Equal(t, 1, v.Frames())
+}
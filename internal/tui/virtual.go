@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// VirtualTerminal is an in-memory terminal, the keys are played
+// in order and the frames are recorded without escape sequences
+type VirtualTerminal struct {
+	width  int
+	height int
+	keys   []Key
+	frames [][]string
+}
+
+// NewVirtualTerminal creates a terminal that will send the given keys
+func NewVirtualTerminal(width int, height int, keys ...Key) *VirtualTerminal {
+	return &VirtualTerminal{width: width, height: height, keys: keys}
+}
+
+// Size returns the size given at the creation
+func (v *VirtualTerminal) Size() (int, int) {
+	return v.width, v.height
+}
+
+// ReadKey returns the next key, io.EOF is returned once all keys are sent
+func (v *VirtualTerminal) ReadKey() (Key, error) {
+	if len(v.keys) == 0 {
+		return Key{}, io.EOF
+	}
+	key := v.keys[0]
+	v.keys = v.keys[1:]
+	return key, nil
+}
+
+// Draw records a frame, a frame overflowing the screen is an error
+func (v *VirtualTerminal) Draw(lines []string) error {
+	if len(lines) > v.height {
+		return fmt.Errorf("%d lines drawn on a screen of %d lines", len(lines), v.height)
+	}
+	frame := []string{}
+	for i, line := range lines {
+		if visibleLength(line) > v.width {
+			return fmt.Errorf("line %d is wider than the screen : %q", i+1, line)
+		}
+		frame = append(frame, strings.TrimRight(escapeRegexp.ReplaceAllString(line, ""), " "))
+	}
+	v.frames = append(v.frames, frame)
+	return nil
+}
+
+// Screen returns the last frame drawn
+func (v *VirtualTerminal) Screen() string {
+	if len(v.frames) == 0 {
+		return ""
+	}
+	return strings.Join(v.frames[len(v.frames)-1], "\n")
+}
+
+// Frames returns the number of frames drawn
+func (v *VirtualTerminal) Frames() int {
+	return len(v.frames)
+}
+
+// Type converts a text to the keys typed to write it
+func Type(text string) []Key {
+	keys := []Key{}
+	for _, r := range text {
+		keys = append(keys, Key{Code: KeyRune, Rune: r})
+	}
+	return keys
+}
@@ -0,0 +1,338 @@
+// Package yopmail reads and manages yopmail inboxes, it runs
+// the same code as the yogo command line.
+//
+// The API of this package follows the semantic versioning of the
+// module: no exported identifier is removed or changed in an
+// incompatible way before the next major version. The packages under
+// internal are not covered and must not be relied on.
+//
+// An inbox is identified by its name, the part of the address before
+// @yopmail.com, e.g. helloworld for helloworld@yopmail.com.
+//
+//	c, err := yopmail.New()
+//	if err != nil {
+//		return err
+//	}
+//	items, err := c.List("helloworld", 10)
+//	if err != nil {
+//		return err
+//	}
+//	for _, item := range items {
+//		m, err := c.Mail("helloworld", item.ID)
+//		...
+//	}
+package yopmail
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/antham/yogo/v4/internal/client"
+	"github.com/antham/yogo/v4/internal/inbox"
+)
+
+// ErrCaptcha is returned when yopmail asks for a CAPTCHA, slowing
+// down the requests with the YOGO_REQUEST_INTERVAL environment
+// variable helps to avoid it
+var ErrCaptcha = client.ErrCaptcha
+
+// ErrOffline is returned by an offline client for the
+// requests that can't be served from the cache
+var ErrOffline = client.ErrOffline
+
+// Option configures a client
+type Option func(*options)
+
+type options struct {
+	cache   *client.Cache
+	offline bool
+	debug   bool
+}
+
+// WithCache stores the fetched pages in dir, an email is read from
+// the cache once fetched and the last inbox pages listed are kept to be
+// listed offline, the entries older than maxAge are ignored and the
+// oldest entries are removed to keep the cache under maxSize bytes, a
+// zero value disables the limit
+func WithCache(dir string, maxAge time.Duration, maxSize int64) Option {
+	return func(o *options) {
+		o.cache = client.NewCache(dir, maxAge, maxSize)
+	}
+}
+
+// WithOffline serves everything from the cache, no request is sent
+// to yopmail, it must be combined with WithCache
+func WithOffline() Option {
+	return func(o *options) {
+		o.offline = true
+	}
+}
+
+// WithDebug dumps the requests sent to yopmail and their responses on stdout
+func WithDebug() Option {
+	return func(o *options) {
+		o.debug = true
+	}
+}
+
+// Client sends the requests to yopmail, all the requests go through
+// the same session, like a browser would do, so they run one at a time,
+// a client is safe for concurrent use
+type Client struct {
+	mu    sync.Mutex
+	debug bool
+	opts  []client.Option
+}
+
+// New creates a client, a session is opened with
+// yopmail unless the client works offline
+func New(opts ...Option) (*Client, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	clientOpts := []client.Option{}
+	if o.cache != nil {
+		clientOpts = append(clientOpts, client.WithCache(o.cache), client.WithInboxCache())
+	}
+	if o.offline {
+		if o.cache == nil {
+			return nil, errors.New("the cache must be enabled to work offline")
+		}
+		clientOpts = append(clientOpts, client.WithOffline())
+	}
+	session, err := client.NewSession(o.debug, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{debug: o.debug, opts: append(clientOpts, client.WithSession(session))}, nil
+}
+
+// Sender is the sender of an email, yopmail provides
+// either its name or its address in the inbox listing
+type Sender struct {
+	Mail string `json:"mail,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// String renders the sender the way a mail client would,
+// a nil sender is rendered as an empty string
+func (s *Sender) String() string {
+	return (*inbox.Sender)(s).String()
+}
+
+func newSender(s *inbox.Sender) *Sender {
+	if s == nil {
+		return nil
+	}
+	sender := Sender(*s)
+	return &sender
+}
+
+// Item is an email of an inbox listing
+type Item struct {
+	ID      string     `json:"id"`
+	Sender  *Sender    `json:"sender,omitempty"`
+	Subject string     `json:"subject"`
+	Date    *time.Time `json:"date,omitempty"`
+	SPAM    bool       `json:"spam"`
+}
+
+// Link is a link found in an email
+type Link struct {
+	URL  string `json:"url"`
+	Text string `json:"text,omitempty"`
+}
+
+// Mail is an email read from its HTML or its text page
+type Mail struct {
+	ID      string     `json:"id"`
+	Sender  *Sender    `json:"sender,omitempty"`
+	Subject string     `json:"subject,omitempty"`
+	Date    *time.Time `json:"date,omitempty"`
+	// HTML is the body as the HTML fragment displayed by
+	// yopmail, it is empty for an email read from its text page
+	HTML string `json:"html,omitempty"`
+	// Text is the body converted to text
+	Text  string `json:"text,omitempty"`
+	Links []Link `json:"links,omitempty"`
+}
+
+// Header is a header field of an email source
+type Header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Headers are the header fields of an email in the order they were received
+type Headers []Header
+
+// Values returns all the values of a header, the key is case insensitive
+func (h Headers) Values(key string) []string {
+	values := []string{}
+	for _, header := range h {
+		if textproto.CanonicalMIMEHeaderKey(header.Key) == textproto.CanonicalMIMEHeaderKey(key) {
+			values = append(values, header.Value)
+		}
+	}
+	return values
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     []byte `json:"-"`
+}
+
+// Source is an email read from its source
+type Source struct {
+	ID string `json:"id"`
+	// Headers are decoded, e.g. the encoded words of the subject
+	Headers Headers `json:"headers"`
+	// Text is the decoded text of the body
+	Text        string       `json:"text"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Raw is the message as received with CRLF line endings
+	Raw []byte `json:"-"`
+}
+
+// List returns the last emails of an inbox, the most recent first,
+// up to limit emails, yopmail provides 15 emails per page and the
+// pages are fetched one per second to avoid triggering a CAPTCHA
+func (c *Client) List(name string, limit int) ([]Item, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf(`limit "%d" must be greater than 0`, limit)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	in, err := inbox.NewInbox[client.MailHTMLDoc](name, c.debug, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := in.ParseInboxPages(limit); err != nil {
+		return nil, err
+	}
+	items := []Item{}
+	for _, i := range in.GetMails() {
+		items = append(items, Item{
+			ID:      i.ID,
+			Sender:  newSender(i.Sender),
+			Subject: i.Subject,
+			Date:    i.Date,
+			SPAM:    i.IsSPAM,
+		})
+	}
+	return items, nil
+}
+
+// Mail fetches an email from its HTML page, the
+// body is provided both as HTML and as text
+func (c *Client) Mail(name string, ID string) (*Mail, error) {
+	m, err := fetch[client.MailHTMLDoc, *inbox.HTMLMail](c, name, ID)
+	if err != nil {
+		return nil, err
+	}
+	return newMail(m), nil
+}
+
+// Text fetches an email from its text page, the body is
+// converted to text by yopmail and no HTML is provided
+func (c *Client) Text(name string, ID string) (*Mail, error) {
+	m, err := fetch[client.MailTextDoc, *inbox.HTMLMail](c, name, ID)
+	if err != nil {
+		return nil, err
+	}
+	return newMail(m), nil
+}
+
+func newMail(m *inbox.HTMLMail) *Mail {
+	links := []Link{}
+	for _, link := range m.Links {
+		links = append(links, Link{URL: link.URL, Text: link.Text})
+	}
+	return &Mail{
+		ID:      m.ID,
+		Sender:  newSender(m.Sender),
+		Subject: m.Subject,
+		Date:    m.Date,
+		HTML:    m.HTML(),
+		Text:    m.Content(),
+		Links:   links,
+	}
+}
+
+// Source fetches the source of an email with all its headers and attachments
+func (c *Client) Source(name string, ID string) (*Source, error) {
+	m, err := fetch[client.MailSourceDoc, *inbox.SourceMail](c, name, ID)
+	if err != nil {
+		return nil, err
+	}
+	headers := Headers{}
+	for _, header := range m.Headers {
+		headers = append(headers, Header{Key: header.Key, Value: header.Value})
+	}
+	attachments := []Attachment{}
+	for _, attachment := range m.Attachments {
+		attachments = append(attachments, Attachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Content:     attachment.Content,
+		})
+	}
+	return &Source{
+		ID:          m.ID,
+		Headers:     headers,
+		Text:        m.Body,
+		Attachments: attachments,
+		Raw:         m.Message(),
+	}, nil
+}
+
+// Delete removes an email from an inbox
+func (c *Client) Delete(name string, ID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cl, err := client.New[client.MailHTMLDoc](c.debug, c.opts...)
+	if err != nil {
+		return err
+	}
+	return cl.DeleteMail(name, ID)
+}
+
+// Flush removes all the emails of an inbox
+func (c *Client) Flush(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	in, err := inbox.NewInbox[client.MailHTMLDoc](name, c.debug, c.opts...)
+	if err != nil {
+		return err
+	}
+	if err := in.ParseInboxPages(1); err != nil {
+		return err
+	}
+	return in.Flush()
+}
+
+// fetch reads an email from the page of kind D, M is the type it is parsed to
+func fetch[D client.MailDoc, M inbox.Mail](c *Client, name string, ID string) (M, error) {
+	var m M
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	in, err := inbox.NewInbox[D](name, c.debug, c.opts...)
+	if err != nil {
+		return m, err
+	}
+	mail, err := in.FetchByID(ID)
+	if err != nil {
+		return m, err
+	}
+	m, ok := mail.(M)
+	if !ok {
+		return m, fmt.Errorf(`email "%s" can't be read`, ID)
+	}
+	return m, nil
+}
@@ -0,0 +1,163 @@
+package yopmail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	inboxPage1URL = "https://yopmail.com/en/inbox?ad=0&ctrl=&d=&id=&login=test&p=1&r_c=&scrl=&spam=true&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp"
+	inboxPage2URL = "https://yopmail.com/en/inbox?ad=0&ctrl=&d=&id=&login=test&p=2&r_c=&scrl=&spam=true&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp"
+	inboxFeatures = "../internal/inbox/features"
+	mailFeatures  = "../internal/inbox/internal/mail/features"
+)
+
+type responder struct {
+	URL      string
+	filename string
+}
+
+// newClient creates a client answered by the given pages, the
+// pages needed to open a session are always registered
+func newClient(t *testing.T, responders []responder, opts ...Option) *Client {
+	httpmock.Activate()
+	t.Cleanup(httpmock.DeactivateAndReset)
+	responders = append(responders,
+		responder{"https://yopmail.com", filepath.Join(inboxFeatures, "main_page.html")},
+		responder{"https://yopmail.com/ver/4.8/webmail.js", filepath.Join(inboxFeatures, "webmail.js")},
+	)
+	for _, r := range responders {
+		b, err := os.ReadFile(r.filename)
+		require.NoError(t, err)
+		httpmock.RegisterResponder("GET", r.URL, httpmock.NewBytesResponder(200, b))
+	}
+	c, err := New(opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(WithOffline())
+	assert.EqualError(t, err, "the cache must be enabled to work offline")
+}
+
+func TestList(t *testing.T) {
+	c := newClient(t, []responder{
+		{inboxPage1URL, filepath.Join(inboxFeatures, "inbox_page_1.html")},
+		{inboxPage2URL, filepath.Join(inboxFeatures, "inbox_page_2.html")},
+	})
+
+	items, err := c.List("test", 17)
+	require.NoError(t, err)
+	require.Len(t, items, 17)
+	assert.Equal(t, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==", items[0].ID)
+	require.NotNil(t, items[0].Sender)
+	assert.NotEmpty(t, items[0].Sender.String())
+	assert.NotEmpty(t, items[0].Subject)
+	assert.NotNil(t, items[0].Date)
+
+	_, err = c.List("test", 0)
+	assert.EqualError(t, err, `limit "0" must be greater than 0`)
+}
+
+func TestMail(t *testing.T) {
+	c := newClient(t, []responder{
+		{"https://yopmail.com/en/mail?b=test&id=me_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D", filepath.Join(inboxFeatures, "mail.html")},
+	})
+
+	m, err := c.Mail("test", "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+	require.NoError(t, err)
+	assert.Equal(t, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==", m.ID)
+	require.NotNil(t, m.Sender)
+	assert.NotEmpty(t, m.Subject)
+	assert.NotNil(t, m.Date)
+	assert.Contains(t, m.HTML, "<")
+	assert.NotEmpty(t, m.Text)
+	assert.NotContains(t, m.Text, "<div")
+}
+
+func TestText(t *testing.T) {
+	c := newClient(t, []responder{
+		{"https://yopmail.com/en/mail?b=test&id=te_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D", filepath.Join(mailFeatures, "text_mail.html")},
+	})
+
+	m, err := c.Text("test", "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+	require.NoError(t, err)
+	assert.Equal(t, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==", m.ID)
+	assert.Equal(t, &Sender{Name: "Liana", Mail: "AnnaMartinezpisea@lionspest.com.au"}, m.Sender)
+	assert.Equal(t, "Liana <AnnaMartinezpisea@lionspest.com.au>", m.Sender.String())
+	assert.Equal(t, "In any case, I am happy that we met", m.Subject)
+	assert.Empty(t, m.HTML)
+	assert.Contains(t, m.Text, "Will you come to me on the weekend?")
+	assert.Equal(t, []Link{{URL: "https://example.com/profile", Text: "https://example.com/profile"}}, m.Links)
+}
+
+func TestSenderString(t *testing.T) {
+	var s *Sender
+	assert.Empty(t, s.String())
+	assert.Equal(t, "Acme", (&Sender{Name: "Acme"}).String())
+}
+
+func TestSource(t *testing.T) {
+	c := newClient(t, []responder{
+		{"https://yopmail.com/en/mail?b=test&id=se_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D", filepath.Join(mailFeatures, "source_mail_multipart.html")},
+	})
+
+	m, err := c.Source("test", "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==")
+	require.NoError(t, err)
+	assert.Equal(t, "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj==", m.ID)
+	assert.NotEmpty(t, m.Headers)
+	assert.Equal(t, m.Headers.Values("content-type"), m.Headers.Values("Content-Type"))
+	assert.NotEmpty(t, m.Headers.Values("Content-Type"))
+	assert.NotEmpty(t, m.Text)
+	assert.Equal(t, []Attachment{
+		{Filename: "invoice.pdf", ContentType: "application/pdf", Content: []byte("Hello World!")},
+		{Filename: "résumé.txt", ContentType: "text/plain", Content: []byte("résumé")},
+	}, m.Attachments)
+	assert.Contains(t, string(m.Raw), "\r\n")
+}
+
+func TestDelete(t *testing.T) {
+	c := newClient(t, []responder{
+		{"https://yopmail.com/en/inbox?ad=0&ctrl=&d=e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D&id=&login=test&p=1&r_c=&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp", filepath.Join(inboxFeatures, "noop.html")},
+	})
+
+	assert.NoError(t, c.Delete("test", "e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj=="))
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET https://yopmail.com/en/inbox?ad=0&ctrl=&d=e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D&id=&login=test&p=1&r_c=&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp"])
+}
+
+func TestFlush(t *testing.T) {
+	type scenario struct {
+		name    string
+		page    string
+		flushed int
+	}
+	scenarios := []scenario{
+		{
+			name:    "Inbox with emails",
+			page:    "inbox_page_1.html",
+			flushed: 1,
+		},
+		{
+			name: "Empty inbox",
+			page: "inbox_empty.html",
+		},
+	}
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			c := newClient(t, []responder{
+				{inboxPage1URL, filepath.Join(inboxFeatures, scenario.page)},
+				{"https://yopmail.com/en/inbox?ad=0&ctrl=e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D&d=all&id=&login=test&p=1&r_c=&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp", filepath.Join(inboxFeatures, "noop.html")},
+			})
+
+			assert.NoError(t, c.Flush("test"))
+			assert.Equal(t, scenario.flushed, httpmock.GetCallCountInfo()["GET https://yopmail.com/en/inbox?ad=0&ctrl=e_ZwRjAwRmZGtmAwZ1ZQNjAwt5AQZmZj%3D%3D&d=all&id=&login=test&p=1&r_c=&v=4.8&yj=VZGV5AmpjZwp5ZGNmZwL0BQH&yp=UAQDkAGH2Amp2Zmt0ZmVmAGp"])
+		})
+	}
+}